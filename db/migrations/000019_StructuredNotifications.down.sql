@@ -0,0 +1,30 @@
+/* Revert notifications to the original unstructured table */
+
+DROP INDEX IF EXISTS idx_notifications_actor_id;
+DROP INDEX IF EXISTS idx_notifications_target;
+
+CREATE TABLE notifications_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    type TEXT CHECK (type IN ('follow', 'follow_request', 'group_invite', 'group_request', 'event', 'message', 'comment', 'post')) NOT NULL,
+    related_id INTEGER,
+    content TEXT NOT NULL,
+    is_read BOOLEAN NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Copy data (excluding types the old constraint does not allow)
+INSERT INTO notifications_new (id, user_id, type, related_id, content, is_read, created_at)
+SELECT id, user_id, type, related_id, content, is_read, created_at
+FROM notifications
+WHERE type IN ('follow', 'follow_request', 'group_invite', 'group_request', 'event', 'message', 'comment', 'post');
+
+DROP TABLE notifications;
+
+ALTER TABLE notifications_new RENAME TO notifications;
+
+CREATE INDEX idx_notifications_user_id ON notifications(user_id);
+CREATE INDEX idx_notifications_type ON notifications(type);
+CREATE INDEX idx_notifications_is_read ON notifications(is_read);
+CREATE INDEX idx_notifications_created_at ON notifications(created_at);
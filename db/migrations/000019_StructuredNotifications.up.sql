@@ -0,0 +1,39 @@
+/* Structured notification payloads
+   actor_id, target_type, target_id and params let clients deep-link and let the
+   notification service re-render content at read time (names, locale).
+   content is kept as the pre-rendered fallback for older clients. */
+
+-- SQLite doesn't support ALTER TABLE for CHECK constraints, so the table is rebuilt.
+-- The type CHECK is dropped: types are validated by the notification service,
+-- which already emits 'group_activity' (rejected by the old constraint).
+CREATE TABLE notifications_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    type TEXT NOT NULL,
+    related_id INTEGER,
+    content TEXT NOT NULL,
+    actor_id INTEGER,
+    target_type TEXT CHECK (target_type IN ('user', 'group', 'event', 'post', 'comment', 'message')),
+    target_id INTEGER,
+    params TEXT,
+    is_read BOOLEAN NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Copy existing rows (structured columns stay NULL for legacy notifications)
+INSERT INTO notifications_new (id, user_id, type, related_id, content, is_read, created_at)
+SELECT id, user_id, type, related_id, content, is_read, created_at
+FROM notifications;
+
+DROP TABLE notifications;
+
+ALTER TABLE notifications_new RENAME TO notifications;
+
+CREATE INDEX idx_notifications_user_id ON notifications(user_id);
+CREATE INDEX idx_notifications_type ON notifications(type);
+CREATE INDEX idx_notifications_is_read ON notifications(is_read);
+CREATE INDEX idx_notifications_created_at ON notifications(created_at);
+CREATE INDEX idx_notifications_actor_id ON notifications(actor_id);
+CREATE INDEX idx_notifications_target ON notifications(target_type, target_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS email_digest_items;
+DROP TABLE IF EXISTS email_digests;
+DROP TABLE IF EXISTS notification_preferences;
@@ -0,0 +1,38 @@
+/* Notification preferences and email digest log */
+
+-- One row per user, created lazily by the notification service
+CREATE TABLE notification_preferences (
+    user_id INTEGER PRIMARY KEY,
+    digest_frequency TEXT CHECK (digest_frequency IN ('off', 'hourly', 'daily', 'weekly')) NOT NULL DEFAULT 'daily',
+    locale TEXT NOT NULL DEFAULT 'en',
+    unsubscribe_token TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    updated_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Each digest email (claimed as 'sending' before the SMTP call, 'sent' after)
+CREATE TABLE email_digests (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    frequency TEXT NOT NULL,
+    recipient TEXT NOT NULL,
+    notification_count INTEGER NOT NULL,
+    status TEXT CHECK (status IN ('sending', 'sent')) NOT NULL DEFAULT 'sending',
+    created_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    sent_at DATETIME,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_email_digests_user_created ON email_digests(user_id, created_at);
+
+-- Notifications included in a digest; UNIQUE(notification_id) means a
+-- notification can never be emailed twice
+CREATE TABLE email_digest_items (
+    digest_id INTEGER NOT NULL,
+    notification_id INTEGER NOT NULL UNIQUE,
+    FOREIGN KEY (digest_id) REFERENCES email_digests(id) ON DELETE CASCADE,
+    FOREIGN KEY (notification_id) REFERENCES notifications(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_email_digest_items_digest_id ON email_digest_items(digest_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS push_subscriptions;
+
+ALTER TABLE notification_preferences DROP COLUMN push_enabled;
@@ -0,0 +1,19 @@
+/* Web Push subscriptions and the per-user push switch */
+
+ALTER TABLE notification_preferences ADD COLUMN push_enabled INTEGER NOT NULL DEFAULT 1;
+
+-- One row per browser PushSubscription; endpoint is unique across users so a
+-- browser that changes accounts moves its subscription instead of duplicating it
+CREATE TABLE push_subscriptions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    endpoint TEXT NOT NULL UNIQUE,
+    p256dh TEXT NOT NULL,
+    auth TEXT NOT NULL,
+    user_agent TEXT,
+    created_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    last_success_at DATETIME,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_push_subscriptions_user_id ON push_subscriptions(user_id);
@@ -0,0 +1,5 @@
+CREATE INDEX idx_notifications_user_id ON notifications(user_id);
+
+DROP INDEX IF EXISTS idx_notifications_user_type_created;
+DROP INDEX IF EXISTS idx_notifications_user_read_created;
+DROP INDEX IF EXISTS idx_notifications_user_created;
@@ -0,0 +1,10 @@
+/* Composite indexes for cursor pagination of the notification list.
+   Each matches an ORDER BY created_at DESC, id DESC scan for one user,
+   optionally narrowed by read state or type. */
+
+CREATE INDEX idx_notifications_user_created ON notifications(user_id, created_at, id);
+CREATE INDEX idx_notifications_user_read_created ON notifications(user_id, is_read, created_at, id);
+CREATE INDEX idx_notifications_user_type_created ON notifications(user_id, type, created_at, id);
+
+-- Covered by the composite indexes above
+DROP INDEX IF EXISTS idx_notifications_user_id;
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_notification_preferences_quiet_pending;
+
+ALTER TABLE notification_preferences DROP COLUMN quiet_pending_count;
+ALTER TABLE notification_preferences DROP COLUMN quiet_pending_from_id;
+ALTER TABLE notification_preferences DROP COLUMN quiet_bypass;
+ALTER TABLE notification_preferences DROP COLUMN dnd_until;
+ALTER TABLE notification_preferences DROP COLUMN timezone;
+ALTER TABLE notification_preferences DROP COLUMN quiet_end;
+ALTER TABLE notification_preferences DROP COLUMN quiet_start;
+ALTER TABLE notification_preferences DROP COLUMN quiet_hours_enabled;
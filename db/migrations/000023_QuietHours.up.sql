@@ -0,0 +1,19 @@
+/* Quiet hours and do-not-disturb */
+
+-- Daily quiet window as local "HH:MM" in the user's IANA timezone (may wrap past midnight)
+ALTER TABLE notification_preferences ADD COLUMN quiet_hours_enabled INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE notification_preferences ADD COLUMN quiet_start TEXT NOT NULL DEFAULT '22:00';
+ALTER TABLE notification_preferences ADD COLUMN quiet_end TEXT NOT NULL DEFAULT '07:00';
+ALTER TABLE notification_preferences ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+
+-- Manual do-not-disturb, active until this time (NULL = off)
+ALTER TABLE notification_preferences ADD COLUMN dnd_until DATETIME;
+
+-- JSON array of rules that still deliver live, e.g. [{"type":"follow_request","from":"mutuals"}]
+ALTER TABLE notification_preferences ADD COLUMN quiet_bypass TEXT NOT NULL DEFAULT '[]';
+
+-- Notifications held back while quiet, summarised once the quiet period ends
+ALTER TABLE notification_preferences ADD COLUMN quiet_pending_from_id INTEGER;
+ALTER TABLE notification_preferences ADD COLUMN quiet_pending_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_notification_preferences_quiet_pending ON notification_preferences(quiet_pending_count) WHERE quiet_pending_count > 0;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_reactions_comment_deleted;
+DROP TRIGGER IF EXISTS trg_reactions_post_deleted;
+DROP TABLE IF EXISTS reactions;
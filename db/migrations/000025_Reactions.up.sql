@@ -0,0 +1,27 @@
+/* Emoji reactions on posts and comments. A user may add several different
+   emoji to the same target, but each emoji only once. */
+
+CREATE TABLE reactions (
+    target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment')),
+    target_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    emoji TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    PRIMARY KEY (target_type, target_id, user_id, emoji),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) WITHOUT ROWID;
+
+CREATE INDEX idx_reactions_user_id ON reactions(user_id);
+
+/* target_id can't carry a foreign key (it points at posts or comments),
+   so clean up with triggers. Comments deleted by the posts cascade fire
+   their own trigger. */
+CREATE TRIGGER trg_reactions_post_deleted AFTER DELETE ON posts
+BEGIN
+    DELETE FROM reactions WHERE target_type = 'post' AND target_id = OLD.id;
+END;
+
+CREATE TRIGGER trg_reactions_comment_deleted AFTER DELETE ON comments
+BEGIN
+    DELETE FROM reactions WHERE target_type = 'comment' AND target_id = OLD.id;
+END;
@@ -0,0 +1,19 @@
+/* Keyset pagination of the feed, group posts and search on (created_at, id).
+
+   Posts created by the post service stored Go's timestamp format
+   ("2006-01-02 15:04:05.999999999-07:00") while the column default uses
+   datetime('now'). Mixed formats don't compare correctly as cursors, so
+   normalize everything to UTC "YYYY-MM-DD HH:MM:SS"; id breaks ties. */
+
+UPDATE posts SET created_at = datetime(created_at)
+WHERE created_at IS NOT datetime(created_at);
+
+-- Feed and search scan group_id IS NULL, group pages scan one group_id
+CREATE INDEX idx_posts_group_created ON posts(group_id, created_at, id);
+-- A single author's posts (profile pages)
+CREATE INDEX idx_posts_user_created ON posts(user_id, created_at, id);
+
+-- Covered by the composite indexes above
+DROP INDEX IF EXISTS idx_posts_group_id;
+DROP INDEX IF EXISTS idx_posts_user_id;
+DROP INDEX IF EXISTS idx_posts_created_at;
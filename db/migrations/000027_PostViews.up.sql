@@ -0,0 +1,14 @@
+/* Posts a user has opened or scrolled past, used by the ranked feed to
+   push already-seen posts down. One row per user and post; seen_at is
+   the first time it was seen. */
+
+CREATE TABLE post_views (
+    user_id INTEGER NOT NULL,
+    post_id INTEGER NOT NULL,
+    seen_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+) WITHOUT ROWID;
+
+CREATE INDEX idx_post_views_post_id ON post_views(post_id);
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS trg_posts_fts_author;
+DROP TRIGGER IF EXISTS trg_posts_fts_delete;
+DROP TRIGGER IF EXISTS trg_posts_fts_update;
+DROP TRIGGER IF EXISTS trg_posts_fts_insert;
+DROP TABLE IF EXISTS posts_fts;
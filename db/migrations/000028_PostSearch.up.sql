@@ -0,0 +1,44 @@
+/* Full-text search over posts. One row per post with rowid = posts.id;
+   author holds the author's names so people can still be found by name.
+   Kept in sync by the triggers below.
+
+   Requires SQLite built with FTS5 (go-sqlite3 build tag sqlite_fts5). */
+
+CREATE VIRTUAL TABLE posts_fts USING fts5(
+    title,
+    content,
+    author,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO posts_fts (rowid, title, content, author)
+SELECT p.id, COALESCE(p.title, ''), p.content,
+       TRIM(COALESCE(u.first_name, '') || ' ' || COALESCE(u.last_name, '') || ' ' || u.username)
+FROM posts p
+INNER JOIN users u ON p.user_id = u.id;
+
+CREATE TRIGGER trg_posts_fts_insert AFTER INSERT ON posts
+BEGIN
+    INSERT INTO posts_fts (rowid, title, content, author)
+    SELECT NEW.id, COALESCE(NEW.title, ''), NEW.content,
+           TRIM(COALESCE(u.first_name, '') || ' ' || COALESCE(u.last_name, '') || ' ' || u.username)
+    FROM users u WHERE u.id = NEW.user_id;
+END;
+
+CREATE TRIGGER trg_posts_fts_update AFTER UPDATE OF title, content ON posts
+BEGIN
+    UPDATE posts_fts SET title = COALESCE(NEW.title, ''), content = NEW.content
+    WHERE rowid = NEW.id;
+END;
+
+CREATE TRIGGER trg_posts_fts_delete AFTER DELETE ON posts
+BEGIN
+    DELETE FROM posts_fts WHERE rowid = OLD.id;
+END;
+
+CREATE TRIGGER trg_posts_fts_author AFTER UPDATE OF first_name, last_name, username ON users
+BEGIN
+    UPDATE posts_fts
+    SET author = TRIM(COALESCE(NEW.first_name, '') || ' ' || COALESCE(NEW.last_name, '') || ' ' || NEW.username)
+    WHERE rowid IN (SELECT id FROM posts WHERE user_id = NEW.id);
+END;
@@ -0,0 +1,24 @@
+/* Hashtags parsed from post titles and content. tags holds each distinct
+   (lowercased) tag once; post_tags links posts to them. created_at is when
+   the tag was added to the post and drives trending. Posts created before
+   this migration are tagged the next time they are edited. */
+
+CREATE TABLE tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE TABLE post_tags (
+    post_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    PRIMARY KEY (post_id, tag_id),
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+) WITHOUT ROWID;
+
+-- Tag pages
+CREATE INDEX idx_post_tags_tag_id ON post_tags(tag_id, post_id);
+-- Trending window scan
+CREATE INDEX idx_post_tags_created_at ON post_tags(created_at);
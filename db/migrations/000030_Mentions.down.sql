@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS trg_mentions_comment_deleted;
+DROP TABLE IF EXISTS mentions;
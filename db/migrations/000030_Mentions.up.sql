@@ -0,0 +1,24 @@
+/* @username mentions in posts and comments, resolved when the content is
+   created or edited. post_id is the post the target belongs to, so
+   mentions go away with the post and "posts that mention me" is one
+   lookup. */
+
+CREATE TABLE mentions (
+    target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment')),
+    target_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    post_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    PRIMARY KEY (target_type, target_id, user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+) WITHOUT ROWID;
+
+CREATE INDEX idx_mentions_user_id ON mentions(user_id, post_id);
+CREATE INDEX idx_mentions_post_id ON mentions(post_id);
+
+-- Comments deleted on their own (not through their post)
+CREATE TRIGGER trg_mentions_comment_deleted AFTER DELETE ON comments
+BEGIN
+    DELETE FROM mentions WHERE target_type = 'comment' AND target_id = OLD.id;
+END;
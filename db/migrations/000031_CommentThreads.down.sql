@@ -0,0 +1,39 @@
+/* SQLite can't drop a column used in a foreign key, so rebuild the table.
+   Tombstones are dropped and replies become flat comments again. */
+
+DROP INDEX IF EXISTS idx_comments_parent;
+DROP INDEX IF EXISTS idx_comments_post_thread;
+
+CREATE TABLE comments_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    image_path TEXT,
+    created_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO comments_old (id, post_id, user_id, content, image_path, created_at)
+SELECT id, post_id, user_id, content, image_path, created_at
+FROM comments
+WHERE deleted_at IS NULL;
+
+-- Dropping the table drops its triggers too; recreated below
+DROP TABLE comments;
+ALTER TABLE comments_old RENAME TO comments;
+
+CREATE INDEX idx_comments_post_id ON comments(post_id);
+CREATE INDEX idx_comments_user_id ON comments(user_id);
+CREATE INDEX idx_comments_created_at ON comments(created_at);
+
+CREATE TRIGGER trg_reactions_comment_deleted AFTER DELETE ON comments
+BEGIN
+    DELETE FROM reactions WHERE target_type = 'comment' AND target_id = OLD.id;
+END;
+
+CREATE TRIGGER trg_mentions_comment_deleted AFTER DELETE ON comments
+BEGIN
+    DELETE FROM mentions WHERE target_type = 'comment' AND target_id = OLD.id;
+END;
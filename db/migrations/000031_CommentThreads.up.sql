@@ -0,0 +1,17 @@
+/* Threaded comment replies. depth is 0 for top-level comments and the
+   parent's depth + 1 for replies. A deleted comment that still has
+   replies becomes a tombstone (deleted_at set, content cleared) so the
+   thread stays readable; it is removed once its last reply is. */
+
+ALTER TABLE comments ADD COLUMN parent_comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE;
+ALTER TABLE comments ADD COLUMN depth INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN deleted_at DATETIME;
+
+-- Keyset pagination on (created_at, id), see 000026 for posts
+UPDATE comments SET created_at = datetime(created_at)
+WHERE created_at IS NOT datetime(created_at);
+
+-- Top-level comments of a post (parent_comment_id IS NULL)
+CREATE INDEX idx_comments_post_thread ON comments(post_id, parent_comment_id, created_at, id);
+-- Replies to a comment, and reply counts
+CREATE INDEX idx_comments_parent ON comments(parent_comment_id, created_at, id);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_comment_revisions_comment_id;
+DROP TABLE IF EXISTS comment_revisions;
+DROP INDEX IF EXISTS idx_post_revisions_post_id;
+DROP TABLE IF EXISTS post_revisions;
+
+ALTER TABLE comments DROP COLUMN edited_at;
+ALTER TABLE posts DROP COLUMN edited_at;
@@ -0,0 +1,30 @@
+/* Edit history. Every edit of a post or comment snapshots the version it
+   replaces; edited_at is the time of the latest edit (NULL if never
+   edited). Authors can purge the snapshots, edited_at stays. */
+
+ALTER TABLE posts ADD COLUMN edited_at DATETIME;
+ALTER TABLE comments ADD COLUMN edited_at DATETIME;
+
+CREATE TABLE post_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL,
+    title TEXT,
+    content TEXT NOT NULL,
+    image_path TEXT,
+    privacy_level TEXT NOT NULL,
+    replaced_at DATETIME NOT NULL, -- When the edit replaced this version
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_post_revisions_post_id ON post_revisions(post_id, id);
+
+CREATE TABLE comment_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    comment_id INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    image_path TEXT,
+    replaced_at DATETIME NOT NULL,
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_comment_revisions_comment_id ON comment_revisions(comment_id, id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_posts_unpublished;
+DROP INDEX IF EXISTS idx_posts_publish_at;
+
+-- Unpublished posts have no place in the old schema
+DELETE FROM posts WHERE status != 'published';
+
+ALTER TABLE posts DROP COLUMN publish_at;
+ALTER TABLE posts DROP COLUMN status;
@@ -0,0 +1,13 @@
+/* Drafts and scheduled posts. Only published posts are visible to anyone
+   but their author. A scheduled post has publish_at set; the posts
+   service's scheduler publishes it once due, setting created_at to the
+   publication time so it is listed as new. */
+
+ALTER TABLE posts ADD COLUMN status TEXT NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'scheduled', 'published'));
+ALTER TABLE posts ADD COLUMN publish_at DATETIME;
+
+-- The scheduler's due-posts scan
+CREATE INDEX idx_posts_publish_at ON posts(publish_at) WHERE status = 'scheduled';
+-- An author's drafts and scheduled posts
+CREATE INDEX idx_posts_unpublished ON posts(user_id) WHERE status != 'published';
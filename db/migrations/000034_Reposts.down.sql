@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS trg_posts_shared_deleted;
+DROP INDEX IF EXISTS idx_posts_unique_repost;
+DROP INDEX IF EXISTS idx_posts_shared_post_id;
+
+-- Reposts have no content of their own; quotes stay as plain posts
+DELETE FROM posts WHERE share_type = 'repost';
+
+ALTER TABLE posts DROP COLUMN share_type;
+ALTER TABLE posts DROP COLUMN shared_post_id;
@@ -0,0 +1,18 @@
+/* Reposts and quote posts. A share is a post referencing the shared post:
+   a repost has no content of its own, a quote adds commentary. Deleting
+   the original leaves shared_post_id NULL, so the share shows it as
+   unavailable (reposts of it drop out of feeds). */
+
+ALTER TABLE posts ADD COLUMN shared_post_id INTEGER;
+ALTER TABLE posts ADD COLUMN share_type TEXT NOT NULL DEFAULT ''
+    CHECK (share_type IN ('', 'repost', 'quote'));
+
+CREATE INDEX idx_posts_shared_post_id ON posts(shared_post_id, share_type);
+-- One repost per user and original
+CREATE UNIQUE INDEX idx_posts_unique_repost ON posts(user_id, shared_post_id) WHERE share_type = 'repost';
+
+-- A trigger rather than a foreign key, so the column can be dropped again
+CREATE TRIGGER trg_posts_shared_deleted AFTER DELETE ON posts
+BEGIN
+    UPDATE posts SET shared_post_id = NULL WHERE shared_post_id = OLD.id;
+END;
@@ -0,0 +1,38 @@
+/* Polls. A post carries at most one poll with 2 to 10 options. A vote is
+   one row per chosen option; single-choice polls allow one row per user
+   and poll, which the service enforces. closes_at is NULL for polls that
+   never close. */
+
+CREATE TABLE polls (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL UNIQUE,
+    multiple_choice BOOLEAN NOT NULL DEFAULT 0,
+    anonymous BOOLEAN NOT NULL DEFAULT 0,
+    hide_results BOOLEAN NOT NULL DEFAULT 0, -- Until the viewer votes or the poll closes
+    closes_at DATETIME,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE TABLE poll_options (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    poll_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+    UNIQUE (poll_id, position)
+);
+
+CREATE TABLE poll_votes (
+    poll_id INTEGER NOT NULL,
+    option_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (option_id, user_id),
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+    FOREIGN KEY (option_id) REFERENCES poll_options(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Counting a poll's votes and finding a user's votes
+CREATE INDEX idx_poll_votes_poll_id ON poll_votes(poll_id, user_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_media_content_hash;
+DROP INDEX IF EXISTS idx_media_unattached;
+DROP INDEX IF EXISTS idx_media_comment_id;
+DROP INDEX IF EXISTS idx_media_post_id;
+DROP TABLE IF EXISTS media;
@@ -0,0 +1,31 @@
+/* Media attachments. Every upload is recorded here by its uploader and
+   attached later to one post or comment, in order. Uploads that stay
+   unattached (post_id and comment_id NULL) past a grace period are
+   garbage-collected along with their files; deleting a post or comment
+   detaches its media, so the collector removes those files too.
+   image_path remains for the single-image clients. */
+
+CREATE TABLE media (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL, -- Uploader, the only user who can attach it
+    path TEXT NOT NULL UNIQUE, -- Relative, like image_path
+    content_type TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size INTEGER NOT NULL, -- Bytes
+    content_hash TEXT NOT NULL, -- Hex SHA-256 of the file
+    alt_text TEXT NOT NULL DEFAULT '',
+    post_id INTEGER,
+    comment_id INTEGER,
+    position INTEGER NOT NULL DEFAULT 0, -- Order within the post or comment
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE SET NULL,
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE SET NULL,
+    CHECK (post_id IS NULL OR comment_id IS NULL)
+);
+
+CREATE INDEX idx_media_post_id ON media(post_id, position) WHERE post_id IS NOT NULL;
+CREATE INDEX idx_media_comment_id ON media(comment_id, position) WHERE comment_id IS NOT NULL;
+CREATE INDEX idx_media_unattached ON media(created_at) WHERE post_id IS NULL AND comment_id IS NULL;
+CREATE INDEX idx_media_content_hash ON media(content_hash);
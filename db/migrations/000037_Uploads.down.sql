@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS trg_media_deleted_uploads;
+DROP TRIGGER IF EXISTS trg_comments_deleted_uploads;
+DROP TRIGGER IF EXISTS trg_posts_deleted_uploads;
+DROP INDEX IF EXISTS idx_uploads_owner;
+DROP TABLE IF EXISTS uploads;
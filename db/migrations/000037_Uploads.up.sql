@@ -0,0 +1,67 @@
+/* Who may see each uploaded post, comment and chat image. A file belongs
+   to its uploader until it is used, then to the one post, comment or
+   message that used it first: the upload handlers only serve it to
+   viewers of that owner. path is the storage key, the stored path
+   without its uploads/ prefix. */
+
+CREATE TABLE uploads (
+    path TEXT PRIMARY KEY, -- Storage key, like posts/9f86d081884c7d65.jpg
+    user_id INTEGER NOT NULL, -- Uploader
+    owner_type TEXT CHECK (owner_type IN ('post', 'comment', 'message')),
+    owner_id INTEGER,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CHECK ((owner_type IS NULL) = (owner_id IS NULL))
+);
+
+CREATE INDEX idx_uploads_owner ON uploads(owner_type, owner_id) WHERE owner_type IS NOT NULL;
+
+/* Backfill from the existing references, first use wins. Uploads that
+   were never used stay with their uploader. */
+INSERT OR IGNORE INTO uploads (path, user_id, owner_type, owner_id, created_at)
+SELECT path, user_id, owner_type, owner_id, created_at FROM (
+    SELECT CASE WHEN path LIKE '/uploads/%' THEN substr(path, 10) ELSE substr(path, 9) END AS path, user_id, CASE WHEN post_id IS NOT NULL THEN 'post' ELSE 'comment' END AS owner_type,
+        COALESCE(post_id, comment_id) AS owner_id, created_at
+    FROM media
+    WHERE (post_id IS NOT NULL OR comment_id IS NOT NULL) AND (path LIKE 'uploads/%' OR path LIKE '/uploads/%')
+    UNION ALL
+    SELECT CASE WHEN image_path LIKE '/uploads/%' THEN substr(image_path, 10) ELSE substr(image_path, 9) END, user_id, 'post', id, created_at
+    FROM posts
+    WHERE image_path LIKE 'uploads/%' OR image_path LIKE '/uploads/%'
+    UNION ALL
+    SELECT CASE WHEN image_path LIKE '/uploads/%' THEN substr(image_path, 10) ELSE substr(image_path, 9) END, user_id, 'comment', id, created_at
+    FROM comments
+    WHERE image_path LIKE 'uploads/%' OR image_path LIKE '/uploads/%'
+    UNION ALL
+    SELECT CASE WHEN image_path LIKE '/uploads/%' THEN substr(image_path, 10) ELSE substr(image_path, 9) END, sender_id, 'message', id, created_at
+    FROM messages
+    WHERE image_path LIKE 'uploads/%' OR image_path LIKE '/uploads/%'
+)
+ORDER BY created_at;
+
+INSERT OR IGNORE INTO uploads (path, user_id, created_at)
+SELECT CASE WHEN path LIKE '/uploads/%' THEN substr(path, 10) ELSE substr(path, 9) END, user_id, created_at
+FROM media
+WHERE path LIKE 'uploads/%' OR path LIKE '/uploads/%';
+
+/* Files of deleted posts and comments are no longer served to anyone,
+   and their media becomes collectable. Files removed by the media
+   collector lose their row too. */
+CREATE TRIGGER IF NOT EXISTS trg_posts_deleted_uploads
+AFTER DELETE ON posts
+BEGIN
+    DELETE FROM uploads WHERE owner_type = 'post' AND owner_id = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_comments_deleted_uploads
+AFTER DELETE ON comments
+BEGIN
+    DELETE FROM uploads WHERE owner_type = 'comment' AND owner_id = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_media_deleted_uploads
+AFTER DELETE ON media
+BEGIN
+    DELETE FROM uploads
+    WHERE path = CASE WHEN OLD.path LIKE '/uploads/%' THEN substr(OLD.path, 10) ELSE substr(OLD.path, 9) END;
+END;
@@ -0,0 +1,30 @@
+/* Bookmarks: posts a user saved for later, only visible to them. A
+   bookmark can be filed in one of the user's named collections; deleting
+   a collection keeps its bookmarks, unfiled. Bookmarks of deleted posts
+   go with them; posts the user can no longer see are filtered out when
+   listing. */
+
+CREATE TABLE bookmark_collections (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE bookmarks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    post_id INTEGER NOT NULL,
+    collection_id INTEGER, -- NULL if not filed in a collection
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (collection_id) REFERENCES bookmark_collections(id) ON DELETE SET NULL,
+    UNIQUE (user_id, post_id)
+);
+
+-- Listing a user's bookmarks, all or by collection, newest first
+CREATE INDEX idx_bookmarks_user ON bookmarks(user_id, created_at, id);
+CREATE INDEX idx_bookmarks_collection ON bookmarks(collection_id, created_at, id);
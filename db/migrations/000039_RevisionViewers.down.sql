@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS trg_post_revisions_deleted_viewers;
+DROP TABLE IF EXISTS post_revision_viewers;
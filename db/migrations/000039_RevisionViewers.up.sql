@@ -0,0 +1,20 @@
+/* The viewers of a "private" post when each revision was replaced, so
+   that edit history stays as private as the versions it keeps: widening
+   a post doesn't show its earlier versions to the new audience.
+   Revisions kept before this have no list, so their private versions
+   are only visible to the author. */
+
+CREATE TABLE post_revision_viewers (
+    revision_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    PRIMARY KEY (revision_id, user_id),
+    FOREIGN KEY (revision_id) REFERENCES post_revisions(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) WITHOUT ROWID;
+
+/* Revisions are also removed with their post, so don't depend on the
+   connection enforcing the cascade */
+CREATE TRIGGER trg_post_revisions_deleted_viewers AFTER DELETE ON post_revisions
+BEGIN
+    DELETE FROM post_revision_viewers WHERE revision_id = OLD.id;
+END;
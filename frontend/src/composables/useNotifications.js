@@ -29,6 +29,9 @@ const reconnectAttempts = ref(0)
 const notifications = ref([])
 const unreadCount = ref(0)
 
+// Highest event_id received, sent on reconnect so the server replays missed notifications
+let lastEventId = 0
+
 // Event listeners registry
 const eventListeners = ref(new Map())
 
@@ -86,7 +89,10 @@ export function useNotifications() {
       // WHY QUERY PARAMS:
       // Your backend: middleware.GetUserIDFromContext(r)
       // Token validates and provides user ID
-      const wsUrl = `${config.notificationsUrl}?token=${encodeURIComponent(token)}`
+      let wsUrl = `${config.notificationsUrl}?token=${encodeURIComponent(token)}`
+      if (lastEventId > 0) {
+        wsUrl += `&last_event_id=${lastEventId}`
+      }
       
       ws.value = new WebSocket(wsUrl)
 
@@ -255,9 +261,23 @@ export function useNotifications() {
   function handleIncomingNotification(data) {
     const { type } = data
 
+    if (data.event_id > lastEventId) {
+      lastEventId = data.event_id
+    }
+
     if (type === 'notification') {
       // New notification arrived
       handleNewNotification(data.notification)
+    } else if (type === 'summary') {
+      // Quiet hours ended: notifications held back meanwhile are already
+      // stored, so refresh the list instead of toasting each one
+      loadNotifications()
+      requestUnreadCount()
+      emit('summary', data.summary)
+    } else if (type === 'resync') {
+      // Missed too much while disconnected, reload from the REST API
+      loadNotifications()
+      requestUnreadCount()
     } else {
       console.warn('Unknown notification type:', type)
     }
@@ -273,6 +293,11 @@ export function useNotifications() {
    * - No need to refetch from API
    */
   function handleNewNotification(notification) {
+    // Replayed notifications may already be in the list loaded on connect
+    if (notifications.value.some(n => n.id === notification.id)) {
+      return
+    }
+
     // Add to notifications array
     notifications.value.unshift(notification)
     
@@ -340,6 +365,9 @@ export function useNotifications() {
       'new_follower': '👥 New Follower',
       'like': '❤️ New Like',
       'comment': '💬 New Comment',
+      'reaction': '😊 New Reaction',
+      'mention': '@ New Mention',
+      'repost': '🔁 New Repost',
       'group_invite': '👥 Group Invitation',
       'event_invite': '📅 Event Invitation',
       'new_message': '✉️ New Message'
@@ -627,6 +655,7 @@ export function useNotifications() {
   function clearNotifications() {
     notifications.value = []
     unreadCount.value = 0
+    lastEventId = 0
   }
 
   /**
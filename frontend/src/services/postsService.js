@@ -98,6 +98,88 @@ export async function deletePost(postId, token) {
   return unwrapResponse(response)
 }
 
+export async function repostPost(postId, token) {
+  const response = await client.post(`/posts/${postId}/repost`, null, {
+    headers: {
+      Authorization: `Bearer ${token}`
+    }
+  })
+
+  return unwrapResponse(response)
+}
+
+export async function undoRepost(postId, token) {
+  const response = await client.delete(`/posts/${postId}/repost`, {
+    headers: {
+      Authorization: `Bearer ${token}`
+    }
+  })
+
+  return unwrapResponse(response)
+}
+
+export async function bookmarkPost(postId, collectionId, token) {
+  const body = collectionId ? { collection_id: collectionId } : null
+  const response = await client.post(`/posts/${postId}/bookmark`, body, {
+    headers: {
+      Authorization: `Bearer ${token}`
+    }
+  })
+
+  return unwrapResponse(response)
+}
+
+export async function unbookmarkPost(postId, token) {
+  const response = await client.delete(`/posts/${postId}/bookmark`, {
+    headers: {
+      Authorization: `Bearer ${token}`
+    }
+  })
+
+  return unwrapResponse(response)
+}
+
+export async function getBookmarks({ collectionId, before, limit } = {}, token) {
+  const response = await client.get('/posts/bookmarks', {
+    params: { collection_id: collectionId, before, limit },
+    headers: {
+      Authorization: `Bearer ${token}`
+    }
+  })
+
+  return unwrapResponse(response)
+}
+
+export async function getBookmarkCollections(token) {
+  const response = await client.get('/posts/bookmarks/collections', {
+    headers: {
+      Authorization: `Bearer ${token}`
+    }
+  })
+
+  return unwrapResponse(response)
+}
+
+export async function createBookmarkCollection(name, token) {
+  const response = await client.post('/posts/bookmarks/collections', { name }, {
+    headers: {
+      Authorization: `Bearer ${token}`
+    }
+  })
+
+  return unwrapResponse(response)
+}
+
+export async function votePoll(postId, optionIds, token) {
+  const response = await client.post(`/posts/${postId}/poll/vote`, { option_ids: optionIds }, {
+    headers: {
+      Authorization: `Bearer ${token}`
+    }
+  })
+
+  return unwrapResponse(response)
+}
+
 export async function getComments(postId, token) {
   const response = await client.get('/comments', {
     params: { post_id: postId },
@@ -153,8 +235,15 @@ export async function deleteComment(commentId, token) {
   return unwrapResponse(response)
 }
 
-export async function getFeedPosts(token) {
+/**
+ * Get one page of the feed, newest first
+ * @param {string} token - Auth token
+ * @param {string} [before] - next_cursor from the previous page
+ * @returns {Promise<{posts: Array, has_more: boolean, next_cursor: string|null}>}
+ */
+export async function getFeedPosts(token, before) {
   const response = await client.get('/posts/feed', {
+    params: before ? { before } : {},
     headers: {
       Authorization: `Bearer ${token}`
     }
@@ -163,9 +252,9 @@ export async function getFeedPosts(token) {
   return unwrapResponse(response)
 }
 
-export async function searchPosts(searchTerm, token) {
+export async function searchPosts(searchTerm, token, before) {
   const response = await client.get('/posts/search', {
-    params: { q: searchTerm },
+    params: before ? { q: searchTerm, before } : { q: searchTerm },
     headers: {
       Authorization: `Bearer ${token}`
     }
@@ -174,28 +263,25 @@ export async function searchPosts(searchTerm, token) {
   return unwrapResponse(response)
 }
 
-export async function getUserPosts(userId, token) {
-  // Get feed posts and filter by user ID on the frontend
+export async function getUserPosts(userId, token, before) {
+  // The feed filtered to one author, so the same visibility rules apply
+  const params = { user_id: userId, limit: 100 }
+  if (before) params.before = before
+
   const response = await client.get('/posts/feed', {
+    params,
     headers: {
       Authorization: `Bearer ${token}`
     }
   })
 
   const data = unwrapResponse(response)
-  
-  // Filter posts by the target user ID
-  if (data.posts && Array.isArray(data.posts)) {
-    return {
-      posts: data.posts.filter(post => post.user_id === userId)
-    }
-  }
-  
-  return { posts: [] }
+  return { ...data, posts: Array.isArray(data.posts) ? data.posts : [] }
 }
 
-export async function getGroupPosts(groupId, token) {
+export async function getGroupPosts(groupId, token, before) {
   const response = await client.get(`/posts/group/${groupId}`, {
+    params: before ? { before } : {},
     headers: {
       Authorization: `Bearer ${token}`
     }
@@ -6,13 +6,21 @@ import (
 	"social-network/services/chat/models"
 )
 
-// SaveMessage stores a new message in the database
+// SaveMessage stores a new message in the database. Its image, if any,
+// becomes the message's; ErrImageNotFound is returned if it isn't an unused
+// upload of the sender.
 func SaveMessage(db *sql.DB, msg *models.Message) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO messages (sender_id, recipient_id, content, is_read, created_at, image_path)
 		VALUES (?, ?, ?, ?, ?, ?)
 	`
-	result, err := db.Exec(query, msg.SenderID, msg.ReceiverID, msg.Content, msg.IsRead, msg.CreatedAt, msg.ImagePath)
+	result, err := tx.Exec(query, msg.SenderID, msg.ReceiverID, msg.Content, msg.IsRead, msg.CreatedAt, msg.ImagePath)
 	if err != nil {
 		return err
 	}
@@ -21,8 +29,13 @@ func SaveMessage(db *sql.DB, msg *models.Message) error {
 	if err != nil {
 		return err
 	}
+	if msg.ImagePath != nil && *msg.ImagePath != "" {
+		if err := claimUpload(tx, *msg.ImagePath, msg.SenderID, int(id)); err != nil {
+			return err
+		}
+	}
 	msg.ID = int(id)
-	return nil
+	return tx.Commit()
 }
 
 // GetChatHistory retrieves all messages between two users
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"social-network/services/common/storage"
+	"time"
+)
+
+// ErrImageNotFound is returned when a message uses an image that isn't an
+// unused upload of its sender
+var ErrImageNotFound = errors.New("image not found")
+
+// CreateUpload records an uploaded file, owned by its uploader until a
+// message uses it
+func CreateUpload(db *sql.DB, key string, userID int) error {
+	_, err := db.Exec(`INSERT INTO uploads (path, user_id, created_at) VALUES (?, ?, ?)`,
+		key, userID, time.Now().UTC().Format("2006-01-02 15:04:05"))
+	return err
+}
+
+// DeleteUnusedUpload removes the record of a file the user uploaded and no
+// message uses. Returns false if there is no such file.
+func DeleteUnusedUpload(db *sql.DB, key string, userID int) (bool, error) {
+	result, err := db.Exec(`DELETE FROM uploads WHERE path = ? AND user_id = ? AND owner_type IS NULL`, key, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// CanViewUpload reports whether a user may see an uploaded chat image:
+// unused uploads only by their uploader, the others by both sides of the
+// message that uses them
+func CanViewUpload(db *sql.DB, key string, userID int) (bool, error) {
+	var allowed bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM uploads u
+			LEFT JOIN messages m ON u.owner_type = 'message' AND m.id = u.owner_id
+			WHERE u.path = ? AND (
+				(u.owner_type IS NULL AND u.user_id = ?) OR
+				(m.sender_id = ? OR m.recipient_id = ?)
+			)
+		)
+	`, key, userID, userID, userID).Scan(&allowed)
+	return allowed, err
+}
+
+// claimUpload makes a message the owner of the image it uses, which must
+// be an unused upload of its sender
+func claimUpload(tx *sql.Tx, path string, senderID, messageID int) error {
+	result, err := tx.Exec(`
+		UPDATE uploads SET owner_type = 'message', owner_id = ?
+		WHERE path = ? AND user_id = ? AND owner_type IS NULL
+	`, messageID, storage.Key(path), senderID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return ErrImageNotFound
+	}
+	return nil
+}
@@ -78,6 +78,9 @@ func (h *ChatHandlers) GetChatHistory(w http.ResponseWriter, r *http.Request) {
 		utils.ErrorResponse(w, "Failed to retrieve messages", http.StatusInternalServerError)
 		return
 	}
+	for i := range messages {
+		messages[i].ImageURL = h.hub.imageURL(messages[i].ImagePath)
+	}
 
 	utils.SuccessResponse(w, map[string]interface{}{
 		"messages": messages,
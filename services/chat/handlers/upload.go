@@ -1,31 +1,72 @@
 package handlers
 
 import (
-	"fmt"
-	"io"
+	"database/sql"
+	"errors"
+	"log"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
+	"path"
 	"time"
 
+	"social-network/services/chat/db"
 	"social-network/services/chat/middleware"
 	"social-network/services/chat/utils"
+	"social-network/services/common/imaging"
+	"social-network/services/common/storage"
 )
 
 const (
 	maxUploadSize = 5 << 20 // 5MB
-	uploadDir     = "./uploads/chat"
+	uploadDir     = "chat"  // Storage key prefix
 )
 
+// imageURLTTL is how long the signed image URLs of responses work
+const imageURLTTL = 12 * time.Hour
+
+// uploadLimits are the image limits of chat uploads
+var uploadLimits = imaging.Limits{
+	MaxBytes:     maxUploadSize,
+	MaxDimension: imaging.DefaultLimits.MaxDimension,
+	MaxPixels:    imaging.DefaultLimits.MaxPixels,
+	MaxGIFPixels: imaging.DefaultLimits.MaxGIFPixels,
+}
+
 // UploadHandlers handles file upload requests
-type UploadHandlers struct{}
+type UploadHandlers struct {
+	database *sql.DB
+	store    storage.Storage
+}
 
 // NewUploadHandlers creates a new upload handlers instance
-func NewUploadHandlers() *UploadHandlers {
-	// Ensure upload directory exists
-	os.MkdirAll(uploadDir, os.ModePerm)
-	return &UploadHandlers{}
+func NewUploadHandlers(database *sql.DB, store storage.Storage) *UploadHandlers {
+	return &UploadHandlers{database: database, store: store}
+}
+
+// CanViewUpload is the storage.Authorizer of chat images: they are served
+// to the two sides of the message using them, or to their uploader until
+// then
+func (h *UploadHandlers) CanViewUpload(r *http.Request, key string) (bool, error) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		return false, nil
+	}
+	return db.CanViewUpload(h.database, key, userID)
+}
+
+// imageURL returns the signed URL of a message image path, or "" if there
+// is none or it isn't in the storage
+func (h *Hub) imageURL(path *string) string {
+	if path == nil || *path == "" {
+		return ""
+	}
+	url, err := h.store.SignedURL(storage.Key(*path), imageURLTTL)
+	if err != nil {
+		if !errors.Is(err, storage.ErrInvalidKey) {
+			log.Printf("Error signing URL of %s: %v", *path, err)
+		}
+		return ""
+	}
+	return url
 }
 
 // UploadImage handles POST /upload/image requests
@@ -50,44 +91,56 @@ func (h *UploadHandlers) UploadImage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get the file from form
-	file, fileHeader, err := r.FormFile("image")
+	file, _, err := r.FormFile("image")
 	if err != nil {
 		utils.ErrorResponse(w, "No file provided", http.StatusBadRequest)
 		return
 	}
 	defer file.Close()
 
-	// Validate file type
-	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
-	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".gif" {
-		utils.ErrorResponse(w, "Invalid file type. Only JPG, PNG, and GIF allowed", http.StatusBadRequest)
+	// Identify, check and re-encode the image (stripping its metadata)
+	img, err := imaging.Process(file, uploadLimits)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Generate unique filename
-	timestamp := time.Now().Unix()
-	filename := fmt.Sprintf("%d_%d%s", userID, timestamp, ext)
-	filePath := filepath.Join(uploadDir, filename)
-
-	// Create the file on disk
-	dst, err := os.Create(filePath)
+	// Save it with its thumbnails under a unique name
+	name, err := storage.RandomName()
 	if err != nil {
 		utils.ErrorResponse(w, "Failed to save file", http.StatusInternalServerError)
 		return
 	}
-	defer dst.Close()
+	key, err := img.Save(r.Context(), h.store, uploadDir, name)
+	if err != nil {
+		log.Printf("Error saving upload: %v", err)
+		utils.ErrorResponse(w, "Failed to save file", http.StatusInternalServerError)
+		return
+	}
 
-	// Copy file content
-	if _, err := io.Copy(dst, file); err != nil {
+	// It's only visible to the uploader until a message uses it
+	if err := db.CreateUpload(h.database, key, userID); err != nil {
+		log.Printf("Error recording upload: %v", err)
+		imaging.Remove(r.Context(), h.store, key)
 		utils.ErrorResponse(w, "Failed to save file", http.StatusInternalServerError)
 		return
 	}
 
-	// Return the file path (relative URL with leading slash)
-	relativePath := fmt.Sprintf("/uploads/chat/%s", filename)
-	utils.SuccessResponse(w, map[string]string{
+	// Return the file path (relative URL with leading slash), and where
+	// the image and its thumbnails can be loaded for now
+	relativePath := storage.Path(key)
+	thumbnails := map[int]string{}
+	for size, thumbKey := range imaging.ThumbnailPaths(key) {
+		thumbnails[size], _ = h.store.SignedURL(thumbKey, imageURLTTL)
+	}
+	imageURL, _ := h.store.SignedURL(key, imageURLTTL)
+	utils.SuccessResponse(w, map[string]interface{}{
 		"image_path": relativePath,
-		"filename":   filename,
+		"image_url":  imageURL,
+		"filename":   path.Base(key),
+		"width":      img.Width,
+		"height":     img.Height,
+		"thumbnails": thumbnails,
 	})
 }
 
@@ -99,7 +152,7 @@ func (h *UploadHandlers) DeleteImage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get authenticated user ID from context
-	_, ok := middleware.GetUserIDFromContext(r)
+	userID, ok := middleware.GetUserIDFromContext(r)
 	if !ok {
 		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -113,17 +166,21 @@ func (h *UploadHandlers) DeleteImage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract filename from path
-	filename := filepath.Base(imagePath)
-	fullPath := filepath.Join(uploadDir, filename)
+	key := uploadDir + "/" + path.Base(imagePath)
 
-	// Check if file exists
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+	// Only the uploader can delete a file, and only while no message uses it
+	deleted, err := db.DeleteUnusedUpload(h.database, key, userID)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to delete file", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
 		utils.ErrorResponse(w, "File not found", http.StatusNotFound)
 		return
 	}
 
-	// Delete the file
-	if err := os.Remove(fullPath); err != nil {
+	// Delete the file and its thumbnails
+	if err := imaging.Remove(r.Context(), h.store, key); err != nil {
 		utils.ErrorResponse(w, "Failed to delete file", http.StatusInternalServerError)
 		return
 	}
@@ -10,6 +10,7 @@ import (
 	"social-network/services/chat/models"
 	"social-network/services/chat/utils"
 	"social-network/services/common/notify"
+	"social-network/services/common/storage"
 	"sync"
 	"time"
 
@@ -30,6 +31,7 @@ type Hub struct {
 	unregister chan *Client
 	mu         sync.RWMutex
 	database   *sql.DB
+	store      storage.Storage
 }
 
 // Client represents a WebSocket client connection
@@ -41,9 +43,10 @@ type Client struct {
 	username string
 }
 
-// NewHub creates a new Hub instance
-func NewHub(database *sql.DB) *Hub {
+// NewHub creates a new Hub instance. Chat images are kept in store.
+func NewHub(database *sql.DB, store storage.Storage) *Hub {
 	return &Hub{
+		store:      store,
 		clients:    make(map[int]*Client),
 		broadcast:  make(chan *models.WebSocketMessage, 256),
 		register:   make(chan *Client),
@@ -292,7 +295,10 @@ func (c *Client) handleChatMessage(wsMsg *models.WebSocketMessage) {
 		CreatedAt:  time.Now(),
 	}
 
-	if err := db.SaveMessage(c.hub.database, msg); err != nil {
+	if err := db.SaveMessage(c.hub.database, msg); err == db.ErrImageNotFound {
+		c.sendError("Image not found")
+		return
+	} else if err != nil {
 		log.Printf("Error saving message: %v", err)
 		c.sendError("Failed to save message")
 		return
@@ -301,6 +307,7 @@ func (c *Client) handleChatMessage(wsMsg *models.WebSocketMessage) {
 	// Update WebSocket message with database ID and sanitized content
 	wsMsg.MessageID = msg.ID
 	wsMsg.Content = sanitizedContent
+	wsMsg.ImageURL = c.hub.imageURL(wsMsg.ImagePath)
 	wsMsg.Type = "message"
 
 	// Broadcast to receiver if online
@@ -308,7 +315,7 @@ func (c *Client) handleChatMessage(wsMsg *models.WebSocketMessage) {
 
 	// Send notification if receiver is offline
 	if !c.hub.IsUserOnline(wsMsg.ReceiverID) {
-		notify.NewMessage(wsMsg.ReceiverID, msg.ID, c.username)
+		notify.NewMessage(wsMsg.ReceiverID, msg.ID, c.userID, c.username)
 	}
 
 	// Send confirmation back to sender
@@ -406,8 +413,9 @@ func (c *Client) handleGroupChatMessage(wsMsg *models.WebSocketMessage) {
 			}
 		}
 		if len(offlineMemberIDs) > 0 {
-			// Use generic group name since chat service doesn't have access to group details
-			notify.NewGroupMessage(offlineMemberIDs, msg.ID, c.userID, c.username, "group chat")
+			// Generic group name for the fallback content; the notification service
+			// resolves the real group name from the target at read time
+			notify.NewGroupMessage(offlineMemberIDs, wsMsg.GroupID, msg.ID, c.userID, c.username, "group chat")
 		}
 	}
 }
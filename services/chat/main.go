@@ -10,6 +10,7 @@ import (
 	"social-network/services/chat/handlers"
 	"social-network/services/chat/middleware"
 	"social-network/services/common/authcache"
+	"social-network/services/common/storage"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -36,13 +37,19 @@ func main() {
 	authServiceURL := middleware.GetAuthServiceURL()
 	log.Printf("Auth service URL: %s", authServiceURL)
 
+	// Chat images live in the shared storage
+	store, err := storage.FromEnv()
+	if err != nil {
+		log.Fatalf("Failed to set up upload storage: %v", err)
+	}
+
 	// Create WebSocket hub
-	hub := handlers.NewHub(database)
+	hub := handlers.NewHub(database, store)
 	go hub.Run()
 
 	// Create handlers
 	chatHandlers := handlers.NewChatHandlers(database, hub)
-	uploadHandlers := handlers.NewUploadHandlers()
+	uploadHandlers := handlers.NewUploadHandlers(database, store)
 
 	// Create auth middleware and rate limiter
 	authMiddleware := authcache.AuthMiddleware(authServiceURL)
@@ -70,9 +77,8 @@ func main() {
 	mux.Handle("/upload/image", authMiddleware(rateLimiter.RateLimit(http.HandlerFunc(uploadHandlers.UploadImage))))
 	mux.Handle("/upload/delete", authMiddleware(rateLimiter.RateLimit(http.HandlerFunc(uploadHandlers.DeleteImage))))
 
-	// Static file server for uploaded images (no auth required for viewing)
-	fs := http.FileServer(http.Dir("./uploads"))
-	mux.Handle("/uploads/", http.StripPrefix("/uploads/", fs))
+	// Uploaded images, to the two sides of the message using them
+	mux.Handle(storage.PathPrefix, storage.AuthorizedHandler(store, authMiddleware, uploadHandlers.CanViewUpload))
 
 	// Group chat endpoints (auth required + rate limited for writes)
 	mux.Handle("/chat/groups/", authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -9,6 +9,7 @@ type Message struct {
 	ReceiverID int       `json:"receiver_id"`
 	Content    string    `json:"content"`
 	ImagePath  *string   `json:"image_path,omitempty"`
+	ImageURL   string    `json:"image_url,omitempty"` // Signed URL of image_path, expires
 	IsRead     bool      `json:"is_read"`
 	CreatedAt  time.Time `json:"created_at"`
 }
@@ -57,6 +58,7 @@ type WebSocketMessage struct {
 	GroupID    int       `json:"group_id,omitempty"`    // For group chat
 	Content    string    `json:"content,omitempty"`
 	ImagePath  *string   `json:"image_path,omitempty"`
+	ImageURL   string    `json:"image_url,omitempty"` // Signed URL of image_path, set by the server
 	Timestamp  time.Time `json:"timestamp"`
 	Error      string    `json:"error,omitempty"`
 }
@@ -0,0 +1,337 @@
+// Package imaging is the upload pipeline shared by the services that store
+// user images (posts, chat, avatars, group images). Uploads are identified
+// by their magic bytes rather than their name or Content-Type, checked
+// against pixel limits before being decoded, and re-encoded with the
+// standard library codecs, which drops EXIF (GPS included) and any other
+// metadata. Every image gets thumbnails at the fixed ThumbnailSizes.
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"path"
+	"strings"
+
+	"social-network/services/common/storage"
+)
+
+// Formats accepted by Process
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatGIF  = "gif"
+)
+
+// ThumbnailSizes are the longest sides, in pixels, of the thumbnails made
+// for every image. Images smaller than a size are not upscaled.
+var ThumbnailSizes = []int{160, 640}
+
+// Limits bound what Process accepts
+type Limits struct {
+	MaxBytes     int64 // Size of the upload
+	MaxDimension int   // Width or height
+	MaxPixels    int   // Width x height
+	MaxGIFPixels int   // Width x height x frames, for animations
+}
+
+// DefaultLimits suits photos from current phones
+var DefaultLimits = Limits{
+	MaxBytes:     10 << 20,
+	MaxDimension: 8192,
+	MaxPixels:    25_000_000,
+	MaxGIFPixels: 100_000_000,
+}
+
+// Errors returned by Process. Their messages are fit for API responses.
+var (
+	ErrFileTooLarge      = errors.New("file too large")
+	ErrUnsupportedFormat = errors.New("unsupported image format: only JPEG, PNG and GIF are allowed")
+	ErrInvalidImage      = errors.New("invalid image file")
+	ErrImageTooLarge     = errors.New("image dimensions too large")
+)
+
+// Image is a processed upload, ready to be stored
+type Image struct {
+	Format      string // FormatJPEG, FormatPNG or FormatGIF
+	ContentType string
+	Ext         string // Canonical file extension for Format
+	Width       int
+	Height      int
+	Data        []byte // Re-encoded, without metadata
+	Thumbnails  []Thumbnail
+}
+
+// Thumbnail is a downscaled copy of an Image. JPEG images get JPEG
+// thumbnails; PNG and GIF images get PNG ones (first frame of animations).
+type Thumbnail struct {
+	Size   int // The ThumbnailSizes entry it was made for
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// Sniff identifies an image format from the first bytes of a file
+func Sniff(header []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(header, []byte{0xFF, 0xD8, 0xFF}):
+		return FormatJPEG, nil
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG, nil
+	case bytes.HasPrefix(header, []byte("GIF87a")), bytes.HasPrefix(header, []byte("GIF89a")):
+		return FormatGIF, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+// Process reads an uploaded image and re-encodes it. The dimensions are
+// checked from the header before anything is decoded, so small files that
+// decompress to huge images are rejected cheaply.
+func Process(r io.Reader, limits Limits) (*Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limits.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limits.MaxBytes {
+		return nil, ErrFileTooLarge
+	}
+
+	format, err := Sniff(data)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := decodeConfig(format, data)
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if err := checkDimensions(config.Width, config.Height, limits); err != nil {
+		return nil, err
+	}
+
+	var img *Image
+	var still image.Image // What the thumbnails are made from
+	switch format {
+	case FormatJPEG:
+		img, still, err = processJPEG(data)
+	case FormatPNG:
+		img, still, err = processPNG(data)
+	case FormatGIF:
+		img, still, err = processGIF(data, config, limits)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, size := range ThumbnailSizes {
+		thumb, err := makeThumbnail(still, size, format)
+		if err != nil {
+			return nil, err
+		}
+		img.Thumbnails = append(img.Thumbnails, thumb)
+	}
+	return img, nil
+}
+
+// decodeConfig reads the dimensions of an image of a known format
+func decodeConfig(format string, data []byte) (image.Config, error) {
+	switch format {
+	case FormatJPEG:
+		return jpeg.DecodeConfig(bytes.NewReader(data))
+	case FormatPNG:
+		return png.DecodeConfig(bytes.NewReader(data))
+	default:
+		return gif.DecodeConfig(bytes.NewReader(data))
+	}
+}
+
+// checkDimensions applies the pixel limits
+func checkDimensions(width, height int, limits Limits) error {
+	if width <= 0 || height <= 0 {
+		return ErrInvalidImage
+	}
+	if width > limits.MaxDimension || height > limits.MaxDimension || width*height > limits.MaxPixels {
+		return ErrImageTooLarge
+	}
+	return nil
+}
+
+// processJPEG re-encodes a JPEG upright: the EXIF orientation is applied
+// to the pixels, since the tag itself doesn't survive re-encoding
+func processJPEG(data []byte) (*Image, image.Image, error) {
+	decoded, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ErrInvalidImage
+	}
+	upright := applyOrientation(decoded, exifOrientation(data))
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, upright, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, nil, err
+	}
+	return newImage(FormatJPEG, upright.Bounds(), buf.Bytes()), upright, nil
+}
+
+// processPNG re-encodes a PNG, dropping its text and other ancillary chunks
+func processPNG(data []byte) (*Image, image.Image, error) {
+	decoded, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ErrInvalidImage
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, decoded); err != nil {
+		return nil, nil, err
+	}
+	return newImage(FormatPNG, decoded.Bounds(), buf.Bytes()), decoded, nil
+}
+
+// processGIF re-encodes a GIF, keeping its animation but not its comment
+// and application extensions. The frames are counted before decoding, as
+// each one costs a full frame of memory.
+func processGIF(data []byte, config image.Config, limits Limits) (*Image, image.Image, error) {
+	frames, err := countGIFFrames(data)
+	if err != nil {
+		return nil, nil, ErrInvalidImage
+	}
+	if frames*config.Width*config.Height > limits.MaxGIFPixels {
+		return nil, nil, ErrImageTooLarge
+	}
+
+	decoded, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil || len(decoded.Image) == 0 {
+		return nil, nil, ErrInvalidImage
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, decoded); err != nil {
+		return nil, nil, err
+	}
+
+	// Frames may cover part of the canvas only
+	bounds := image.Rect(0, 0, config.Width, config.Height)
+	first := image.NewRGBA(bounds)
+	draw.Draw(first, decoded.Image[0].Bounds(), decoded.Image[0], decoded.Image[0].Bounds().Min, draw.Over)
+
+	return newImage(FormatGIF, bounds, buf.Bytes()), first, nil
+}
+
+// newImage fills in an Image of a format
+func newImage(format string, bounds image.Rectangle, data []byte) *Image {
+	return &Image{
+		Format:      format,
+		ContentType: "image/" + format,
+		Ext:         extensions[format],
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		Data:        data,
+	}
+}
+
+// extensions are the canonical file extensions of the formats
+var extensions = map[string]string{
+	FormatJPEG: ".jpg",
+	FormatPNG:  ".png",
+	FormatGIF:  ".gif",
+}
+
+// makeThumbnail downscales an image so its longest side is size
+func makeThumbnail(src image.Image, size int, format string) (Thumbnail, error) {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if longest := max(width, height); longest > size {
+		width = max(1, (width*size+longest/2)/longest)
+		height = max(1, (height*size+longest/2)/longest)
+	}
+	scaled := resize(src, width, height)
+
+	var buf bytes.Buffer
+	var err error
+	if format == FormatJPEG {
+		err = jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, scaled)
+	}
+	if err != nil {
+		return Thumbnail{}, err
+	}
+	return Thumbnail{Size: size, Width: width, Height: height, Data: buf.Bytes()}, nil
+}
+
+// ============================================
+// FILES
+// ============================================
+
+// Save stores the image under dir/name (name without extension) plus Ext,
+// with its thumbnails next to it (see ThumbnailPath). Returns the image's
+// key. Nothing is left behind if a write fails.
+func (img *Image) Save(ctx context.Context, store storage.Storage, dir, name string) (string, error) {
+	key := dir + "/" + name + img.Ext
+	written := []string{}
+	write := func(key, contentType string, data []byte) error {
+		if err := store.Put(ctx, key, bytes.NewReader(data), contentType); err != nil {
+			return err
+		}
+		written = append(written, key)
+		return nil
+	}
+
+	err := write(key, img.ContentType, img.Data)
+	for i := 0; err == nil && i < len(img.Thumbnails); i++ {
+		thumbKey := ThumbnailPath(key, img.Thumbnails[i].Size)
+		err = write(thumbKey, mime.TypeByExtension(path.Ext(thumbKey)), img.Thumbnails[i].Data)
+	}
+	if err != nil {
+		for _, k := range written {
+			store.Delete(ctx, k)
+		}
+		return "", err
+	}
+	return key, nil
+}
+
+// ThumbnailPath returns where Save puts the thumbnail of the given size for
+// an image path: photo.jpg gives photo_160.jpg, anim.gif gives anim_160.png
+func ThumbnailPath(p string, size int) string {
+	ext := path.Ext(p)
+	thumbExt := ".png"
+	if ext == ".jpg" {
+		thumbExt = ".jpg"
+	}
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(p, ext), size, thumbExt)
+}
+
+// ThumbnailPaths returns the thumbnail paths of an image, keyed by size
+func ThumbnailPaths(p string) map[int]string {
+	paths := make(map[int]string, len(ThumbnailSizes))
+	for _, size := range ThumbnailSizes {
+		paths[size] = ThumbnailPath(p, size)
+	}
+	return paths
+}
+
+// Remove deletes a stored image and its thumbnails. Missing files are not
+// an error.
+func Remove(ctx context.Context, store storage.Storage, key string) error {
+	keys := []string{key}
+	for _, size := range ThumbnailSizes {
+		keys = append(keys, ThumbnailPath(key, size))
+	}
+
+	var firstErr error
+	for _, k := range keys {
+		if err := store.Delete(ctx, k); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
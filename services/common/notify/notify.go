@@ -25,16 +25,33 @@ func init() {
 // CORE FUNCTION (called by all helpers below)
 // ============================================
 
-// createNotification makes an HTTP call to the notification service
-func createNotification(userID int, notifType, content string, relatedID int) error {
-	payload := map[string]interface{}{
-		"user_id":    userID,
-		"type":       notifType,
-		"related_id": relatedID,
-		"content":    content,
-	}
+// Target types used for deep links
+const (
+	TargetUser    = "user"
+	TargetGroup   = "group"
+	TargetEvent   = "event"
+	TargetPost    = "post"
+	TargetComment = "comment"
+	TargetMessage = "message"
+)
 
-	jsonData, err := json.Marshal(payload)
+// Notification is the payload sent to the notification service.
+// Content is the pre-rendered English fallback; ActorID, Target* and Params
+// let the notification service re-render it at read time.
+type Notification struct {
+	UserID     int                    `json:"user_id"`
+	Type       string                 `json:"type"`
+	RelatedID  int                    `json:"related_id"`
+	Content    string                 `json:"content"`
+	ActorID    int                    `json:"actor_id,omitempty"`
+	TargetType string                 `json:"target_type,omitempty"`
+	TargetID   int                    `json:"target_id,omitempty"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+}
+
+// createNotification makes an HTTP call to the notification service
+func createNotification(notif Notification) error {
+	jsonData, err := json.Marshal(notif)
 	if err != nil {
 		log.Printf("[Notify] Failed to marshal notification: %v", err)
 		return err
@@ -57,7 +74,7 @@ func createNotification(userID int, notifType, content string, relatedID int) er
 		return fmt.Errorf("notification service error: %d", resp.StatusCode)
 	}
 
-	log.Printf("[Notify] Created notification: type=%s, user=%d", notifType, userID)
+	log.Printf("[Notify] Created notification: type=%s, user=%d", notif.Type, notif.UserID)
 	return nil
 }
 
@@ -67,20 +84,44 @@ func createNotification(userID int, notifType, content string, relatedID int) er
 
 // FollowRequest notifies user about a follow request (private profile)
 func FollowRequest(targetUserID, followerID int, followerName string) {
-	content := fmt.Sprintf("%s sent you a follow request", followerName)
-	createNotification(targetUserID, "follow_request", content, followerID)
+	createNotification(Notification{
+		UserID:     targetUserID,
+		Type:       "follow_request",
+		RelatedID:  followerID,
+		Content:    fmt.Sprintf("%s sent you a follow request", followerName),
+		ActorID:    followerID,
+		TargetType: TargetUser,
+		TargetID:   followerID,
+		Params:     map[string]interface{}{"actor_name": followerName},
+	})
 }
 
 // FollowAccepted notifies user their follow request was accepted
 func FollowAccepted(requesterID, accepterID int, accepterName string) {
-	content := fmt.Sprintf("%s accepted your follow request", accepterName)
-	createNotification(requesterID, "follow", content, accepterID)
+	createNotification(Notification{
+		UserID:     requesterID,
+		Type:       "follow",
+		RelatedID:  accepterID,
+		Content:    fmt.Sprintf("%s accepted your follow request", accepterName),
+		ActorID:    accepterID,
+		TargetType: TargetUser,
+		TargetID:   accepterID,
+		Params:     map[string]interface{}{"verb": "accepted", "actor_name": accepterName},
+	})
 }
 
 // NewFollower notifies user about a new follower (public profile)
 func NewFollower(targetUserID, followerID int, followerName string) {
-	content := fmt.Sprintf("%s started following you", followerName)
-	createNotification(targetUserID, "follow", content, followerID)
+	createNotification(Notification{
+		UserID:     targetUserID,
+		Type:       "follow",
+		RelatedID:  followerID,
+		Content:    fmt.Sprintf("%s started following you", followerName),
+		ActorID:    followerID,
+		TargetType: TargetUser,
+		TargetID:   followerID,
+		Params:     map[string]interface{}{"actor_name": followerName},
+	})
 }
 
 // ============================================
@@ -88,54 +129,119 @@ func NewFollower(targetUserID, followerID int, followerName string) {
 // ============================================
 
 // GroupInvite notifies user about group invitation
-func GroupInvite(invitedUserID, groupID int, inviterName, groupName string) {
-	content := fmt.Sprintf("%s invited you to join %s", inviterName, groupName)
-	createNotification(invitedUserID, "group_invite", content, groupID)
+func GroupInvite(invitedUserID, groupID, inviterID int, inviterName, groupName string) {
+	createNotification(Notification{
+		UserID:     invitedUserID,
+		Type:       "group_invite",
+		RelatedID:  groupID,
+		Content:    fmt.Sprintf("%s invited you to join %s", inviterName, groupName),
+		ActorID:    inviterID,
+		TargetType: TargetGroup,
+		TargetID:   groupID,
+		Params:     map[string]interface{}{"actor_name": inviterName, "group_name": groupName},
+	})
 }
 
 // GroupJoinRequest notifies group creator about join request
-func GroupJoinRequest(creatorID, groupID int, requesterName, groupName string) {
-	content := fmt.Sprintf("%s wants to join your group %s", requesterName, groupName)
-	createNotification(creatorID, "group_request", content, groupID)
+func GroupJoinRequest(creatorID, groupID, requesterID int, requesterName, groupName string) {
+	createNotification(Notification{
+		UserID:     creatorID,
+		Type:       "group_request",
+		RelatedID:  groupID,
+		Content:    fmt.Sprintf("%s wants to join your group %s", requesterName, groupName),
+		ActorID:    requesterID,
+		TargetType: TargetGroup,
+		TargetID:   groupID,
+		Params:     map[string]interface{}{"actor_name": requesterName, "group_name": groupName},
+	})
 }
 
 // GroupRequestAccepted notifies user their join request was accepted
-func GroupRequestAccepted(requesterID, groupID int, groupName string) {
-	content := fmt.Sprintf("Your request to join %s was accepted", groupName)
-	createNotification(requesterID, "group_activity", content, groupID)
+func GroupRequestAccepted(requesterID, groupID, creatorID int, groupName string) {
+	createNotification(Notification{
+		UserID:     requesterID,
+		Type:       "group_activity",
+		RelatedID:  groupID,
+		Content:    fmt.Sprintf("Your request to join %s was accepted", groupName),
+		ActorID:    creatorID,
+		TargetType: TargetGroup,
+		TargetID:   groupID,
+		Params:     map[string]interface{}{"verb": "request_accepted", "group_name": groupName},
+	})
 }
 
 // GroupRequestRejected notifies user their join request was rejected
-func GroupRequestRejected(requesterID, groupID int, groupName string) {
-	content := fmt.Sprintf("Your request to join %s was declined", groupName)
-	createNotification(requesterID, "group_activity", content, groupID)
+func GroupRequestRejected(requesterID, groupID, creatorID int, groupName string) {
+	createNotification(Notification{
+		UserID:     requesterID,
+		Type:       "group_activity",
+		RelatedID:  groupID,
+		Content:    fmt.Sprintf("Your request to join %s was declined", groupName),
+		ActorID:    creatorID,
+		TargetType: TargetGroup,
+		TargetID:   groupID,
+		Params:     map[string]interface{}{"verb": "request_rejected", "group_name": groupName},
+	})
 }
 
 // NewGroupMember notifies creator when someone joins group
-func NewGroupMember(creatorID, groupID int, memberName, groupName string) {
-	content := fmt.Sprintf("%s joined your group %s", memberName, groupName)
-	createNotification(creatorID, "group_activity", content, groupID)
+func NewGroupMember(creatorID, groupID, memberID int, memberName, groupName string) {
+	createNotification(Notification{
+		UserID:     creatorID,
+		Type:       "group_activity",
+		RelatedID:  groupID,
+		Content:    fmt.Sprintf("%s joined your group %s", memberName, groupName),
+		ActorID:    memberID,
+		TargetType: TargetGroup,
+		TargetID:   groupID,
+		Params:     map[string]interface{}{"verb": "member_joined", "actor_name": memberName, "group_name": groupName},
+	})
 }
 
 // GroupInvitationAccepted notifies group creator when someone accepts invitation
-func GroupInvitationAccepted(creatorID, groupID int, memberName, groupName string) {
-	content := fmt.Sprintf("%s accepted your invitation to %s", memberName, groupName)
-	createNotification(creatorID, "group_activity", content, groupID)
+func GroupInvitationAccepted(creatorID, groupID, memberID int, memberName, groupName string) {
+	createNotification(Notification{
+		UserID:     creatorID,
+		Type:       "group_activity",
+		RelatedID:  groupID,
+		Content:    fmt.Sprintf("%s accepted your invitation to %s", memberName, groupName),
+		ActorID:    memberID,
+		TargetType: TargetGroup,
+		TargetID:   groupID,
+		Params:     map[string]interface{}{"verb": "invitation_accepted", "actor_name": memberName, "group_name": groupName},
+	})
 }
 
 // GroupInvitationDeclined notifies group creator when someone declines invitation
-func GroupInvitationDeclined(creatorID, groupID int, memberName, groupName string) {
-	content := fmt.Sprintf("%s declined your invitation to %s", memberName, groupName)
-	createNotification(creatorID, "group_activity", content, groupID)
+func GroupInvitationDeclined(creatorID, groupID, memberID int, memberName, groupName string) {
+	createNotification(Notification{
+		UserID:     creatorID,
+		Type:       "group_activity",
+		RelatedID:  groupID,
+		Content:    fmt.Sprintf("%s declined your invitation to %s", memberName, groupName),
+		ActorID:    memberID,
+		TargetType: TargetGroup,
+		TargetID:   groupID,
+		Params:     map[string]interface{}{"verb": "invitation_declined", "actor_name": memberName, "group_name": groupName},
+	})
 }
 
 // GroupPost notifies members about new group post
-func GroupPost(memberIDs []int, postID int, authorName, groupName string) {
+func GroupPost(memberIDs []int, postID, groupID, authorID int, authorName, groupName string) {
 	content := fmt.Sprintf("%s posted in %s", authorName, groupName)
 
 	// Send to all members except author
 	for _, memberID := range memberIDs {
-		createNotification(memberID, "post", content, postID)
+		createNotification(Notification{
+			UserID:     memberID,
+			Type:       "post",
+			RelatedID:  postID,
+			Content:    content,
+			ActorID:    authorID,
+			TargetType: TargetPost,
+			TargetID:   postID,
+			Params:     map[string]interface{}{"verb": "group_post", "actor_name": authorName, "group_id": groupID, "group_name": groupName},
+		})
 	}
 }
 
@@ -144,18 +250,35 @@ func GroupPost(memberIDs []int, postID int, authorName, groupName string) {
 // ============================================
 
 // EventCreated notifies group members about new event
-func EventCreated(memberIDs []int, eventID int, creatorName, eventTitle, groupName string) {
+func EventCreated(memberIDs []int, eventID, groupID, creatorID int, creatorName, eventTitle, groupName string) {
 	content := fmt.Sprintf("%s created event %s in %s", creatorName, eventTitle, groupName)
 
 	for _, memberID := range memberIDs {
-		createNotification(memberID, "event", content, eventID)
+		createNotification(Notification{
+			UserID:     memberID,
+			Type:       "event",
+			RelatedID:  eventID,
+			Content:    content,
+			ActorID:    creatorID,
+			TargetType: TargetEvent,
+			TargetID:   eventID,
+			Params:     map[string]interface{}{"verb": "created", "actor_name": creatorName, "event_title": eventTitle, "group_id": groupID, "group_name": groupName},
+		})
 	}
 }
 
 // EventResponse notifies event creator about response
-func EventResponse(creatorID, eventID int, responderName, eventTitle, response string) {
-	content := fmt.Sprintf("%s is %s to %s", responderName, response, eventTitle)
-	createNotification(creatorID, "event", content, eventID)
+func EventResponse(creatorID, eventID, responderID int, responderName, eventTitle, response string) {
+	createNotification(Notification{
+		UserID:     creatorID,
+		Type:       "event",
+		RelatedID:  eventID,
+		Content:    fmt.Sprintf("%s is %s to %s", responderName, response, eventTitle),
+		ActorID:    responderID,
+		TargetType: TargetEvent,
+		TargetID:   eventID,
+		Params:     map[string]interface{}{"verb": "response_" + response, "actor_name": responderName, "event_title": eventTitle},
+	})
 }
 
 // ============================================
@@ -163,21 +286,148 @@ func EventResponse(creatorID, eventID int, responderName, eventTitle, response s
 // ============================================
 
 // NewComment notifies post author about comment
-func NewComment(postAuthorID, commentID int, commenterName, commentPreview string) {
+func NewComment(postAuthorID, postID, commentID, commenterID int, commenterName, commentPreview string) {
 	// Truncate preview if needed
 	if len(commentPreview) > 50 {
 		commentPreview = commentPreview[:50] + "..."
 	}
-	content := fmt.Sprintf("%s commented on your post: '%s'", commenterName, commentPreview)
-	createNotification(postAuthorID, "comment", content, commentID)
+	createNotification(Notification{
+		UserID:     postAuthorID,
+		Type:       "comment",
+		RelatedID:  commentID,
+		Content:    fmt.Sprintf("%s commented on your post: '%s'", commenterName, commentPreview),
+		ActorID:    commenterID,
+		TargetType: TargetPost,
+		TargetID:   postID,
+		Params:     map[string]interface{}{"actor_name": commenterName, "comment_id": commentID, "preview": commentPreview},
+	})
+}
+
+// CommentReply notifies a comment's author about a reply to it
+func CommentReply(parentAuthorID, postID, parentCommentID, replyID, replierID int, replierName, replyPreview string) {
+	// Truncate preview if needed
+	if len(replyPreview) > 50 {
+		replyPreview = replyPreview[:50] + "..."
+	}
+	createNotification(Notification{
+		UserID:     parentAuthorID,
+		Type:       "comment",
+		RelatedID:  replyID,
+		Content:    fmt.Sprintf("%s replied to your comment: '%s'", replierName, replyPreview),
+		ActorID:    replierID,
+		TargetType: TargetPost,
+		TargetID:   postID,
+		Params: map[string]interface{}{
+			"actor_name":        replierName,
+			"comment_id":        replyID,
+			"parent_comment_id": parentCommentID,
+			"preview":           replyPreview,
+			"verb":              "reply",
+		},
+	})
+}
+
+// Reaction notifies an author about reactions to their post or comment.
+// Reactions arriving close together are sent as one notification: actorName
+// is the latest reactor and others the number of other people.
+func Reaction(authorID, postID int, targetType string, targetID, actorID int, actorName, emoji string, others int) {
+	onComment := targetType == TargetComment
+
+	var content, verb string
+	switch {
+	case others > 0 && onComment:
+		content = fmt.Sprintf("%s and %d other(s) reacted to your comment", actorName, others)
+		verb = "comment_aggregated"
+	case others > 0:
+		content = fmt.Sprintf("%s and %d other(s) reacted to your post", actorName, others)
+		verb = "aggregated"
+	case onComment:
+		content = fmt.Sprintf("%s reacted %s to your comment", actorName, emoji)
+		verb = "comment"
+	default:
+		content = fmt.Sprintf("%s reacted %s to your post", actorName, emoji)
+	}
+
+	params := map[string]interface{}{"actor_name": actorName, "emoji": emoji, "others": others, "post_id": postID}
+	if verb != "" {
+		params["verb"] = verb
+	}
+
+	createNotification(Notification{
+		UserID:     authorID,
+		Type:       "reaction",
+		RelatedID:  postID,
+		Content:    content,
+		ActorID:    actorID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Params:     params,
+	})
+}
+
+// Mention notifies a user that they were mentioned in a post or comment
+func Mention(userID, postID int, targetType string, targetID, actorID int, actorName, preview string) {
+	// Truncate preview if needed
+	if len(preview) > 50 {
+		preview = preview[:50] + "..."
+	}
+
+	content := fmt.Sprintf("%s mentioned you in a post: '%s'", actorName, preview)
+	params := map[string]interface{}{"actor_name": actorName, "preview": preview, "post_id": postID}
+	if targetType == TargetComment {
+		content = fmt.Sprintf("%s mentioned you in a comment: '%s'", actorName, preview)
+		params["verb"] = "comment"
+	}
+
+	createNotification(Notification{
+		UserID:     userID,
+		Type:       "mention",
+		RelatedID:  postID,
+		Content:    content,
+		ActorID:    actorID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Params:     params,
+	})
+}
+
+// Share notifies an author that their post was reposted (quote false) or
+// quoted. The target is the share, so the notification opens the quote.
+func Share(authorID, originalID, shareID, sharerID int, sharerName string, quote bool) {
+	content := fmt.Sprintf("%s reposted your post", sharerName)
+	params := map[string]interface{}{"actor_name": sharerName, "post_id": originalID}
+	if quote {
+		content = fmt.Sprintf("%s quoted your post", sharerName)
+		params["verb"] = "quote"
+	}
+
+	createNotification(Notification{
+		UserID:     authorID,
+		Type:       "repost",
+		RelatedID:  originalID,
+		Content:    content,
+		ActorID:    sharerID,
+		TargetType: TargetPost,
+		TargetID:   shareID,
+		Params:     params,
+	})
 }
 
 // NewPost notifies followers about new post
-func NewPost(followerIDs []int, postID int, authorName string) {
+func NewPost(followerIDs []int, postID, authorID int, authorName string) {
 	content := fmt.Sprintf("%s shared a new post", authorName)
 
 	for _, followerID := range followerIDs {
-		createNotification(followerID, "post", content, postID)
+		createNotification(Notification{
+			UserID:     followerID,
+			Type:       "post",
+			RelatedID:  postID,
+			Content:    content,
+			ActorID:    authorID,
+			TargetType: TargetPost,
+			TargetID:   postID,
+			Params:     map[string]interface{}{"actor_name": authorName},
+		})
 	}
 }
 
@@ -186,19 +436,36 @@ func NewPost(followerIDs []int, postID int, authorName string) {
 // ============================================
 
 // NewMessage notifies user about private message
-func NewMessage(receiverID, messageID int, senderName string) {
-	content := fmt.Sprintf("New message from %s", senderName)
-	createNotification(receiverID, "message", content, messageID)
+func NewMessage(receiverID, messageID, senderID int, senderName string) {
+	createNotification(Notification{
+		UserID:     receiverID,
+		Type:       "message",
+		RelatedID:  messageID,
+		Content:    fmt.Sprintf("New message from %s", senderName),
+		ActorID:    senderID,
+		TargetType: TargetUser,
+		TargetID:   senderID,
+		Params:     map[string]interface{}{"actor_name": senderName, "message_id": messageID},
+	})
 }
 
 // NewGroupMessage notifies group members about group chat message
-func NewGroupMessage(memberIDs []int, messageID, senderID int, senderName, groupName string) {
+func NewGroupMessage(memberIDs []int, groupID, messageID, senderID int, senderName, groupName string) {
 	content := fmt.Sprintf("%s sent a message in %s", senderName, groupName)
 
 	// Send to all members except sender
 	for _, memberID := range memberIDs {
 		if memberID != senderID {
-			createNotification(memberID, "message", content, messageID)
+			createNotification(Notification{
+				UserID:     memberID,
+				Type:       "message",
+				RelatedID:  messageID,
+				Content:    content,
+				ActorID:    senderID,
+				TargetType: TargetGroup,
+				TargetID:   groupID,
+				Params:     map[string]interface{}{"verb": "group_message", "actor_name": senderName, "group_name": groupName, "message_id": messageID},
+			})
 		}
 	}
 }
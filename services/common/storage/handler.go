@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// verifier is implemented by storages whose signed URLs are served by
+// Handler (Local)
+type verifier interface {
+	Verify(key string, query url.Values) bool
+}
+
+// Cache lifetimes of served files. Names are never reused, so files
+// don't change; private ones are only cached by the viewer's browser.
+const (
+	publicMaxAge  = 7 * 24 * time.Hour
+	privateMaxAge = time.Hour
+)
+
+// Authorizer decides whether an authenticated request may read the file at
+// key. Returning false serves a 404, so that files can't be probed.
+type Authorizer func(r *http.Request, key string) (bool, error)
+
+// Handler serves stored files under PathPrefix. Keys starting with one of
+// the public prefixes (such as "avatars/") are served to anyone. Other
+// files need a valid signed URL; S3 signed URLs point at the bucket
+// directly, so with S3 those are never served here.
+func Handler(store Storage, public ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := requestKey(w, r)
+		if !ok {
+			return
+		}
+
+		if isPublic(key, public) {
+			serve(w, r, store, key, "public, max-age="+seconds(publicMaxAge))
+			return
+		}
+		if maxAge, ok := signed(store, key, r); ok {
+			serve(w, r, store, key, "private, max-age="+seconds(maxAge))
+			return
+		}
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	})
+}
+
+// AuthorizedHandler serves private files under PathPrefix. Requests with a
+// valid signed URL are served as by Handler. Others go through auth, which
+// authenticates the viewer (such as authcache.AuthMiddleware), and are
+// served if authorize allows them.
+func AuthorizedHandler(store Storage, auth func(http.Handler) http.Handler, authorize Authorizer) http.Handler {
+	authorized := auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, PathPrefix)
+		allowed, err := authorize(r, key)
+		if err != nil {
+			log.Printf("Error authorizing upload %s: %v", key, err)
+			http.Error(w, "Failed to read file", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			http.NotFound(w, r)
+			return
+		}
+		serve(w, r, store, key, "private, max-age="+seconds(privateMaxAge))
+	}))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := requestKey(w, r)
+		if !ok {
+			return
+		}
+
+		if maxAge, ok := signed(store, key, r); ok {
+			serve(w, r, store, key, "private, max-age="+seconds(maxAge))
+			return
+		}
+		authorized.ServeHTTP(w, r)
+	})
+}
+
+// requestKey checks the method of a request for a file and returns the
+// file's key, or writes the error
+func requestKey(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return "", false
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, PathPrefix)
+	if !validKey(key) {
+		http.NotFound(w, r)
+		return "", false
+	}
+	return key, true
+}
+
+// signed reports whether a request carries a valid signed URL for key, and
+// for how much longer it is valid
+func signed(store Storage, key string, r *http.Request) (time.Duration, bool) {
+	v, ok := store.(verifier)
+	if !ok || r.URL.Query().Get("signature") == "" || !v.Verify(key, r.URL.Query()) {
+		return 0, false
+	}
+	expires, _ := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	return time.Until(time.Unix(expires, 0)), true
+}
+
+// serve writes a stored file. The key doubles as the ETag, since the file
+// behind a key never changes.
+func serve(w http.ResponseWriter, r *http.Request, store Storage, key, cacheControl string) {
+	obj, err := store.Get(r.Context(), key)
+	if errors.Is(err, ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Error reading upload %s: %v", key, err)
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+	defer obj.Body.Close()
+
+	etag := `"` + key + `"`
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", obj.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// Local files can seek, which gives range and conditional requests
+	if rs, ok := obj.Body.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, key, obj.ModTime, rs)
+		return
+	}
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if obj.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+	}
+	if r.Method == "HEAD" {
+		return
+	}
+	io.Copy(w, obj.Body)
+}
+
+// isPublic reports whether a key starts with one of the public prefixes
+func isPublic(key string, public []string) bool {
+	for _, prefix := range public {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// seconds formats a max-age
+func seconds(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return strconv.Itoa(int(d.Seconds()))
+}
@@ -0,0 +1,300 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPresignTTL is the longest validity S3 accepts for a signed URL
+const maxPresignTTL = 7 * 24 * time.Hour
+
+// S3Config locates a bucket on an S3-compatible API (AWS, MinIO, ...)
+type S3Config struct {
+	Endpoint        string // API base URL, such as http://minio:9000
+	PublicEndpoint  string // Base URL of signed URLs, if browsers reach the API elsewhere
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool // Bucket in the path rather than the host name
+}
+
+// S3ConfigFromEnv reads the S3 settings: S3_BUCKET, S3_ACCESS_KEY_ID and
+// S3_SECRET_ACCESS_KEY are required. S3_REGION defaults to us-east-1 and
+// S3_ENDPOINT to AWS. With a custom endpoint, path-style addressing is used
+// unless S3_PATH_STYLE=false. S3_PUBLIC_ENDPOINT defaults to S3_ENDPOINT.
+func S3ConfigFromEnv() (S3Config, error) {
+	config := S3Config{
+		Endpoint:        os.Getenv("S3_ENDPOINT"),
+		PublicEndpoint:  os.Getenv("S3_PUBLIC_ENDPOINT"),
+		Region:          os.Getenv("S3_REGION"),
+		Bucket:          os.Getenv("S3_BUCKET"),
+		AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+	}
+	if config.Bucket == "" || config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return config, errors.New("S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+
+	config.PathStyle = config.Endpoint != ""
+	if value := os.Getenv("S3_PATH_STYLE"); value != "" {
+		pathStyle, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid S3_PATH_STYLE %q", value)
+		}
+		config.PathStyle = pathStyle
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://s3." + config.Region + ".amazonaws.com"
+	}
+	if config.PublicEndpoint == "" {
+		config.PublicEndpoint = config.Endpoint
+	}
+	return config, nil
+}
+
+// S3 stores files in a bucket, signing requests with AWS Signature V4. Its
+// signed URLs are presigned GET URLs on PublicEndpoint.
+type S3 struct {
+	config   S3Config
+	endpoint *url.URL
+	public   *url.URL
+	client   *http.Client
+}
+
+// NewS3 creates an S3 storage
+func NewS3(config S3Config) (*S3, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", config.Endpoint)
+	}
+	public, err := url.Parse(config.PublicEndpoint)
+	if err != nil || public.Host == "" {
+		return nil, fmt.Errorf("invalid S3 public endpoint %q", config.PublicEndpoint)
+	}
+	return &S3{
+		config:   config,
+		endpoint: endpoint,
+		public:   public,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Put uploads a file. It is read whole first, to sign its hash.
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.request(ctx, "PUT", key, data)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError("put", key, resp)
+	}
+	return nil
+}
+
+// Get downloads a file
+func (s *S3) Get(ctx context.Context, key string) (*Object, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+	req, err := s.request(ctx, "GET", key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError("get", key, resp)
+	}
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return &Object{
+		Body:        resp.Body,
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        resp.ContentLength,
+		ModTime:     modTime,
+	}, nil
+}
+
+// Delete removes a file
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	req, err := s.request(ctx, "DELETE", key, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return responseError("delete", key, resp)
+	}
+}
+
+// SignedURL returns a presigned GET URL, valid for at most 7 days
+func (s *S3) SignedURL(key string, ttl time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+	if ttl > maxPresignTTL {
+		ttl = maxPresignTTL
+	}
+	return s.presign(key, ttl, time.Now().UTC()), nil
+}
+
+// presign builds the presigned GET URL of a key as of now
+func (s *S3) presign(key string, ttl time.Duration, now time.Time) string {
+	host, uri := s.location(s.public, key)
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.config.AccessKeyID+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(ttl.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonical := strings.Join([]string{
+		"GET",
+		uri,
+		query.Encode(),
+		"host:" + host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	query.Set("X-Amz-Signature", s.signature(now, canonical))
+
+	return s.public.Scheme + "://" + host + uri + "?" + query.Encode()
+}
+
+// request builds a signed request for a key on the API endpoint
+func (s *S3) request(ctx context.Context, method, key string, body []byte) (*http.Request, error) {
+	host, uri := s.location(s.endpoint, key)
+	req, err := http.NewRequestWithContext(ctx, method, s.endpoint.Scheme+"://"+host+uri, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		method,
+		uri,
+		"",
+		"host:" + host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, s.scope(now), signedHeaders, s.signature(now, canonical)))
+	return req, nil
+}
+
+// location returns the host and escaped path of a key on a base URL
+func (s *S3) location(base *url.URL, key string) (string, string) {
+	prefix := strings.TrimSuffix(base.EscapedPath(), "/")
+	if s.config.PathStyle {
+		return base.Host, prefix + "/" + escapePath(s.config.Bucket) + "/" + escapePath(key)
+	}
+	return s.config.Bucket + "." + base.Host, prefix + "/" + escapePath(key)
+}
+
+// scope is the credential scope of a request made at t
+func (s *S3) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.config.Region + "/s3/aws4_request"
+}
+
+// signature signs a canonical request made at t
+func (s *S3) signature(t time.Time, canonical string) string {
+	stringToSign := "AWS4-HMAC-SHA256\n" + t.Format("20060102T150405Z") + "\n" + s.scope(t) + "\n" + sha256Hex([]byte(canonical))
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// escapePath URI-encodes a key the way Signature V4 expects: everything but
+// unreserved characters and slashes
+func escapePath(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c == '/' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// responseError describes a failed S3 call, with the start of its error body
+func responseError(op, key string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("s3 %s %s: %s: %s", op, key, resp.Status, strings.TrimSpace(string(body)))
+}
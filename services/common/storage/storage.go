@@ -0,0 +1,125 @@
+// Package storage keeps uploaded files behind one interface so services
+// don't depend on the disk they run on. Files are addressed by keys such as
+// "posts/1_1700000000.jpg": the path under /uploads/ that the databases
+// store, without that prefix. The backend is picked at startup from the
+// environment (see FromEnv): the local filesystem for development, or an
+// S3-compatible bucket shared by every replica.
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// PathPrefix is the URL path uploads are served under
+const PathPrefix = "/uploads/"
+
+// Errors returned by Storage implementations
+var (
+	ErrNotFound   = errors.New("file not found")
+	ErrInvalidKey = errors.New("invalid file key")
+)
+
+// Object is a stored file being read. Callers must close Body.
+type Object struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// Storage stores uploaded files by key
+type Storage interface {
+	// Put stores a file, replacing any file with the same key
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+
+	// Get opens a file. Returns ErrNotFound if there is none.
+	Get(ctx context.Context, key string) (*Object, error)
+
+	// Delete removes a file. Missing files are not an error.
+	Delete(ctx context.Context, key string) error
+
+	// SignedURL returns a URL that serves the file until ttl has passed,
+	// without authentication
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// Key returns the key of a stored path, as kept in the database:
+// "/uploads/chat/a.png" and "uploads/chat/a.png" both give "chat/a.png"
+func Key(p string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(p, "/"), strings.TrimPrefix(PathPrefix, "/"))
+}
+
+// Path returns the path a key is served at without signing, "/uploads/key"
+func Path(key string) string {
+	return PathPrefix + key
+}
+
+// RandomName returns a new unguessable file name, without extension
+func RandomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validKey reports whether a key is a clean relative slash path, so that no
+// backend can be made to step outside its root
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	if path.Clean(key) != key {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == ".." || part == "." {
+			return false
+		}
+	}
+	return true
+}
+
+// FromEnv creates the storage selected by STORAGE_BACKEND:
+//
+//   - "local" (default): files under UPLOAD_DIR (default ./uploads), with
+//     URLs signed by UPLOAD_SIGNING_KEY
+//   - "s3": an S3-compatible bucket, see S3ConfigFromEnv
+//
+// Without UPLOAD_SIGNING_KEY a random key is used, so signed URLs only work
+// on the replica that issued them until it restarts.
+func FromEnv() (Storage, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		dir := os.Getenv("UPLOAD_DIR")
+		if dir == "" {
+			dir = "./uploads"
+		}
+		secret := []byte(os.Getenv("UPLOAD_SIGNING_KEY"))
+		if len(secret) == 0 {
+			log.Printf("Warning: UPLOAD_SIGNING_KEY is not set, signed upload URLs won't survive a restart")
+			secret = make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				return nil, err
+			}
+		}
+		return NewLocal(dir, secret)
+	case "s3":
+		config, err := S3ConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return NewS3(config)
+	default:
+		return nil, errors.New("unknown STORAGE_BACKEND " + backend)
+	}
+}
@@ -0,0 +1,84 @@
+// Package testdb gives tests a throwaway SQLite database with every
+// migration of db/migrations applied, like the one the services share.
+package testdb
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Open creates a migrated database in the test's temporary directory. It
+// is closed when the test ends.
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+
+	dir, err := migrationsDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+
+	database, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	for _, file := range files {
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := database.Exec(string(migration)); err != nil {
+			t.Fatalf("%s: %v", filepath.Base(file), err)
+		}
+	}
+	return database
+}
+
+// migrationsDir finds db/migrations above the working directory, which is
+// the package of the test
+func migrationsDir() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		candidate := filepath.Join(dir, "db", "migrations")
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return candidate, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", os.ErrNotExist
+		}
+		dir = parent
+	}
+}
+
+// User inserts a user and returns its ID
+func User(t testing.TB, database *sql.DB, username string) int {
+	t.Helper()
+
+	result, err := database.Exec(`
+		INSERT INTO users (username, email, password_hash, first_name, last_name, date_of_birth)
+		VALUES (?, ?, 'x', ?, 'Test', '1990-01-01')
+	`, username, username+"@example.com", username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int(id)
+}
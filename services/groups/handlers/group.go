@@ -3,11 +3,10 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
+	"social-network/services/common/imaging"
+	"social-network/services/common/storage"
 	"social-network/services/groups/middleware"
 	"social-network/services/groups/models"
 	"social-network/services/groups/services"
@@ -17,12 +16,26 @@ import (
 	"time"
 )
 
+const (
+	maxImageSize = 10 << 20 // 10MB
+	imageDir     = "groups" // Storage key prefix, served publicly
+)
+
+// imageLimits are the image limits of group images
+var imageLimits = imaging.Limits{
+	MaxBytes:     maxImageSize,
+	MaxDimension: imaging.DefaultLimits.MaxDimension,
+	MaxPixels:    imaging.DefaultLimits.MaxPixels,
+	MaxGIFPixels: imaging.DefaultLimits.MaxGIFPixels,
+}
+
 type GroupHandlers struct {
 	service *services.GroupService
+	store   storage.Storage
 }
 
-func NewGroupHandlers(service *services.GroupService) *GroupHandlers {
-	return &GroupHandlers{service: service}
+func NewGroupHandlers(service *services.GroupService, store storage.Storage) *GroupHandlers {
+	return &GroupHandlers{service: service, store: store}
 }
 
 // HealthHandler handles GET /health
@@ -39,7 +52,8 @@ func (h *GroupHandlers) CreateGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse multipart form for image upload
-	err := r.ParseMultipartForm(10 << 20) // 10MB max
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageSize)
+	err := r.ParseMultipartForm(maxImageSize)
 	if err != nil {
 		utils.SendError(w, http.StatusBadRequest, "Failed to parse form data")
 		return
@@ -59,44 +73,26 @@ func (h *GroupHandlers) CreateGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Handle optional image upload
-	file, header, err := r.FormFile("image")
+	file, _, err := r.FormFile("image")
 	if err == nil {
 		defer file.Close()
 
-		// Validate file type
-		contentType := header.Header.Get("Content-Type")
-		if !strings.HasPrefix(contentType, "image/") {
-			utils.SendError(w, http.StatusBadRequest, "File must be an image")
-			return
-		}
-
-		// Generate unique filename
-		ext := filepath.Ext(header.Filename)
-		if ext == "" {
-			ext = ".jpg"
-		}
-		filename := fmt.Sprintf("group_%d_%d%s", userID, time.Now().Unix(), ext)
-		filePath := filepath.Join("uploads", "groups", filename)
-
-		// Create directory if not exists
-		dirPath := filepath.Dir(filePath)
-		os.MkdirAll(dirPath, os.ModePerm)
-
-		// Save file
-		dst, err := os.Create(filePath)
+		// Identify, check and re-encode the image (stripping its metadata)
+		img, err := imaging.Process(file, imageLimits)
 		if err != nil {
-			log.Printf("Error creating file: %v", err)
-			utils.SendError(w, http.StatusInternalServerError, "Failed to save image")
+			utils.SendError(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		defer dst.Close()
 
-		if _, err := io.Copy(dst, file); err != nil {
+		// Save it with its thumbnails under a unique name
+		key, err := img.Save(r.Context(), h.store, imageDir, fmt.Sprintf("group_%d_%d", userID, time.Now().UnixNano()))
+		if err != nil {
 			log.Printf("Error saving file: %v", err)
 			utils.SendError(w, http.StatusInternalServerError, "Failed to save image")
 			return
 		}
 
+		filePath := strings.TrimPrefix(storage.Path(key), "/")
 		req.ImageURL = &filePath
 	}
 
@@ -149,57 +145,40 @@ func (h *GroupHandlers) UpdateGroupImage(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Parse multipart form
-	err = r.ParseMultipartForm(10 << 20) // 10MB max
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageSize)
+	err = r.ParseMultipartForm(maxImageSize)
 	if err != nil {
 		utils.SendError(w, http.StatusBadRequest, "Failed to parse form data")
 		return
 	}
 
 	// Handle image upload
-	file, header, err := r.FormFile("image")
+	file, _, err := r.FormFile("image")
 	if err != nil {
 		utils.SendError(w, http.StatusBadRequest, "Image file is required")
 		return
 	}
 	defer file.Close()
 
-	// Validate file type
-	contentType := header.Header.Get("Content-Type")
-	if !strings.HasPrefix(contentType, "image/") {
-		utils.SendError(w, http.StatusBadRequest, "File must be an image")
-		return
-	}
-
-	// Generate unique filename
-	ext := filepath.Ext(header.Filename)
-	if ext == "" {
-		ext = ".jpg"
-	}
-	filename := fmt.Sprintf("group_%d_%d%s", groupID, time.Now().Unix(), ext)
-	filePath := filepath.Join("uploads", "groups", filename)
-
-	// Create directory if not exists
-	dirPath := filepath.Dir(filePath)
-	os.MkdirAll(dirPath, os.ModePerm)
-
-	// Save file
-	dst, err := os.Create(filePath)
+	// Identify, check and re-encode the image (stripping its metadata)
+	img, err := imaging.Process(file, imageLimits)
 	if err != nil {
-		log.Printf("Error creating file: %v", err)
-		utils.SendError(w, http.StatusInternalServerError, "Failed to save image")
+		utils.SendError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer dst.Close()
 
-	if _, err := io.Copy(dst, file); err != nil {
+	// Save it with its thumbnails under a unique name
+	key, err := img.Save(r.Context(), h.store, imageDir, fmt.Sprintf("group_%d_%d", groupID, time.Now().UnixNano()))
+	if err != nil {
 		log.Printf("Error saving file: %v", err)
 		utils.SendError(w, http.StatusInternalServerError, "Failed to save image")
 		return
 	}
+	filePath := strings.TrimPrefix(storage.Path(key), "/")
 
-	// Delete old image if exists
+	// Delete old image and its thumbnails if exists
 	if group.ImageURL != nil && *group.ImageURL != "" {
-		os.Remove(*group.ImageURL)
+		imaging.Remove(r.Context(), h.store, storage.Key(*group.ImageURL))
 	}
 
 	// Update group image in database
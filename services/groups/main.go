@@ -10,6 +10,7 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 
 	"social-network/services/common/authcache"
+	"social-network/services/common/storage"
 	"social-network/services/groups/handlers"
 	"social-network/services/groups/middleware"
 	"social-network/services/groups/services"
@@ -32,8 +33,14 @@ func main() {
 	// Initialize services
 	groupService := services.NewGroupService(db)
 
+	// Group images live in the shared storage
+	store, err := storage.FromEnv()
+	if err != nil {
+		log.Fatalf("Failed to set up upload storage: %v", err)
+	}
+
 	// Initialize handlers
-	groupHandlers := handlers.NewGroupHandlers(groupService)
+	groupHandlers := handlers.NewGroupHandlers(groupService, store)
 
 	// Get auth service URL from environment
 	authServiceURL := os.Getenv("AUTH_SERVICE_URL")
@@ -52,8 +59,8 @@ func main() {
 	// Health check (no auth required)
 	mux.HandleFunc("/health", handlers.HealthHandler)
 
-	// Serve static files from uploads directory (no auth required for viewing images)
-	mux.Handle("/uploads/", http.StripPrefix("/uploads/", http.FileServer(http.Dir("./uploads"))))
+	// Serve group images from the storage (no auth required for viewing images)
+	mux.Handle(storage.PathPrefix, storage.Handler(store, "groups/"))
 
 	// Group routes (auth required + rate limited for write operations)
 	mux.Handle("/groups", authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -74,7 +74,7 @@ func (s *GroupService) InviteMember(groupID, inviterID, invitedUserID int, invit
 	// Get group info for notification
 	group, err := db.GetGroupWithDetails(s.database, groupID, inviterID)
 	if err == nil {
-		notify.GroupInvite(invitedUserID, groupID, inviterName, group.Name)
+		notify.GroupInvite(invitedUserID, groupID, inviterID, inviterName, group.Name)
 	}
 
 	return nil
@@ -90,7 +90,7 @@ func (s *GroupService) RequestToJoin(groupID, userID int, requesterName string)
 	// Get group info and notify creator
 	group, err := db.GetGroupByID(s.database, groupID)
 	if err == nil {
-		notify.GroupJoinRequest(group.CreatorID, groupID, requesterName, group.Name)
+		notify.GroupJoinRequest(group.CreatorID, groupID, userID, requesterName, group.Name)
 	}
 
 	return nil
@@ -144,14 +144,14 @@ func (s *GroupService) RespondToRequest(groupID, memberID, userID int, accept bo
 	group, err := db.GetGroupByID(s.database, groupID)
 	if err == nil && requesterID > 0 {
 		if accept {
-			notify.GroupRequestAccepted(requesterID, groupID, group.Name)
+			notify.GroupRequestAccepted(requesterID, groupID, userID, group.Name)
 			// Notify creator about new member
 			requesterName, err := db.GetUsernameByID(s.database, requesterID)
 			if err == nil {
-				notify.NewGroupMember(group.CreatorID, groupID, requesterName, group.Name)
+				notify.NewGroupMember(group.CreatorID, groupID, requesterID, requesterName, group.Name)
 			}
 		} else {
-			notify.GroupRequestRejected(requesterID, groupID, group.Name)
+			notify.GroupRequestRejected(requesterID, groupID, userID, group.Name)
 		}
 	}
 
@@ -202,9 +202,9 @@ func (s *GroupService) RespondToInvitation(invitationID, userID int, accept bool
 	username, err := db.GetUsernameByID(s.database, userID)
 	if err == nil {
 		if accept {
-			notify.GroupInvitationAccepted(creatorID, groupID, username, groupName)
+			notify.GroupInvitationAccepted(creatorID, groupID, userID, username, groupName)
 		} else {
-			notify.GroupInvitationDeclined(creatorID, groupID, username, groupName)
+			notify.GroupInvitationDeclined(creatorID, groupID, userID, username, groupName)
 		}
 	}
 
@@ -229,9 +229,9 @@ func (s *GroupService) RespondToInvitationByGroupID(groupID, userID int, accept
 	username, err := db.GetUsernameByID(s.database, userID)
 	if err == nil {
 		if accept {
-			notify.GroupInvitationAccepted(group.CreatorID, groupID, username, group.Name)
+			notify.GroupInvitationAccepted(group.CreatorID, groupID, userID, username, group.Name)
 		} else {
-			notify.GroupInvitationDeclined(group.CreatorID, groupID, username, group.Name)
+			notify.GroupInvitationDeclined(group.CreatorID, groupID, userID, username, group.Name)
 		}
 	}
 
@@ -294,7 +294,7 @@ func (s *GroupService) CreateEvent(req *models.CreateEventRequest, creatorID int
 				}
 			}
 			if len(memberIDs) > 0 {
-				notify.EventCreated(memberIDs, event.ID, creatorName, req.Title, group.Name)
+				notify.EventCreated(memberIDs, event.ID, req.GroupID, creatorID, creatorName, req.Title, group.Name)
 			}
 		}
 	}
@@ -350,7 +350,7 @@ func (s *GroupService) RespondToEvent(req *models.EventResponseRequest, userID i
 
 	// Notify event creator
 	if event.CreatorID != nil && *event.CreatorID != userID {
-		notify.EventResponse(*event.CreatorID, event.ID, userName, event.Title, req.Response)
+		notify.EventResponse(*event.CreatorID, event.ID, userID, userName, event.Title, req.Response)
 	}
 
 	return nil
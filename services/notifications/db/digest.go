@@ -0,0 +1,158 @@
+package db
+
+import (
+	"database/sql"
+	"social-network/services/notifications/models"
+	"time"
+)
+
+// sqliteTimeFormat matches the format of datetime('now') defaults
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+// sqliteTime formats t for comparison against datetime('now') columns
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+// GetDigestRecipients returns users on the given digest frequency who had no
+// digest since `due` and have unread, not-yet-emailed notifications created
+// since `oldest`. Users without a preferences row are treated as
+// defaultFrequency.
+func GetDigestRecipients(database *sql.DB, frequency, defaultFrequency string, due, oldest time.Time) ([]models.DigestRecipient, error) {
+	query := `
+		SELECT u.id, u.username, u.email
+		FROM users u
+		LEFT JOIN notification_preferences p ON p.user_id = u.id
+		WHERE COALESCE(p.digest_frequency, ?) = ?
+			AND EXISTS (
+				SELECT 1 FROM notifications n
+				WHERE n.user_id = u.id AND n.is_read = 0 AND n.created_at >= ?
+					AND NOT EXISTS (SELECT 1 FROM email_digest_items i WHERE i.notification_id = n.id)
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM email_digests d
+				WHERE d.user_id = u.id AND d.created_at >= ?
+			)
+	`
+
+	rows, err := database.Query(query, defaultFrequency, frequency, sqliteTime(oldest), sqliteTime(due))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipients := []models.DigestRecipient{}
+	for rows.Next() {
+		r := models.DigestRecipient{Frequency: frequency}
+		if err := rows.Scan(&r.UserID, &r.Username, &r.Email); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, r)
+	}
+
+	return recipients, rows.Err()
+}
+
+// GetUndigestedNotifications retrieves unread notifications created since
+// `since` that have not been included in any digest yet (newest first)
+func GetUndigestedNotifications(database *sql.DB, userID int, since time.Time, limit int) ([]models.Notification, error) {
+	query := `
+		SELECT ` + notificationColumns + `
+		FROM notifications n
+		WHERE n.user_id = ? AND n.is_read = 0 AND n.created_at >= ?
+			AND NOT EXISTS (SELECT 1 FROM email_digest_items i WHERE i.notification_id = n.id)
+		ORDER BY n.created_at DESC, n.id DESC
+		LIMIT ?
+	`
+
+	rows, err := database.Query(query, userID, sqliteTime(since), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		notif, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, *notif)
+	}
+
+	return notifications, rows.Err()
+}
+
+// ClaimDigest records a digest as 'sending' together with its notifications.
+// The UNIQUE constraint on email_digest_items makes the claim fail if any of
+// the notifications was already claimed by another digest.
+func ClaimDigest(database *sql.DB, recipient models.DigestRecipient, notificationIDs []int) (int, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO email_digests (user_id, frequency, recipient, notification_count)
+		VALUES (?, ?, ?, ?)
+	`, recipient.UserID, recipient.Frequency, recipient.Email, len(notificationIDs))
+	if err != nil {
+		return 0, err
+	}
+
+	digestID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO email_digest_items (digest_id, notification_id) VALUES (?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, id := range notificationIDs {
+		if _, err := stmt.Exec(digestID, id); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(digestID), nil
+}
+
+// MarkDigestSent marks a claimed digest as delivered
+func MarkDigestSent(database *sql.DB, digestID int) error {
+	query := `
+		UPDATE email_digests
+		SET status = 'sent', sent_at = datetime('now')
+		WHERE id = ?
+	`
+
+	_, err := database.Exec(query, digestID)
+	return err
+}
+
+// ReleaseDigest deletes a claimed digest whose delivery failed,
+// so its notifications can be picked up by the next run
+func ReleaseDigest(database *sql.DB, digestID int) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Foreign keys aren't enabled on this connection, so delete items explicitly
+	if _, err := tx.Exec(`DELETE FROM email_digest_items WHERE digest_id = ?`, digestID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM email_digests WHERE id = ? AND status = 'sending'`, digestID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
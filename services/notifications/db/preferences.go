@@ -0,0 +1,143 @@
+package db
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"social-network/services/notifications/models"
+)
+
+// GetPreferences retrieves a user's preferences, creating the row with
+// defaultFrequency and a fresh unsubscribe token if it doesn't exist yet
+func GetPreferences(database *sql.DB, userID int, defaultFrequency string) (*models.Preferences, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = database.Exec(`
+		INSERT OR IGNORE INTO notification_preferences (user_id, digest_frequency, unsubscribe_token)
+		VALUES (?, ?, ?)
+	`, userID, defaultFrequency, token)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs, err := FindPreferences(database, userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	return prefs, nil
+}
+
+// FindPreferences retrieves a user's preferences without creating them.
+// Returns nil if the user never had a preferences row.
+func FindPreferences(database *sql.DB, userID int) (*models.Preferences, error) {
+	query := `
+		SELECT user_id, digest_frequency, locale, push_enabled,
+			quiet_hours_enabled, quiet_start, quiet_end, timezone, dnd_until, quiet_bypass,
+			unsubscribe_token, updated_at
+		FROM notification_preferences
+		WHERE user_id = ?
+	`
+
+	var prefs models.Preferences
+	var dndUntil sql.NullTime
+	var bypass string
+
+	err := database.QueryRow(query, userID).Scan(
+		&prefs.UserID,
+		&prefs.DigestFrequency,
+		&prefs.Locale,
+		&prefs.PushEnabled,
+		&prefs.QuietHoursEnabled,
+		&prefs.QuietStart,
+		&prefs.QuietEnd,
+		&prefs.Timezone,
+		&dndUntil,
+		&bypass,
+		&prefs.UnsubscribeToken,
+		&prefs.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if dndUntil.Valid {
+		prefs.DNDUntil = &dndUntil.Time
+	}
+	prefs.QuietBypass = []models.QuietBypassRule{}
+	if err := json.Unmarshal([]byte(bypass), &prefs.QuietBypass); err != nil {
+		return nil, err
+	}
+
+	return &prefs, nil
+}
+
+// UpdatePreferences saves a user's editable settings
+func UpdatePreferences(database *sql.DB, prefs *models.Preferences) error {
+	if prefs.QuietBypass == nil {
+		prefs.QuietBypass = []models.QuietBypassRule{}
+	}
+	bypass, err := json.Marshal(prefs.QuietBypass)
+	if err != nil {
+		return err
+	}
+
+	var dndUntil interface{}
+	if prefs.DNDUntil != nil {
+		dndUntil = sqliteTime(*prefs.DNDUntil)
+	}
+
+	query := `
+		UPDATE notification_preferences
+		SET digest_frequency = ?, locale = ?, push_enabled = ?,
+			quiet_hours_enabled = ?, quiet_start = ?, quiet_end = ?, timezone = ?, dnd_until = ?, quiet_bypass = ?,
+			updated_at = datetime('now')
+		WHERE user_id = ?
+	`
+
+	_, err = database.Exec(query, prefs.DigestFrequency, prefs.Locale, prefs.PushEnabled,
+		prefs.QuietHoursEnabled, prefs.QuietStart, prefs.QuietEnd, prefs.Timezone, dndUntil, string(bypass),
+		prefs.UserID)
+	return err
+}
+
+// UnsubscribeByToken turns off email digests for the owner of token.
+// Returns false if no user has that token.
+func UnsubscribeByToken(database *sql.DB, token string) (bool, error) {
+	query := `
+		UPDATE notification_preferences
+		SET digest_frequency = 'off', updated_at = datetime('now')
+		WHERE unsubscribe_token = ?
+	`
+
+	result, err := database.Exec(query, token)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// newToken generates a random 32-byte hex token
+func newToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"database/sql"
+	"social-network/services/notifications/models"
+	"time"
+)
+
+// SavePushSubscription stores a subscription for userID.
+// Re-subscribing the same endpoint refreshes its keys and moves it to userID.
+func SavePushSubscription(database *sql.DB, sub *models.PushSubscription) error {
+	query := `
+		INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth, user_agent)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(endpoint) DO UPDATE SET
+			user_id = excluded.user_id,
+			p256dh = excluded.p256dh,
+			auth = excluded.auth,
+			user_agent = excluded.user_agent
+	`
+
+	_, err := database.Exec(query, sub.UserID, sub.Endpoint, sub.P256dh, sub.Auth, nullableString(sub.UserAgent))
+	return err
+}
+
+// GetPushSubscriptions retrieves all subscriptions of a user
+func GetPushSubscriptions(database *sql.DB, userID int) ([]models.PushSubscription, error) {
+	query := `
+		SELECT id, user_id, endpoint, p256dh, auth, user_agent, created_at, last_success_at
+		FROM push_subscriptions
+		WHERE user_id = ?
+		ORDER BY created_at DESC
+	`
+
+	rows, err := database.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []models.PushSubscription{}
+	for rows.Next() {
+		var sub models.PushSubscription
+		var userAgent sql.NullString
+		var lastSuccess sql.NullTime
+
+		err := rows.Scan(
+			&sub.ID,
+			&sub.UserID,
+			&sub.Endpoint,
+			&sub.P256dh,
+			&sub.Auth,
+			&userAgent,
+			&sub.CreatedAt,
+			&lastSuccess,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		sub.UserAgent = userAgent.String
+		if lastSuccess.Valid {
+			sub.LastSuccessAt = &lastSuccess.Time
+		}
+
+		subs = append(subs, sub)
+	}
+
+	return subs, rows.Err()
+}
+
+// GetPushSettings returns whether a user wants push notifications and their
+// locale. Users without a preferences row get the defaults.
+func GetPushSettings(database *sql.DB, userID int) (bool, string, error) {
+	query := `
+		SELECT push_enabled, locale
+		FROM notification_preferences
+		WHERE user_id = ?
+	`
+
+	enabled, locale := true, "en"
+	err := database.QueryRow(query, userID).Scan(&enabled, &locale)
+	if err != nil && err != sql.ErrNoRows {
+		return false, "", err
+	}
+
+	return enabled, locale, nil
+}
+
+// DeletePushSubscription removes a user's subscription by endpoint
+func DeletePushSubscription(database *sql.DB, userID int, endpoint string) error {
+	query := `DELETE FROM push_subscriptions WHERE user_id = ? AND endpoint = ?`
+	_, err := database.Exec(query, userID, endpoint)
+	return err
+}
+
+// PrunePushSubscription removes a subscription the push service reported as gone
+func PrunePushSubscription(database *sql.DB, subscriptionID int) error {
+	query := `DELETE FROM push_subscriptions WHERE id = ?`
+	_, err := database.Exec(query, subscriptionID)
+	return err
+}
+
+// MarkPushSuccess records a successful delivery to a subscription
+func MarkPushSuccess(database *sql.DB, subscriptionID int, at time.Time) error {
+	query := `UPDATE push_subscriptions SET last_success_at = ? WHERE id = ?`
+	_, err := database.Exec(query, sqliteTime(at), subscriptionID)
+	return err
+}
@@ -2,17 +2,99 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"social-network/services/notifications/models"
+	"strings"
 )
 
+// notificationColumns is the column list shared by all notification SELECTs
+const notificationColumns = `id, user_id, type, related_id, content, actor_id, target_type, target_id, params, is_read, created_at`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanNotification scans a row selected with notificationColumns
+func scanNotification(row rowScanner) (*models.Notification, error) {
+	var notif models.Notification
+	var relatedID, actorID, targetID sql.NullInt64
+	var targetType, params sql.NullString
+
+	err := row.Scan(
+		&notif.ID,
+		&notif.UserID,
+		&notif.Type,
+		&relatedID,
+		&notif.Content,
+		&actorID,
+		&targetType,
+		&targetID,
+		&params,
+		&notif.IsRead,
+		&notif.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle nullable fields (legacy notifications have no structured data)
+	notif.RelatedID = int(relatedID.Int64)
+	if actorID.Valid {
+		id := int(actorID.Int64)
+		notif.ActorID = &id
+	}
+	if targetType.Valid {
+		notif.TargetType = &targetType.String
+	}
+	if targetID.Valid {
+		id := int(targetID.Int64)
+		notif.TargetID = &id
+	}
+	if params.Valid && params.String != "" {
+		if err := json.Unmarshal([]byte(params.String), &notif.Params); err != nil {
+			return nil, err
+		}
+	}
+
+	return &notif, nil
+}
+
+// nullableInt stores zero IDs as NULL
+func nullableInt(v int) interface{} {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
+// nullableString stores empty strings as NULL
+func nullableString(v string) interface{} {
+	if v == "" {
+		return nil
+	}
+	return v
+}
+
 // CreateNotification inserts a new notification into the database
 func CreateNotification(database *sql.DB, notif *models.CreateNotificationRequest) (*models.Notification, error) {
+	var params interface{}
+	if len(notif.Params) > 0 {
+		data, err := json.Marshal(notif.Params)
+		if err != nil {
+			return nil, err
+		}
+		params = string(data)
+	}
+
 	query := `
-		INSERT INTO notifications (user_id, type, related_id, content)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO notifications (user_id, type, related_id, content, actor_id, target_type, target_id, params)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := database.Exec(query, notif.UserID, notif.Type, notif.RelatedID, notif.Content)
+	result, err := database.Exec(query, notif.UserID, notif.Type, notif.RelatedID, notif.Content,
+		nullableInt(notif.ActorID), nullableString(notif.TargetType), nullableInt(notif.TargetID), params)
 	if err != nil {
 		return nil, err
 	}
@@ -29,76 +111,98 @@ func CreateNotification(database *sql.DB, notif *models.CreateNotificationReques
 // GetNotificationByID retrieves a notification by ID
 func GetNotificationByID(database *sql.DB, id int) (*models.Notification, error) {
 	query := `
-		SELECT id, user_id, type, related_id, content, is_read, created_at
+		SELECT ` + notificationColumns + `
 		FROM notifications
 		WHERE id = ?
 	`
 
-	var notif models.Notification
-	err := database.QueryRow(query, id).Scan(
-		&notif.ID,
-		&notif.UserID,
-		&notif.Type,
-		&notif.RelatedID,
-		&notif.Content,
-		&notif.IsRead,
-		&notif.CreatedAt,
-	)
+	return scanNotification(database.QueryRow(query, id))
+}
 
-	if err != nil {
-		return nil, err
+// ListNotifications retrieves one page of a user's notifications, newest
+// first. It returns the cursor of the next page, or nil on the last page.
+func ListNotifications(database *sql.DB, userID int, filter models.NotificationFilter) ([]models.Notification, *models.NotificationCursor, error) {
+	conditions := []string{"user_id = ?"}
+	args := []interface{}{userID}
+
+	if len(filter.Types) > 0 {
+		conditions = append(conditions, "type IN ("+placeholders(len(filter.Types))+")")
+		for _, t := range filter.Types {
+			args = append(args, t)
+		}
+	}
+	if filter.IsRead != nil {
+		conditions = append(conditions, "is_read = ?")
+		args = append(args, *filter.IsRead)
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, sqliteTime(*filter.Since))
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, sqliteTime(*filter.Until))
 	}
 
-	return &notif, nil
-}
+	offset := filter.Offset
+	if filter.Cursor != nil {
+		conditions = append(conditions, "(created_at < ? OR (created_at = ? AND id < ?))")
+		args = append(args, filter.Cursor.CreatedAt, filter.Cursor.CreatedAt, filter.Cursor.ID)
+		offset = 0
+	}
 
-// GetUserNotifications retrieves all notifications for a user
-func GetUserNotifications(database *sql.DB, userID int, limit, offset int) ([]models.Notification, error) {
+	// Fetch one extra row to know whether there is a next page
 	query := `
-		SELECT id, user_id, type, related_id, content, is_read, created_at
+		SELECT ` + notificationColumns + `
 		FROM notifications
-		WHERE user_id = ?
-		ORDER BY created_at DESC
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY created_at DESC, id DESC
 		LIMIT ? OFFSET ?
 	`
+	args = append(args, filter.Limit+1, offset)
 
-	rows, err := database.Query(query, userID, limit, offset)
+	rows, err := database.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
 	notifications := []models.Notification{}
 	for rows.Next() {
-		var notif models.Notification
-		err := rows.Scan(
-			&notif.ID,
-			&notif.UserID,
-			&notif.Type,
-			&notif.RelatedID,
-			&notif.Content,
-			&notif.IsRead,
-			&notif.CreatedAt,
-		)
+		notif, err := scanNotification(rows)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		notifications = append(notifications, notif)
+		notifications = append(notifications, *notif)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
 	}
 
-	return notifications, nil
+	if len(notifications) <= filter.Limit {
+		return notifications, nil, nil
+	}
+
+	notifications = notifications[:filter.Limit]
+	last := notifications[len(notifications)-1]
+	return notifications, &models.NotificationCursor{
+		CreatedAt: sqliteTime(last.CreatedAt),
+		ID:        last.ID,
+	}, nil
 }
 
-// GetUnreadNotifications retrieves unread notifications for a user
-func GetUnreadNotifications(database *sql.DB, userID int) ([]models.Notification, error) {
+// GetNotificationsAfter retrieves a user's notifications with an ID greater
+// than afterID, oldest first. IDs are AUTOINCREMENT so they double as event IDs.
+func GetNotificationsAfter(database *sql.DB, userID, afterID, limit int) ([]models.Notification, error) {
 	query := `
-		SELECT id, user_id, type, related_id, content, is_read, created_at
+		SELECT ` + notificationColumns + `
 		FROM notifications
-		WHERE user_id = ? AND is_read = 0
-		ORDER BY created_at DESC
+		WHERE user_id = ? AND id > ?
+		ORDER BY id ASC
+		LIMIT ?
 	`
 
-	rows, err := database.Query(query, userID)
+	rows, err := database.Query(query, userID, afterID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -106,23 +210,14 @@ func GetUnreadNotifications(database *sql.DB, userID int) ([]models.Notification
 
 	notifications := []models.Notification{}
 	for rows.Next() {
-		var notif models.Notification
-		err := rows.Scan(
-			&notif.ID,
-			&notif.UserID,
-			&notif.Type,
-			&notif.RelatedID,
-			&notif.Content,
-			&notif.IsRead,
-			&notif.CreatedAt,
-		)
+		notif, err := scanNotification(rows)
 		if err != nil {
 			return nil, err
 		}
-		notifications = append(notifications, notif)
+		notifications = append(notifications, *notif)
 	}
 
-	return notifications, nil
+	return notifications, rows.Err()
 }
 
 // GetUnreadCount returns the count of unread notifications for a user
@@ -186,3 +281,43 @@ func DeleteAllRead(database *sql.DB, userID int) error {
 	_, err := database.Exec(query, userID)
 	return err
 }
+
+// GetUsernames returns the current usernames for the given user IDs
+func GetUsernames(database *sql.DB, userIDs []int) (map[int]string, error) {
+	return getNames(database, `SELECT id, username FROM users WHERE id IN (%s)`, userIDs)
+}
+
+// GetGroupNames returns the current names for the given group IDs
+func GetGroupNames(database *sql.DB, groupIDs []int) (map[int]string, error) {
+	return getNames(database, `SELECT id, name FROM groups WHERE id IN (%s)`, groupIDs)
+}
+
+// getNames runs an id -> name lookup for a batch of IDs
+func getNames(database *sql.DB, queryFormat string, ids []int) (map[int]string, error) {
+	names := make(map[int]string)
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := database.Query(fmt.Sprintf(queryFormat, placeholders(len(ids))), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		names[id] = name
+	}
+
+	return names, rows.Err()
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"database/sql"
+	"social-network/services/notifications/models"
+)
+
+// RecordQuietSuppressed notes that a notification was held back by quiet
+// hours or DND, so a summary is sent when the quiet period ends
+func RecordQuietSuppressed(database *sql.DB, userID, notificationID int) error {
+	query := `
+		UPDATE notification_preferences
+		SET quiet_pending_count = quiet_pending_count + 1,
+			quiet_pending_from_id = COALESCE(quiet_pending_from_id, ?)
+		WHERE user_id = ?
+	`
+
+	_, err := database.Exec(query, notificationID, userID)
+	return err
+}
+
+// GetQuietPendingUsers returns the users with held-back notifications
+func GetQuietPendingUsers(database *sql.DB) ([]int, error) {
+	rows, err := database.Query(`SELECT user_id FROM notification_preferences WHERE quiet_pending_count > 0`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	return userIDs, rows.Err()
+}
+
+// ClaimQuietSummary resets a user's held-back counter and returns what it
+// held (first notification ID and count). A zero count means nothing to send.
+func ClaimQuietSummary(database *sql.DB, userID int) (int, int, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	var fromID sql.NullInt64
+	var count int
+	err = tx.QueryRow(`
+		SELECT quiet_pending_from_id, quiet_pending_count
+		FROM notification_preferences
+		WHERE user_id = ?
+	`, userID).Scan(&fromID, &count)
+	if err == sql.ErrNoRows || (err == nil && count == 0) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE notification_preferences
+		SET quiet_pending_from_id = NULL, quiet_pending_count = 0
+		WHERE user_id = ?
+	`, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+
+	return int(fromID.Int64), count, nil
+}
+
+// GetNotificationsFromID retrieves a user's notifications with an ID of at
+// least fromID, newest first
+func GetNotificationsFromID(database *sql.DB, userID, fromID, limit int) ([]models.Notification, error) {
+	query := `
+		SELECT ` + notificationColumns + `
+		FROM notifications
+		WHERE user_id = ? AND id >= ?
+		ORDER BY id DESC
+		LIMIT ?
+	`
+
+	rows, err := database.Query(query, userID, fromID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		notif, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, *notif)
+	}
+
+	return notifications, rows.Err()
+}
+
+// IsMutualFollow reports whether two users follow each other
+func IsMutualFollow(database *sql.DB, userA, userB int) (bool, error) {
+	query := `
+		SELECT COUNT(*) FROM follows
+		WHERE status = 'accepted'
+			AND ((follower_id = ? AND following_id = ?) OR (follower_id = ? AND following_id = ?))
+	`
+
+	var count int
+	if err := database.QueryRow(query, userA, userB, userB, userA).Scan(&count); err != nil {
+		return false, err
+	}
+
+	return count == 2, nil
+}
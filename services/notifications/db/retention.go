@@ -0,0 +1,132 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// PruneReadBefore deletes up to batchSize read notifications created before
+// cutoff. If types is non-empty only those types are considered; otherwise
+// every type except excludeTypes. Returns the number of rows deleted.
+func PruneReadBefore(database *sql.DB, cutoff time.Time, types, excludeTypes []string, batchSize int) (int, error) {
+	conditions := []string{"is_read = 1", "created_at < ?"}
+	args := []interface{}{sqliteTime(cutoff)}
+
+	if len(types) > 0 {
+		conditions = append(conditions, "type IN ("+placeholders(len(types))+")")
+		for _, t := range types {
+			args = append(args, t)
+		}
+	} else if len(excludeTypes) > 0 {
+		conditions = append(conditions, "type NOT IN ("+placeholders(len(excludeTypes))+")")
+		for _, t := range excludeTypes {
+			args = append(args, t)
+		}
+	}
+
+	query := `
+		SELECT id FROM notifications
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY id
+		LIMIT ?
+	`
+	args = append(args, batchSize)
+
+	return deleteBatch(database, query, args...)
+}
+
+// GetUsersOverCap returns the users holding more than limit notifications
+func GetUsersOverCap(database *sql.DB, limit int) ([]int, error) {
+	query := `
+		SELECT user_id FROM notifications
+		GROUP BY user_id
+		HAVING COUNT(*) > ?
+	`
+
+	rows, err := database.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	return userIDs, rows.Err()
+}
+
+// PruneUserOverCap deletes up to batchSize of a user's oldest notifications
+// beyond the newest limit. Returns the number of rows deleted.
+func PruneUserOverCap(database *sql.DB, userID, limit, batchSize int) (int, error) {
+	query := `
+		SELECT id FROM notifications
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`
+
+	return deleteBatch(database, query, userID, batchSize, limit)
+}
+
+// deleteBatch deletes the notifications selected by idQuery in one short
+// transaction, together with their email digest items (foreign keys aren't
+// enabled on this connection, so nothing cascades)
+func deleteBatch(database *sql.DB, idQuery string, args ...interface{}) (int, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(idQuery, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := []interface{}{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	in := placeholders(len(ids))
+	if _, err := tx.Exec(`DELETE FROM email_digest_items WHERE notification_id IN (`+in+`)`, ids...); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM notifications WHERE id IN (`+in+`)`, ids...)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+// placeholders returns "?,?,...,?" with n placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
@@ -0,0 +1,393 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"social-network/services/notifications/models"
+	"time"
+)
+
+// webhookColumns is the column list shared by all webhook SELECTs
+const webhookColumns = `id, user_id, url, secret, types, active, consecutive_failures, disabled_reason, created_at, updated_at`
+
+// scanWebhook scans a row selected with webhookColumns
+func scanWebhook(row rowScanner) (*models.Webhook, error) {
+	var hook models.Webhook
+	var types string
+	var disabledReason sql.NullString
+
+	err := row.Scan(
+		&hook.ID,
+		&hook.UserID,
+		&hook.URL,
+		&hook.Secret,
+		&types,
+		&hook.Active,
+		&hook.ConsecutiveFailures,
+		&disabledReason,
+		&hook.CreatedAt,
+		&hook.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if disabledReason.Valid {
+		hook.DisabledReason = &disabledReason.String
+	}
+	if err := json.Unmarshal([]byte(types), &hook.Types); err != nil {
+		return nil, err
+	}
+
+	return &hook, nil
+}
+
+// CreateWebhook inserts a new webhook and sets its ID and timestamps
+func CreateWebhook(database *sql.DB, hook *models.Webhook) error {
+	types, err := json.Marshal(hook.Types)
+	if err != nil {
+		return err
+	}
+
+	result, err := database.Exec(`
+		INSERT INTO webhooks (user_id, url, secret, types)
+		VALUES (?, ?, ?, ?)
+	`, hook.UserID, hook.URL, hook.Secret, string(types))
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	created, err := GetWebhookByID(database, int(id))
+	if err != nil {
+		return err
+	}
+
+	hook.ID = created.ID
+	hook.Active = created.Active
+	hook.CreatedAt = created.CreatedAt
+	hook.UpdatedAt = created.UpdatedAt
+	return nil
+}
+
+// CountWebhooks returns how many webhooks a user has
+func CountWebhooks(database *sql.DB, userID int) (int, error) {
+	var count int
+	err := database.QueryRow(`SELECT COUNT(*) FROM webhooks WHERE user_id = ?`, userID).Scan(&count)
+	return count, err
+}
+
+// GetWebhooks retrieves all webhooks of a user
+func GetWebhooks(database *sql.DB, userID int) ([]models.Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE user_id = ?
+		ORDER BY id
+	`
+
+	return queryWebhooks(database, query, userID)
+}
+
+// GetWebhook retrieves a user's webhook, or nil if it doesn't exist
+func GetWebhook(database *sql.DB, webhookID, userID int) (*models.Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE id = ? AND user_id = ?
+	`
+
+	hook, err := scanWebhook(database.QueryRow(query, webhookID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return hook, err
+}
+
+// GetWebhookByID retrieves a webhook regardless of owner
+func GetWebhookByID(database *sql.DB, webhookID int) (*models.Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE id = ?
+	`
+
+	return scanWebhook(database.QueryRow(query, webhookID))
+}
+
+// GetActiveWebhooksForType retrieves a user's active webhooks subscribed to notifType
+func GetActiveWebhooksForType(database *sql.DB, userID int, notifType string) ([]models.Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE user_id = ? AND active = 1
+			AND EXISTS (SELECT 1 FROM json_each(webhooks.types) WHERE value = ?)
+	`
+
+	return queryWebhooks(database, query, userID, notifType)
+}
+
+// queryWebhooks runs a webhook SELECT and scans every row
+func queryWebhooks(database *sql.DB, query string, args ...interface{}) ([]models.Webhook, error) {
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []models.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, *hook)
+	}
+
+	return hooks, rows.Err()
+}
+
+// UpdateWebhook saves a webhook's editable fields and failure state
+func UpdateWebhook(database *sql.DB, hook *models.Webhook) error {
+	types, err := json.Marshal(hook.Types)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE webhooks
+		SET url = ?, secret = ?, types = ?, active = ?, consecutive_failures = ?, disabled_reason = ?,
+			updated_at = datetime('now')
+		WHERE id = ?
+	`
+
+	var disabledReason interface{}
+	if hook.DisabledReason != nil {
+		disabledReason = *hook.DisabledReason
+	}
+
+	_, err = database.Exec(query, hook.URL, hook.Secret, string(types), hook.Active,
+		hook.ConsecutiveFailures, disabledReason, hook.ID)
+	return err
+}
+
+// DeleteWebhook deletes a user's webhook and its delivery log.
+// Returns false if the user has no such webhook.
+func DeleteWebhook(database *sql.DB, webhookID, userID int) (bool, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM webhooks WHERE id = ? AND user_id = ?`, webhookID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	// Foreign keys aren't enabled on this connection, so delete the log explicitly
+	if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, webhookID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// RecordWebhookResult updates a webhook's consecutive failure count after an
+// attempt, disabling it once the count reaches disableAfter.
+// Returns true if this call disabled the webhook.
+func RecordWebhookResult(database *sql.DB, webhookID int, success bool, disableAfter int) (bool, error) {
+	if success {
+		_, err := database.Exec(`UPDATE webhooks SET consecutive_failures = 0 WHERE id = ?`, webhookID)
+		return false, err
+	}
+
+	result, err := database.Exec(`
+		UPDATE webhooks
+		SET consecutive_failures = consecutive_failures + 1,
+			active = CASE WHEN consecutive_failures + 1 >= ? THEN 0 ELSE active END,
+			disabled_reason = CASE WHEN consecutive_failures + 1 >= ? THEN 'Disabled after repeated delivery failures' ELSE disabled_reason END,
+			updated_at = datetime('now')
+		WHERE id = ? AND active = 1
+	`, disableAfter, disableAfter, webhookID)
+	if err != nil {
+		return false, err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+
+	var active bool
+	if err := database.QueryRow(`SELECT active FROM webhooks WHERE id = ?`, webhookID).Scan(&active); err != nil {
+		return false, err
+	}
+	return !active, nil
+}
+
+// ============================================
+// DELIVERIES
+// ============================================
+
+// deliveryColumns is the column list shared by all delivery SELECTs
+const deliveryColumns = `id, webhook_id, notification_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, completed_at`
+
+// scanDelivery scans a row selected with deliveryColumns
+func scanDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var notificationID, statusCode sql.NullInt64
+	var lastError sql.NullString
+	var completedAt sql.NullTime
+
+	err := row.Scan(
+		&d.ID,
+		&d.WebhookID,
+		&notificationID,
+		&d.EventType,
+		&d.Payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&statusCode,
+		&lastError,
+		&d.CreatedAt,
+		&completedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if notificationID.Valid {
+		id := int(notificationID.Int64)
+		d.NotificationID = &id
+	}
+	if statusCode.Valid {
+		code := int(statusCode.Int64)
+		d.LastStatusCode = &code
+	}
+	if lastError.Valid {
+		d.LastError = &lastError.String
+	}
+	if completedAt.Valid {
+		d.CompletedAt = &completedAt.Time
+	}
+
+	return &d, nil
+}
+
+// EnqueueDelivery queues a payload for immediate delivery to a webhook
+func EnqueueDelivery(database *sql.DB, webhookID, notificationID int, eventType, payload string) error {
+	_, err := database.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, notification_id, event_type, payload)
+		VALUES (?, ?, ?, ?)
+	`, webhookID, nullableInt(notificationID), eventType, payload)
+	return err
+}
+
+// GetDueDeliveries retrieves pending deliveries whose next attempt is due
+func GetDueDeliveries(database *sql.DB, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, id
+		LIMIT ?
+	`
+
+	return queryDeliveries(database, query, sqliteTime(now), limit)
+}
+
+// GetDeliveries retrieves a webhook's delivery log, newest first.
+// beforeID > 0 continues from a previous page.
+func GetDeliveries(database *sql.DB, webhookID, beforeID, limit int) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries
+		WHERE webhook_id = ? AND (? = 0 OR id < ?)
+		ORDER BY id DESC
+		LIMIT ?
+	`
+
+	return queryDeliveries(database, query, webhookID, beforeID, beforeID, limit)
+}
+
+// queryDeliveries runs a delivery SELECT and scans every row
+func queryDeliveries(database *sql.DB, query string, args ...interface{}) ([]models.WebhookDelivery, error) {
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// RecordDeliveryAttempt stores the outcome of one attempt. status is
+// 'pending' (retry at nextAttemptAt), 'succeeded' or 'failed'.
+func RecordDeliveryAttempt(database *sql.DB, d *models.WebhookDelivery) error {
+	var completedAt interface{}
+	if d.Status != models.DeliveryPending {
+		completedAt = sqliteTime(time.Now())
+	}
+
+	var statusCode, lastError interface{}
+	if d.LastStatusCode != nil {
+		statusCode = *d.LastStatusCode
+	}
+	if d.LastError != nil {
+		lastError = *d.LastError
+	}
+
+	_, err := database.Exec(`
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?, last_error = ?, completed_at = ?
+		WHERE id = ?
+	`, d.Status, d.Attempts, sqliteTime(d.NextAttemptAt), statusCode, lastError, completedAt, d.ID)
+	return err
+}
+
+// FailPendingDeliveries gives up on every pending delivery of a webhook
+func FailPendingDeliveries(database *sql.DB, webhookID int, reason string) error {
+	_, err := database.Exec(`
+		UPDATE webhook_deliveries
+		SET status = 'failed', last_error = ?, completed_at = datetime('now')
+		WHERE webhook_id = ? AND status = 'pending'
+	`, reason, webhookID)
+	return err
+}
+
+// PruneWebhookDeliveries deletes up to batchSize finished deliveries created
+// before cutoff. Returns the number of rows deleted.
+func PruneWebhookDeliveries(database *sql.DB, cutoff time.Time, batchSize int) (int, error) {
+	result, err := database.Exec(`
+		DELETE FROM webhook_deliveries
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status != 'pending' AND created_at < ?
+			ORDER BY id
+			LIMIT ?
+		)
+	`, sqliteTime(cutoff), batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
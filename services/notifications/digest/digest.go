@@ -0,0 +1,275 @@
+package digest
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"social-network/services/notifications/db"
+	"social-network/services/notifications/email"
+	"social-network/services/notifications/models"
+	"social-network/services/notifications/quiet"
+	"social-network/services/notifications/templates"
+	"time"
+
+	texttemplate "text/template"
+)
+
+// maxItems caps how many notifications are listed in one email
+const maxItems = 20
+
+// Periods maps each digest frequency to the window it covers
+var Periods = map[string]time.Duration{
+	models.DigestHourly: time.Hour,
+	models.DigestDaily:  24 * time.Hour,
+	models.DigestWeekly: 7 * 24 * time.Hour,
+}
+
+// Config holds digest job settings
+type Config struct {
+	CheckInterval    time.Duration // How often the job looks for due digests
+	MaxAge           time.Duration // Oldest notifications a digest still lists
+	DefaultFrequency string        // Frequency for users who never set one
+	PublicURL        string        // Public base URL of this service (for unsubscribe links)
+	AppURL           string        // Frontend URL linked from the email
+	From             string        // From header
+}
+
+// LoadConfig reads the digest settings from the environment
+func LoadConfig() Config {
+	cfg := Config{
+		CheckInterval:    5 * time.Minute,
+		MaxAge:           14 * 24 * time.Hour,
+		DefaultFrequency: models.DigestDaily,
+		PublicURL:        "http://localhost:8086",
+		AppURL:           "http://localhost:3000",
+		From:             email.DefaultFrom(),
+	}
+
+	if v := os.Getenv("DIGEST_CHECK_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.CheckInterval = d
+		} else {
+			log.Printf("[Digest] Ignoring invalid DIGEST_CHECK_INTERVAL %q", v)
+		}
+	}
+	if v := os.Getenv("DIGEST_MAX_AGE"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= Periods[models.DigestWeekly] {
+			cfg.MaxAge = d
+		} else {
+			log.Printf("[Digest] Ignoring invalid DIGEST_MAX_AGE %q", v)
+		}
+	}
+	if v := os.Getenv("DIGEST_DEFAULT_FREQUENCY"); v != "" {
+		if _, ok := Periods[v]; ok || v == models.DigestOff {
+			cfg.DefaultFrequency = v
+		} else {
+			log.Printf("[Digest] Ignoring invalid DIGEST_DEFAULT_FREQUENCY %q", v)
+		}
+	}
+	if v := os.Getenv("PUBLIC_URL"); v != "" {
+		cfg.PublicURL = v
+	}
+	if v := os.Getenv("APP_URL"); v != "" {
+		cfg.AppURL = v
+	}
+
+	return cfg
+}
+
+// Scheduler periodically emails unread notification digests
+type Scheduler struct {
+	database *sql.DB
+	sender   email.Sender
+	config   Config
+}
+
+// NewScheduler creates a new digest scheduler
+func NewScheduler(database *sql.DB, sender email.Sender, config Config) *Scheduler {
+	return &Scheduler{
+		database: database,
+		sender:   sender,
+		config:   config,
+	}
+}
+
+// Run checks for due digests every CheckInterval (blocks forever)
+func (s *Scheduler) Run() {
+	log.Printf("[Digest] Scheduler started (interval %v, default frequency %s)", s.config.CheckInterval, s.config.DefaultFrequency)
+
+	ticker := time.NewTicker(s.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.RunOnce(time.Now())
+		<-ticker.C
+	}
+}
+
+// RunOnce sends every digest that is due at `now`: a period after the
+// previous one. A digest lists every unread notification no digest listed
+// yet, up to MaxAge old, so the ones since the previous digest are all
+// covered however late the check runs or quiet hours defer it.
+func (s *Scheduler) RunOnce(now time.Time) {
+	oldest := now.Add(-s.config.MaxAge)
+	for _, frequency := range []string{models.DigestHourly, models.DigestDaily, models.DigestWeekly} {
+		due := now.Add(-Periods[frequency])
+
+		recipients, err := db.GetDigestRecipients(s.database, frequency, s.config.DefaultFrequency, due, oldest)
+		if err != nil {
+			log.Printf("[Digest] Error finding %s recipients: %v", frequency, err)
+			continue
+		}
+
+		for _, recipient := range recipients {
+			if err := s.sendDigest(recipient, oldest, now); err != nil {
+				log.Printf("[Digest] Failed to send %s digest to user %d: %v", frequency, recipient.UserID, err)
+			}
+		}
+	}
+}
+
+// sendDigest builds and delivers one user's digest.
+// The notifications are claimed before sending so that a crash or a
+// concurrent run can never email the same notification twice.
+func (s *Scheduler) sendDigest(recipient models.DigestRecipient, since, now time.Time) error {
+	if recipient.Email == "" {
+		return nil
+	}
+
+	notifications, err := db.GetUndigestedNotifications(s.database, recipient.UserID, since, maxItems+1)
+	if err != nil {
+		return err
+	}
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	more := false
+	if len(notifications) > maxItems {
+		// Unlisted notifications stay unclaimed for the next digest
+		notifications = notifications[:maxItems]
+		more = true
+	}
+
+	prefs, err := db.GetPreferences(s.database, recipient.UserID, s.config.DefaultFrequency)
+	if err != nil {
+		return err
+	}
+
+	// Deferred to the first run after quiet hours / DND
+	if quiet.IsActive(prefs, now) {
+		return nil
+	}
+
+	templates.RenderNotifications(s.database, notifications, prefs.Locale)
+
+	msg, err := s.buildMessage(recipient, prefs, notifications, more)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int, len(notifications))
+	for i, n := range notifications {
+		ids[i] = n.ID
+	}
+
+	digestID, err := db.ClaimDigest(s.database, recipient, ids)
+	if err != nil {
+		return fmt.Errorf("claim: %w", err)
+	}
+
+	if err := s.sender.Send(msg); err != nil {
+		if releaseErr := db.ReleaseDigest(s.database, digestID); releaseErr != nil {
+			log.Printf("[Digest] Failed to release digest %d: %v", digestID, releaseErr)
+		}
+		return fmt.Errorf("send: %w", err)
+	}
+
+	if err := db.MarkDigestSent(s.database, digestID); err != nil {
+		return err
+	}
+
+	log.Printf("[Digest] Sent %s digest %d to user %d (%d notifications)", recipient.Frequency, digestID, recipient.UserID, len(ids))
+	return nil
+}
+
+// digestItem is one line in the email
+type digestItem struct {
+	Content string
+	When    string
+}
+
+// buildMessage renders the text and HTML bodies
+func (s *Scheduler) buildMessage(recipient models.DigestRecipient, prefs *models.Preferences, notifications []models.Notification, more bool) (*email.Message, error) {
+	locale := prefs.Locale
+	if _, ok := wording[locale]; !ok {
+		locale = templates.DefaultLocale
+	}
+
+	unsubscribeURL := s.config.PublicURL + "/notifications/unsubscribe?token=" + url.QueryEscape(prefs.UnsubscribeToken)
+
+	items := make([]digestItem, len(notifications))
+	for i, n := range notifications {
+		items[i] = digestItem{
+			Content: n.Content,
+			When:    n.CreatedAt.UTC().Format("Jan 2, 15:04 UTC"),
+		}
+	}
+
+	phraseData := map[string]interface{}{
+		"Username": recipient.Username,
+		"Count":    len(notifications),
+	}
+	phrase := func(key string) (string, error) {
+		tmpl, err := texttemplate.New(key).Parse(wording[locale][key])
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, phraseData); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	data := map[string]interface{}{
+		"Items":          items,
+		"More":           more,
+		"AppURL":         s.config.AppURL,
+		"UnsubscribeURL": unsubscribeURL,
+	}
+	for field, key := range map[string]string{
+		"Subject":         "subject",
+		"Greeting":        "greeting",
+		"Intro":           "intro",
+		"MoreText":        "more",
+		"ViewAllText":     "view_all",
+		"UnsubscribeText": "unsubscribe",
+	} {
+		text, err := phrase(key)
+		if err != nil {
+			return nil, err
+		}
+		data[field] = text
+	}
+
+	var text, html bytes.Buffer
+	if err := textTemplate.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err := htmlTemplate.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	return &email.Message{
+		From:           s.config.From,
+		To:             recipient.Email,
+		Subject:        data["Subject"].(string),
+		Text:           text.String(),
+		HTML:           html.String(),
+		UnsubscribeURL: unsubscribeURL,
+	}, nil
+}
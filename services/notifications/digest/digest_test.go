@@ -0,0 +1,172 @@
+package digest
+
+import (
+	"database/sql"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"social-network/services/common/testdb"
+	"social-network/services/notifications/email"
+	"social-network/services/notifications/models"
+)
+
+// recorder is an email.Sender that keeps what it sends
+type recorder struct {
+	sent []*email.Message
+}
+
+func (r *recorder) Send(msg *email.Message) error {
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func sqlTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// setup creates an hourly digest user and a scheduler for them
+func setup(t *testing.T) (*sql.DB, *Scheduler, *recorder, int) {
+	database := testdb.Open(t)
+	userID := testdb.User(t, database, "alice")
+	_, err := database.Exec(`
+		INSERT INTO notification_preferences (user_id, digest_frequency, unsubscribe_token)
+		VALUES (?, ?, 'token')
+	`, userID, models.DigestHourly)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sender := &recorder{}
+	config := LoadConfig()
+	return database, NewScheduler(database, sender, config), sender, userID
+}
+
+func notification(t *testing.T, database *sql.DB, userID int, createdAt time.Time) int {
+	t.Helper()
+	result, err := database.Exec(`
+		INSERT INTO notifications (user_id, type, content, created_at) VALUES (?, 'follow', 'Someone followed you', ?)
+	`, userID, sqlTime(createdAt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := result.LastInsertId()
+	return int(id)
+}
+
+// previousDigest records a digest sent at createdAt, listing ids
+func previousDigest(t *testing.T, database *sql.DB, userID int, createdAt time.Time, ids ...int) {
+	t.Helper()
+	result, err := database.Exec(`
+		INSERT INTO email_digests (user_id, frequency, recipient, notification_count, status, created_at, sent_at)
+		VALUES (?, ?, 'alice@example.com', ?, 'sent', ?, ?)
+	`, userID, models.DigestHourly, len(ids), sqlTime(createdAt), sqlTime(createdAt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	digestID, _ := result.LastInsertId()
+	for _, id := range ids {
+		if _, err := database.Exec(`INSERT INTO email_digest_items (digest_id, notification_id) VALUES (?, ?)`, digestID, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// digested lists the notifications claimed by digests, in ID order
+func digested(t *testing.T, database *sql.DB) []int {
+	t.Helper()
+	rows, err := database.Query(`SELECT notification_id FROM email_digest_items`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// A check running later than a period after the previous digest still
+// lists everything since that digest, not only the last period
+func TestRunOnceLateCheck(t *testing.T) {
+	database, scheduler, sender, userID := setup(t)
+	now := time.Now().UTC()
+
+	listed := notification(t, database, userID, now.Add(-100*time.Minute))
+	previousDigest(t, database, userID, now.Add(-90*time.Minute), listed)
+	gap := notification(t, database, userID, now.Add(-80*time.Minute))
+	recent := notification(t, database, userID, now.Add(-10*time.Minute))
+
+	scheduler.RunOnce(now)
+
+	if len(sender.sent) != 1 {
+		t.Fatalf("sent %d digests, want 1", len(sender.sent))
+	}
+	if got, want := digested(t, database), []int{listed, gap, recent}; !reflect.DeepEqual(got, want) {
+		t.Errorf("digested notifications = %v, want %v", got, want)
+	}
+}
+
+func TestRunOnceNotDue(t *testing.T) {
+	database, scheduler, sender, userID := setup(t)
+	now := time.Now().UTC()
+
+	previousDigest(t, database, userID, now.Add(-30*time.Minute))
+	notification(t, database, userID, now.Add(-10*time.Minute))
+
+	scheduler.RunOnce(now)
+
+	if len(sender.sent) != 0 {
+		t.Errorf("sent %d digests within the period of the previous one", len(sender.sent))
+	}
+}
+
+// Notifications held back during DND are listed by the first digest after
+func TestRunOnceDeferredByDND(t *testing.T) {
+	database, scheduler, sender, userID := setup(t)
+	now := time.Now().UTC()
+
+	_, err := database.Exec(`UPDATE notification_preferences SET dnd_until = ? WHERE user_id = ?`,
+		sqlTime(now.Add(3*time.Hour)), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deferred := notification(t, database, userID, now.Add(-10*time.Minute))
+
+	scheduler.RunOnce(now)
+	if len(sender.sent) != 0 {
+		t.Fatalf("sent %d digests during DND", len(sender.sent))
+	}
+
+	scheduler.RunOnce(now.Add(4 * time.Hour))
+	if len(sender.sent) != 1 {
+		t.Fatalf("sent %d digests after DND, want 1", len(sender.sent))
+	}
+	if got, want := digested(t, database), []int{deferred}; !reflect.DeepEqual(got, want) {
+		t.Errorf("digested notifications = %v, want %v", got, want)
+	}
+}
+
+func TestRunOnceMaxAge(t *testing.T) {
+	database, scheduler, sender, userID := setup(t)
+	now := time.Now().UTC()
+
+	notification(t, database, userID, now.Add(-scheduler.config.MaxAge-time.Hour))
+	recent := notification(t, database, userID, now.Add(-time.Hour))
+
+	scheduler.RunOnce(now)
+
+	if len(sender.sent) != 1 {
+		t.Fatalf("sent %d digests, want 1", len(sender.sent))
+	}
+	if got, want := digested(t, database), []int{recent}; !reflect.DeepEqual(got, want) {
+		t.Errorf("digested notifications = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,57 @@
+package digest
+
+import (
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+// wording holds the per-locale text around the notification list.
+// The notification lines themselves come from the templates package.
+var wording = map[string]map[string]string{
+	"en": {
+		"subject":     "You have {{.Count}} unread notification(s)",
+		"greeting":    "Hi {{.Username}},",
+		"intro":       "Here's what you missed:",
+		"more":        "…and more waiting in the app.",
+		"view_all":    "View all notifications",
+		"unsubscribe": "Unsubscribe from these emails",
+	},
+	"fr": {
+		"subject":     "Vous avez {{.Count}} notification(s) non lue(s)",
+		"greeting":    "Bonjour {{.Username}},",
+		"intro":       "Voici ce que vous avez manqué :",
+		"more":        "…et d'autres vous attendent dans l'application.",
+		"view_all":    "Voir toutes les notifications",
+		"unsubscribe": "Se désabonner de ces e-mails",
+	},
+}
+
+var textTemplate = texttemplate.Must(texttemplate.New("digest.txt").Parse(`{{.Greeting}}
+
+{{.Intro}}
+{{range .Items}}
+- {{.Content}} ({{.When}})
+{{- end}}
+{{if .More}}
+{{.MoreText}}
+{{end}}
+{{.ViewAllText}}: {{.AppURL}}
+
+{{.UnsubscribeText}}: {{.UnsubscribeURL}}
+`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("digest.html").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #222;">
+  <p>{{.Greeting}}</p>
+  <p>{{.Intro}}</p>
+  <ul>
+    {{range .Items}}<li>{{.Content}} <span style="color: #888;">({{.When}})</span></li>
+    {{end}}
+  </ul>
+  {{if .More}}<p>{{.MoreText}}</p>{{end}}
+  <p><a href="{{.AppURL}}">{{.ViewAllText}}</a></p>
+  <p style="font-size: 12px; color: #888;"><a href="{{.UnsubscribeURL}}">{{.UnsubscribeText}}</a></p>
+</body>
+</html>
+`))
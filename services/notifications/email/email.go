@@ -0,0 +1,211 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Message is an outgoing email with a plain-text and an HTML part
+type Message struct {
+	From           string
+	To             string
+	Subject        string
+	Text           string
+	HTML           string
+	UnsubscribeURL string // Adds List-Unsubscribe headers (RFC 2369 / RFC 8058 one-click)
+}
+
+// Sender delivers email messages
+type Sender interface {
+	Send(msg *Message) error
+}
+
+// ============================================
+// SMTP SENDER
+// ============================================
+
+// SMTPSender delivers mail through an SMTP server.
+// Auth is only used when Username is set, so it also works against a
+// local stand-in server (e.g. MailHog / smtp4dev) without credentials.
+type SMTPSender struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+// Send sends msg via SMTP (STARTTLS is used if the server offers it)
+func (s *SMTPSender) Send(msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	return smtp.SendMail(s.Host+":"+s.Port, auth, addressOnly(msg.From), []string{addressOnly(msg.To)}, data)
+}
+
+// ============================================
+// FILE SENDER
+// ============================================
+
+// FileSender writes each message as an .eml file into Dir.
+// Useful for local development and tests.
+type FileSender struct {
+	Dir string
+}
+
+// Send writes msg to a new file in the sink directory
+func (s *FileSender) Send(msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.Dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), randomHex(4))
+	return os.WriteFile(filepath.Join(s.Dir, filename), data, 0644)
+}
+
+// ============================================
+// CONFIGURATION
+// ============================================
+
+// NewSenderFromEnv builds a sender from environment variables:
+//
+//	EMAIL_SENDER=smtp  SMTP_HOST, SMTP_PORT (default 25), SMTP_USERNAME, SMTP_PASSWORD
+//	EMAIL_SENDER=file  EMAIL_FILE_DIR (default ./mail)
+//
+// Returns nil when EMAIL_SENDER is unset, which disables email delivery.
+func NewSenderFromEnv() (Sender, error) {
+	switch os.Getenv("EMAIL_SENDER") {
+	case "":
+		return nil, nil
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required when EMAIL_SENDER=smtp")
+		}
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "25"
+		}
+		log.Printf("[Email] Using SMTP sender %s:%s", host, port)
+		return &SMTPSender{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}, nil
+	case "file":
+		dir := os.Getenv("EMAIL_FILE_DIR")
+		if dir == "" {
+			dir = "./mail"
+		}
+		log.Printf("[Email] Using file sender, writing to %s", dir)
+		return &FileSender{Dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unknown EMAIL_SENDER %q (use smtp or file)", os.Getenv("EMAIL_SENDER"))
+	}
+}
+
+// DefaultFrom returns the sender address from EMAIL_FROM
+func DefaultFrom() string {
+	from := os.Getenv("EMAIL_FROM")
+	if from == "" {
+		from = "Social Network <no-reply@social-network.local>"
+	}
+	return from
+}
+
+// ============================================
+// MESSAGE ENCODING
+// ============================================
+
+// Bytes encodes the message as a multipart/alternative MIME email
+func (m *Message) Bytes() ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	if err := writePart(writer, "text/plain; charset=UTF-8", m.Text); err != nil {
+		return nil, err
+	}
+	if err := writePart(writer, "text/html; charset=UTF-8", m.HTML); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+
+	header("From", m.From)
+	header("To", m.To)
+	header("Subject", mime.QEncoding.Encode("UTF-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%d.%s@social-network.local>", time.Now().UnixNano(), randomHex(8)))
+	header("MIME-Version", "1.0")
+	if m.UnsubscribeURL != "" {
+		header("List-Unsubscribe", "<"+m.UnsubscribeURL+">")
+		header("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+	header("Content-Type", "multipart/alternative; boundary="+writer.Boundary())
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+// writePart adds a quoted-printable encoded part
+func writePart(writer *multipart.Writer, contentType, content string) error {
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// addressOnly extracts "user@host" from "Name <user@host>"
+func addressOnly(addr string) string {
+	if parsed, err := mail.ParseAddress(addr); err == nil {
+		return parsed.Address
+	}
+	return addr
+}
+
+// randomHex returns n random bytes hex-encoded
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
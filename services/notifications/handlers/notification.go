@@ -8,22 +8,62 @@ import (
 	"social-network/services/notifications/db"
 	"social-network/services/notifications/middleware"
 	"social-network/services/notifications/models"
+	"social-network/services/notifications/push"
+	"social-network/services/notifications/quiet"
+	"social-network/services/notifications/templates"
 	"social-network/services/notifications/utils"
+	"social-network/services/notifications/webhook"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// Maximum page size for the notification list
+const maxListLimit = 100
+
+// validTypes are the accepted notification types
+var validTypes = map[string]bool{
+	models.TypeFollow:        true,
+	models.TypeFollowRequest: true,
+	models.TypeGroupInvite:   true,
+	models.TypeGroupRequest:  true,
+	models.TypeGroupActivity: true,
+	models.TypeEvent:         true,
+	models.TypeMessage:       true,
+	models.TypeComment:       true,
+	models.TypePost:          true,
+	models.TypeReaction:      true,
+	models.TypeMention:       true,
+	models.TypeRepost:        true,
+}
+
+// validTargets are the accepted target types
+var validTargets = map[string]bool{
+	models.TargetUser:    true,
+	models.TargetGroup:   true,
+	models.TargetEvent:   true,
+	models.TargetPost:    true,
+	models.TargetComment: true,
+	models.TargetMessage: true,
+}
+
 // NotificationHandlers handles notification-related HTTP requests
 type NotificationHandlers struct {
 	database *sql.DB
 	hub      *NotificationHub
+	pusher   *push.Sender // nil when Web Push is not configured
+	quiet    *quiet.Gate
+	webhooks *webhook.Worker
 }
 
 // NewNotificationHandlers creates a new NotificationHandlers
-func NewNotificationHandlers(database *sql.DB, hub *NotificationHub) *NotificationHandlers {
+func NewNotificationHandlers(database *sql.DB, hub *NotificationHub, pusher *push.Sender, webhooks *webhook.Worker) *NotificationHandlers {
 	return &NotificationHandlers{
 		database: database,
 		hub:      hub,
+		pusher:   pusher,
+		quiet:    quiet.NewGate(database),
+		webhooks: webhooks,
 	}
 }
 
@@ -41,22 +81,17 @@ func (h *NotificationHandlers) CreateNotification(w http.ResponseWriter, r *http
 	}
 
 	// Validate notification type
-	validTypes := map[string]bool{
-		models.TypeFollow:        true,
-		models.TypeFollowRequest: true,
-		models.TypeGroupInvite:   true,
-		models.TypeGroupRequest:  true,
-		models.TypeEvent:         true,
-		models.TypeMessage:       true,
-		models.TypeComment:       true,
-		models.TypePost:          true,
-	}
-
 	if !validTypes[req.Type] {
 		utils.SendError(w, http.StatusBadRequest, "Invalid notification type")
 		return
 	}
 
+	// Validate target type (optional for legacy callers)
+	if req.TargetType != "" && !validTargets[req.TargetType] {
+		utils.SendError(w, http.StatusBadRequest, "Invalid target type")
+		return
+	}
+
 	// Create notification
 	notification, err := db.CreateNotification(h.database, &req)
 	if err != nil {
@@ -65,8 +100,20 @@ func (h *NotificationHandlers) CreateNotification(w http.ResponseWriter, r *http
 		return
 	}
 
-	// Broadcast to WebSocket if user is online
-	h.hub.BroadcastNotification(notification)
+	// During quiet hours / DND the notification is only stored; a summary
+	// is delivered live once the quiet period ends
+	if !h.quiet.Hold(notification, time.Now()) {
+		// Broadcast to WebSocket if user is online
+		h.hub.BroadcastNotification(notification)
+
+		// Push to the user's browsers even if no tab is open
+		if h.pusher != nil {
+			go h.pusher.Notify(*notification)
+		}
+	}
+
+	// Webhooks are machine consumers, quiet hours don't apply to them
+	go h.webhooks.Enqueue(*notification)
 
 	utils.SendSuccess(w, notification)
 }
@@ -84,45 +131,112 @@ func (h *NotificationHandlers) GetNotifications(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// Parse query parameters
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-	unreadOnly := r.URL.Query().Get("unread") == "true"
+	filter, errMsg := parseNotificationFilter(r)
+	if errMsg != "" {
+		utils.SendError(w, http.StatusBadRequest, errMsg)
+		return
+	}
 
-	limit := 20
-	offset := 0
+	notifications, next, err := db.ListNotifications(h.database, userID, filter)
+	if err != nil {
+		log.Printf("Error fetching notifications: %v", err)
+		utils.SendError(w, http.StatusInternalServerError, "Failed to fetch notifications")
+		return
+	}
 
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
+	// Render content in the client's locale with current names
+	templates.RenderNotifications(h.database, notifications, templates.Locale(r))
+
+	var nextCursor *string
+	if next != nil {
+		encoded := next.Encode()
+		nextCursor = &encoded
+	}
+
+	utils.SendSuccess(w, map[string]interface{}{
+		"notifications": notifications,
+		"count":         len(notifications),
+		"has_more":      next != nil,
+		"next_cursor":   nextCursor,
+	})
+}
+
+// parseNotificationFilter reads the list query parameters:
+//
+//	limit        page size (default 20, max 100)
+//	cursor       next_cursor from the previous page
+//	offset       legacy offset pagination, ignored when cursor is set
+//	type         comma-separated notification types
+//	read         "true" or "false" (unread=true is accepted as read=false)
+//	since/until  RFC 3339 time range on created_at
+//
+// Returns an error message for invalid parameters.
+func parseNotificationFilter(r *http.Request) (models.NotificationFilter, string) {
+	query := r.URL.Query()
+	filter := models.NotificationFilter{Limit: 20}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			return filter, "Invalid limit"
+		}
+		if l > maxListLimit {
+			l = maxListLimit
 		}
+		filter.Limit = l
 	}
 
-	if offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		cursor, err := models.DecodeNotificationCursor(cursorStr)
+		if err != nil {
+			return filter, "Invalid cursor"
+		}
+		filter.Cursor = cursor
+	} else if offsetStr := query.Get("offset"); offsetStr != "" {
+		o, err := strconv.Atoi(offsetStr)
+		if err != nil || o < 0 {
+			return filter, "Invalid offset"
 		}
+		filter.Offset = o
 	}
 
-	var notifications []models.Notification
-	var err error
+	for _, typesStr := range query["type"] {
+		for _, t := range strings.Split(typesStr, ",") {
+			t = strings.TrimSpace(t)
+			if t == "" {
+				continue
+			}
+			if !validTypes[t] {
+				return filter, "Invalid notification type"
+			}
+			filter.Types = append(filter.Types, t)
+		}
+	}
 
-	if unreadOnly {
-		notifications, err = db.GetUnreadNotifications(h.database, userID)
-	} else {
-		notifications, err = db.GetUserNotifications(h.database, userID, limit, offset)
+	switch readStr := query.Get("read"); readStr {
+	case "":
+		if query.Get("unread") == "true" {
+			isRead := false
+			filter.IsRead = &isRead
+		}
+	case "true", "false":
+		isRead := readStr == "true"
+		filter.IsRead = &isRead
+	default:
+		return filter, "Invalid read filter"
 	}
 
-	if err != nil {
-		log.Printf("Error fetching notifications: %v", err)
-		utils.SendError(w, http.StatusInternalServerError, "Failed to fetch notifications")
-		return
+	for param, dest := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := query.Get(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, "Invalid " + param + " (use RFC 3339)"
+			}
+			*dest = &t
+		}
 	}
 
-	utils.SendSuccess(w, map[string]interface{}{
-		"notifications": notifications,
-		"count":         len(notifications),
-	})
+	return filter, ""
 }
 
 // GetUnreadCount returns the count of unread notifications
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"social-network/services/notifications/db"
+	"social-network/services/notifications/middleware"
+	"social-network/services/notifications/models"
+	"social-network/services/notifications/quiet"
+	"social-network/services/notifications/templates"
+	"social-network/services/notifications/utils"
+	"time"
+)
+
+// PreferenceHandlers handles notification preference requests
+type PreferenceHandlers struct {
+	database         *sql.DB
+	defaultFrequency string
+}
+
+// NewPreferenceHandlers creates a new PreferenceHandlers
+func NewPreferenceHandlers(database *sql.DB, defaultFrequency string) *PreferenceHandlers {
+	return &PreferenceHandlers{
+		database:         database,
+		defaultFrequency: defaultFrequency,
+	}
+}
+
+// Preferences handles GET and PUT /notifications/preferences
+func (h *PreferenceHandlers) Preferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	prefs, err := db.GetPreferences(h.database, userID, h.defaultFrequency)
+	if err != nil {
+		log.Printf("Error loading preferences: %v", err)
+		utils.SendError(w, http.StatusInternalServerError, "Failed to load preferences")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		utils.SendSuccess(w, prefs)
+
+	case http.MethodPut:
+		var req models.UpdatePreferencesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		if req.DigestFrequency != nil {
+			switch *req.DigestFrequency {
+			case models.DigestOff, models.DigestHourly, models.DigestDaily, models.DigestWeekly:
+				prefs.DigestFrequency = *req.DigestFrequency
+			default:
+				utils.SendError(w, http.StatusBadRequest, "Invalid digest frequency")
+				return
+			}
+		}
+
+		if req.Locale != nil {
+			if !templates.IsSupported(*req.Locale) {
+				utils.SendError(w, http.StatusBadRequest, "Unsupported locale")
+				return
+			}
+			prefs.Locale = *req.Locale
+		}
+
+		if req.PushEnabled != nil {
+			prefs.PushEnabled = *req.PushEnabled
+		}
+
+		if msg := applyQuietSettings(prefs, &req); msg != "" {
+			utils.SendError(w, http.StatusBadRequest, msg)
+			return
+		}
+
+		if err := db.UpdatePreferences(h.database, prefs); err != nil {
+			log.Printf("Error updating preferences: %v", err)
+			utils.SendError(w, http.StatusInternalServerError, "Failed to update preferences")
+			return
+		}
+
+		utils.SendSuccess(w, prefs)
+
+	default:
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// Longest manual do-not-disturb period
+const maxDNDMinutes = 7 * 24 * 60
+
+// applyQuietSettings copies the quiet-hours and DND fields of req into prefs,
+// returning an error message for invalid values
+func applyQuietSettings(prefs *models.Preferences, req *models.UpdatePreferencesRequest) string {
+	if req.QuietHoursEnabled != nil {
+		prefs.QuietHoursEnabled = *req.QuietHoursEnabled
+	}
+
+	if req.QuietStart != nil {
+		if _, ok := quiet.ParseClock(*req.QuietStart); !ok {
+			return "Invalid quiet_start (use HH:MM)"
+		}
+		prefs.QuietStart = *req.QuietStart
+	}
+
+	if req.QuietEnd != nil {
+		if _, ok := quiet.ParseClock(*req.QuietEnd); !ok {
+			return "Invalid quiet_end (use HH:MM)"
+		}
+		prefs.QuietEnd = *req.QuietEnd
+	}
+
+	if req.Timezone != nil {
+		if !quiet.ValidTimezone(*req.Timezone) {
+			return "Unknown timezone"
+		}
+		prefs.Timezone = *req.Timezone
+	}
+
+	if req.DNDMinutes != nil {
+		minutes := *req.DNDMinutes
+		if minutes < 0 || minutes > maxDNDMinutes {
+			return "Invalid dnd_minutes"
+		}
+		if minutes == 0 {
+			prefs.DNDUntil = nil
+		} else {
+			until := time.Now().Add(time.Duration(minutes) * time.Minute).UTC()
+			prefs.DNDUntil = &until
+		}
+	}
+
+	if req.QuietBypass != nil {
+		rules := *req.QuietBypass
+		for i := range rules {
+			if !validTypes[rules[i].Type] {
+				return "Invalid notification type in quiet_bypass"
+			}
+			switch rules[i].From {
+			case "":
+				rules[i].From = models.BypassFromAnyone
+			case models.BypassFromAnyone, models.BypassFromMutuals:
+			default:
+				return "Invalid quiet_bypass scope (use anyone or mutuals)"
+			}
+		}
+		prefs.QuietBypass = rules
+	}
+
+	return ""
+}
+
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+{{if .Done}}
+  <p>You have been unsubscribed from notification emails.</p>
+{{else}}
+  <form method="POST" action="/notifications/unsubscribe?token={{.Token}}">
+    <p>Stop receiving notification digest emails?</p>
+    <button type="submit">Unsubscribe</button>
+  </form>
+{{end}}
+</body>
+</html>
+`))
+
+// Unsubscribe handles /notifications/unsubscribe?token=...
+// POST unsubscribes immediately (RFC 8058 one-click from the List-Unsubscribe header).
+// GET only shows a confirmation form, since mail scanners prefetch links.
+func (h *PreferenceHandlers) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		utils.SendError(w, http.StatusBadRequest, "Missing token")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		unsubscribePage.Execute(w, map[string]interface{}{"Token": token})
+
+	case http.MethodPost:
+		found, err := db.UnsubscribeByToken(h.database, token)
+		if err != nil {
+			log.Printf("Error unsubscribing: %v", err)
+			utils.SendError(w, http.StatusInternalServerError, "Failed to unsubscribe")
+			return
+		}
+		if !found {
+			utils.SendError(w, http.StatusNotFound, "Unknown token")
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		unsubscribePage.Execute(w, map[string]interface{}{"Done": true})
+
+	default:
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"social-network/services/notifications/db"
+	"social-network/services/notifications/middleware"
+	"social-network/services/notifications/models"
+	"social-network/services/notifications/push"
+	"social-network/services/notifications/utils"
+	"social-network/services/notifications/webhook"
+)
+
+// PushHandlers handles Web Push subscription requests
+type PushHandlers struct {
+	database *sql.DB
+	pusher   *push.Sender // nil when Web Push is not configured
+}
+
+// NewPushHandlers creates a new PushHandlers
+func NewPushHandlers(database *sql.DB, pusher *push.Sender) *PushHandlers {
+	return &PushHandlers{
+		database: database,
+		pusher:   pusher,
+	}
+}
+
+// PublicKey handles GET /notifications/push/public-key
+// Returns the VAPID key the browser passes as applicationServerKey.
+func (h *PushHandlers) PublicKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if h.pusher == nil {
+		utils.SendError(w, http.StatusServiceUnavailable, "Push notifications are not enabled")
+		return
+	}
+
+	utils.SendSuccess(w, map[string]string{"public_key": h.pusher.PublicKey()})
+}
+
+// Subscriptions handles GET, POST and DELETE /notifications/push/subscriptions
+func (h *PushHandlers) Subscriptions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		subs, err := db.GetPushSubscriptions(h.database, userID)
+		if err != nil {
+			log.Printf("Error fetching push subscriptions: %v", err)
+			utils.SendError(w, http.StatusInternalServerError, "Failed to fetch subscriptions")
+			return
+		}
+
+		utils.SendSuccess(w, map[string]interface{}{
+			"subscriptions": subs,
+			"count":         len(subs),
+		})
+
+	case http.MethodPost:
+		if h.pusher == nil {
+			utils.SendError(w, http.StatusServiceUnavailable, "Push notifications are not enabled")
+			return
+		}
+
+		var req models.SubscribePushRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		if msg := validateSubscription(&req); msg != "" {
+			utils.SendError(w, http.StatusBadRequest, msg)
+			return
+		}
+
+		sub := &models.PushSubscription{
+			UserID:    userID,
+			Endpoint:  req.Endpoint,
+			P256dh:    req.Keys.P256dh,
+			Auth:      req.Keys.Auth,
+			UserAgent: r.UserAgent(),
+		}
+
+		if err := db.SavePushSubscription(h.database, sub); err != nil {
+			log.Printf("Error saving push subscription: %v", err)
+			utils.SendError(w, http.StatusInternalServerError, "Failed to save subscription")
+			return
+		}
+
+		utils.SendSuccess(w, map[string]bool{"subscribed": true})
+
+	case http.MethodDelete:
+		var req models.UnsubscribePushRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Endpoint == "" {
+			utils.SendError(w, http.StatusBadRequest, "Endpoint is required")
+			return
+		}
+
+		if err := db.DeletePushSubscription(h.database, userID, req.Endpoint); err != nil {
+			log.Printf("Error deleting push subscription: %v", err)
+			utils.SendError(w, http.StatusInternalServerError, "Failed to delete subscription")
+			return
+		}
+
+		utils.SendSuccess(w, map[string]bool{"deleted": true})
+
+	default:
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// validateSubscription checks the endpoint and keys, returning an error message
+func validateSubscription(req *models.SubscribePushRequest) string {
+	endpoint, err := url.Parse(req.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return "Endpoint must be an https URL"
+	}
+	if len(req.Endpoint) > 2048 {
+		return "Endpoint is too long"
+	}
+	// The sender POSTs to it from inside the network
+	if err := webhook.ValidateURL(req.Endpoint, false); err != nil {
+		return "Endpoint must be a public URL"
+	}
+
+	if err := push.ValidateKeys(req.Keys.P256dh, req.Keys.Auth); err != nil {
+		return "Invalid subscription keys"
+	}
+
+	return ""
+}
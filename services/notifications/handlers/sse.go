@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"social-network/services/notifications/middleware"
+	"social-network/services/notifications/models"
+	"social-network/services/notifications/templates"
+	"strconv"
+	"time"
+)
+
+// sseHeartbeat is how often a comment line is sent to keep proxies from
+// closing an idle stream
+const sseHeartbeat = 25 * time.Second
+
+// HandleStream handles GET /notifications/stream, a Server-Sent Events
+// alternative to /ws for clients that can't hold a WebSocket.
+// It registers with the hub like a WebSocket client, so both transports get
+// the same frames; the frame's event_id is also sent as the SSE id, which
+// makes EventSource's automatic Last-Event-ID resume work.
+func (h *NotificationHub) HandleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// EventSource sends Last-Event-ID on reconnect; the query param lets
+	// first connections (and non-browser clients) resume too
+	lastEventIDStr := r.Header.Get("Last-Event-ID")
+	if lastEventIDStr == "" {
+		lastEventIDStr = r.URL.Query().Get("last_event_id")
+	}
+	lastEventID, _ := strconv.Atoi(lastEventIDStr)
+	if lastEventID < 0 {
+		lastEventID = 0
+	}
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
+	w.WriteHeader(http.StatusOK)
+
+	// Reconnect delay for EventSource
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if err := rc.Flush(); err != nil {
+		log.Printf("SSE flush not supported: %v", err)
+		return
+	}
+
+	client := &NotificationClient{
+		hub:         h,
+		send:        make(chan *models.WebSocketNotification, 256),
+		userID:      userID,
+		locale:      templates.Locale(r),
+		lastEventID: lastEventID,
+	}
+
+	h.register <- client
+	defer func() {
+		h.unregister <- client
+	}()
+
+	ticker := time.NewTicker(sseHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case frame, ok := <-client.send:
+			if !ok {
+				return // Dropped by the hub for falling behind
+			}
+
+			data, err := json.Marshal(frame)
+			if err != nil {
+				log.Printf("Error encoding notification frame: %v", err)
+				continue
+			}
+
+			rc.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if frame.EventID > 0 {
+				fmt.Fprintf(w, "id: %d\n", frame.EventID)
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", frame.Type, data)
+			if err := rc.Flush(); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			rc.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			fmt.Fprint(w, ": heartbeat\n\n")
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
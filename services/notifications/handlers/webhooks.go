@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"social-network/services/notifications/db"
+	"social-network/services/notifications/middleware"
+	"social-network/services/notifications/models"
+	"social-network/services/notifications/utils"
+	"social-network/services/notifications/webhook"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxWebhooksPerUser = 10
+	minSecretLength    = 16
+	maxSecretLength    = 256
+	maxWebhookURLLen   = 2048
+)
+
+// WebhookHandlers handles webhook management requests
+type WebhookHandlers struct {
+	database     *sql.DB
+	allowPrivate bool // Accept loopback/private URLs (development stand-ins)
+}
+
+// NewWebhookHandlers creates a new WebhookHandlers
+func NewWebhookHandlers(database *sql.DB, allowPrivate bool) *WebhookHandlers {
+	return &WebhookHandlers{
+		database:     database,
+		allowPrivate: allowPrivate,
+	}
+}
+
+// Webhooks handles GET and POST /notifications/webhooks
+func (h *WebhookHandlers) Webhooks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		hooks, err := db.GetWebhooks(h.database, userID)
+		if err != nil {
+			log.Printf("Error fetching webhooks: %v", err)
+			utils.SendError(w, http.StatusInternalServerError, "Failed to fetch webhooks")
+			return
+		}
+
+		// Secrets are only shown when created or rotated
+		for i := range hooks {
+			hooks[i].Secret = ""
+		}
+
+		utils.SendSuccess(w, map[string]interface{}{
+			"webhooks": hooks,
+			"count":    len(hooks),
+		})
+
+	case http.MethodPost:
+		var req models.CreateWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		if msg := h.validateURL(req.URL); msg != "" {
+			utils.SendError(w, http.StatusBadRequest, msg)
+			return
+		}
+
+		types, msg := validateWebhookTypes(req.Types)
+		if msg != "" {
+			utils.SendError(w, http.StatusBadRequest, msg)
+			return
+		}
+
+		secret := req.Secret
+		if secret == "" {
+			generated, err := webhook.NewSecret()
+			if err != nil {
+				log.Printf("Error generating webhook secret: %v", err)
+				utils.SendError(w, http.StatusInternalServerError, "Failed to create webhook")
+				return
+			}
+			secret = generated
+		} else if len(secret) < minSecretLength || len(secret) > maxSecretLength {
+			utils.SendError(w, http.StatusBadRequest, "Secret must be 16 to 256 characters")
+			return
+		}
+
+		count, err := db.CountWebhooks(h.database, userID)
+		if err != nil {
+			log.Printf("Error counting webhooks: %v", err)
+			utils.SendError(w, http.StatusInternalServerError, "Failed to create webhook")
+			return
+		}
+		if count >= maxWebhooksPerUser {
+			utils.SendError(w, http.StatusConflict, "Webhook limit reached")
+			return
+		}
+
+		hook := &models.Webhook{
+			UserID: userID,
+			URL:    req.URL,
+			Secret: secret,
+			Types:  types,
+		}
+
+		if err := db.CreateWebhook(h.database, hook); err != nil {
+			log.Printf("Error creating webhook: %v", err)
+			utils.SendError(w, http.StatusInternalServerError, "Failed to create webhook")
+			return
+		}
+
+		utils.SendSuccess(w, hook)
+
+	default:
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// Webhook handles /notifications/webhooks/{id}:
+//
+//	GET     the webhook
+//	PUT     update url, types, active, or rotate the secret
+//	DELETE  delete the webhook and its delivery log
+//
+// and GET /notifications/webhooks/{id}/deliveries (the delivery log)
+func (h *WebhookHandlers) Webhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get webhook ID from URL path: notifications/webhooks/{id}[/deliveries]
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) < 3 || len(pathParts) > 4 || (len(pathParts) == 4 && pathParts[3] != "deliveries") {
+		utils.SendError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	webhookID, err := strconv.Atoi(pathParts[2])
+	if err != nil {
+		utils.SendError(w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	hook, err := db.GetWebhook(h.database, webhookID, userID)
+	if err != nil {
+		log.Printf("Error fetching webhook: %v", err)
+		utils.SendError(w, http.StatusInternalServerError, "Failed to fetch webhook")
+		return
+	}
+	if hook == nil {
+		utils.SendError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+
+	if len(pathParts) == 4 {
+		h.deliveries(w, r, hook)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		hook.Secret = ""
+		utils.SendSuccess(w, hook)
+
+	case http.MethodPut:
+		h.update(w, r, hook)
+
+	case http.MethodDelete:
+		if _, err := db.DeleteWebhook(h.database, hook.ID, userID); err != nil {
+			log.Printf("Error deleting webhook: %v", err)
+			utils.SendError(w, http.StatusInternalServerError, "Failed to delete webhook")
+			return
+		}
+
+		utils.SendSuccess(w, map[string]bool{"deleted": true})
+
+	default:
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// update applies an UpdateWebhookRequest to hook
+func (h *WebhookHandlers) update(w http.ResponseWriter, r *http.Request, hook *models.Webhook) {
+	var req models.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.URL != nil {
+		if msg := h.validateURL(*req.URL); msg != "" {
+			utils.SendError(w, http.StatusBadRequest, msg)
+			return
+		}
+		hook.URL = *req.URL
+	}
+
+	if req.Types != nil {
+		types, msg := validateWebhookTypes(*req.Types)
+		if msg != "" {
+			utils.SendError(w, http.StatusBadRequest, msg)
+			return
+		}
+		hook.Types = types
+	}
+
+	if req.Active != nil {
+		// Re-enabling starts the failure count over
+		if *req.Active && !hook.Active {
+			hook.ConsecutiveFailures = 0
+		}
+		hook.Active = *req.Active
+		hook.DisabledReason = nil
+	}
+
+	rotated := req.RotateSecret
+	if rotated {
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			log.Printf("Error generating webhook secret: %v", err)
+			utils.SendError(w, http.StatusInternalServerError, "Failed to update webhook")
+			return
+		}
+		hook.Secret = secret
+	}
+
+	if err := db.UpdateWebhook(h.database, hook); err != nil {
+		log.Printf("Error updating webhook: %v", err)
+		utils.SendError(w, http.StatusInternalServerError, "Failed to update webhook")
+		return
+	}
+
+	if !rotated {
+		hook.Secret = ""
+	}
+	utils.SendSuccess(w, hook)
+}
+
+// deliveries handles GET /notifications/webhooks/{id}/deliveries
+// Query parameters: limit (default 20, max 100) and before (next_before of the previous page).
+func (h *WebhookHandlers) deliveries(w http.ResponseWriter, r *http.Request, hook *models.Webhook) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			utils.SendError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		if l > maxListLimit {
+			l = maxListLimit
+		}
+		limit = l
+	}
+
+	before := 0
+	if beforeStr := r.URL.Query().Get("before"); beforeStr != "" {
+		b, err := strconv.Atoi(beforeStr)
+		if err != nil || b <= 0 {
+			utils.SendError(w, http.StatusBadRequest, "Invalid before")
+			return
+		}
+		before = b
+	}
+
+	deliveries, err := db.GetDeliveries(h.database, hook.ID, before, limit)
+	if err != nil {
+		log.Printf("Error fetching webhook deliveries: %v", err)
+		utils.SendError(w, http.StatusInternalServerError, "Failed to fetch deliveries")
+		return
+	}
+
+	var nextBefore *int
+	if len(deliveries) == limit {
+		nextBefore = &deliveries[len(deliveries)-1].ID
+	}
+
+	utils.SendSuccess(w, map[string]interface{}{
+		"deliveries":  deliveries,
+		"count":       len(deliveries),
+		"next_before": nextBefore,
+	})
+}
+
+// validateURL checks a webhook URL, returning an error message
+func (h *WebhookHandlers) validateURL(rawURL string) string {
+	if len(rawURL) > maxWebhookURLLen {
+		return "URL is too long"
+	}
+	if err := webhook.ValidateURL(rawURL, h.allowPrivate); err != nil {
+		if err == webhook.ErrPrivateAddress {
+			return "URL must not point to a private address"
+		}
+		return "URL must be an absolute http or https URL"
+	}
+	return ""
+}
+
+// validateWebhookTypes checks and de-duplicates the subscribed notification
+// types, returning an error message
+func validateWebhookTypes(types []string) ([]string, string) {
+	if len(types) == 0 {
+		return nil, "At least one notification type is required"
+	}
+
+	seen := map[string]bool{}
+	result := make([]string, 0, len(types))
+	for _, t := range types {
+		if !validTypes[t] {
+			return nil, "Invalid notification type: " + t
+		}
+		if !seen[t] {
+			seen[t] = true
+			result = append(result, t)
+		}
+	}
+
+	return result, ""
+}
@@ -5,8 +5,12 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"social-network/services/notifications/db"
 	"social-network/services/notifications/middleware"
 	"social-network/services/notifications/models"
+	"social-network/services/notifications/quiet"
+	"social-network/services/notifications/templates"
+	"strconv"
 	"sync"
 	"time"
 
@@ -19,81 +23,244 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-// NotificationHub manages WebSocket connections for notifications
+// maxReplay caps how many missed notifications are replayed on reconnect.
+// A client that missed more is told to resync from the REST API instead.
+const maxReplay = 100
+
+// NotificationHub fans notifications out to connected WebSocket and SSE clients
 type NotificationHub struct {
-	clients    map[int]*NotificationClient // userID -> Client
+	clients    map[int]map[*NotificationClient]bool // userID -> set of clients (one per tab)
 	broadcast  chan *models.Notification
+	summaries  chan *models.QuietSummary
 	register   chan *NotificationClient
 	unregister chan *NotificationClient
 	mu         sync.RWMutex
 	database   *sql.DB
+	quiet      *quiet.Gate
 }
 
-// NotificationClient represents a WebSocket client
+// NotificationClient represents one subscriber of the hub, either a
+// WebSocket (conn set, served by readPump/writePump) or an SSE stream (conn nil)
 type NotificationClient struct {
-	hub    *NotificationHub
-	conn   *websocket.Conn
-	send   chan []byte
-	userID int
+	hub         *NotificationHub
+	conn        *websocket.Conn
+	send        chan *models.WebSocketNotification
+	userID      int
+	locale      string
+	lastEventID int // Last event ID the client saw before connecting
+	// Highest notification ID the client got on connecting; live broadcasts
+	// up to it are skipped (only touched by the hub loop)
+	replayedThrough int
 }
 
 // NewNotificationHub creates a new NotificationHub
 func NewNotificationHub(database *sql.DB) *NotificationHub {
 	return &NotificationHub{
-		clients:    make(map[int]*NotificationClient),
+		clients:    make(map[int]map[*NotificationClient]bool),
 		broadcast:  make(chan *models.Notification, 256),
+		summaries:  make(chan *models.QuietSummary, 64),
 		register:   make(chan *NotificationClient),
 		unregister: make(chan *NotificationClient),
 		database:   database,
+		quiet:      quiet.NewGate(database),
 	}
 }
 
-// Run starts the hub's main loop
+// Run starts the hub's main loop. Notifications are rendered outside h.mu,
+// since rendering queries the database.
 func (h *NotificationHub) Run() {
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
-			h.clients[client.userID] = client
+			if h.clients[client.userID] == nil {
+				h.clients[client.userID] = make(map[*NotificationClient]bool)
+			}
+			h.clients[client.userID][client] = true
+			client.replayedThrough = client.lastEventID
+			log.Printf("Client registered for notifications: user %d (%d connections)", client.userID, len(h.clients[client.userID]))
 			h.mu.Unlock()
-			log.Printf("Client registered for notifications: user %d", client.userID)
+
+			// Replay here, in the hub loop, so nothing broadcast meanwhile can
+			// overtake the replayed notifications or be sent twice
+			if client.lastEventID > 0 {
+				h.replay(client)
+			}
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client.userID]; ok {
-				delete(h.clients, client.userID)
-				close(client.send)
-				log.Printf("Client unregistered from notifications: user %d", client.userID)
-			}
+			h.removeClient(client)
 			h.mu.Unlock()
 
 		case notification := <-h.broadcast:
+			// Broadcasts aren't ordered by ID, so only those the client
+			// already got from its replay are skipped
+			var clients []*NotificationClient
 			h.mu.RLock()
-			if client, ok := h.clients[notification.UserID]; ok {
-				wsNotif := models.WebSocketNotification{
-					Type:         "notification",
-					Notification: *notification,
+			for client := range h.clients[notification.UserID] {
+				if notification.ID > client.replayedThrough {
+					clients = append(clients, client)
 				}
-				data, err := json.Marshal(wsNotif)
-				if err == nil {
-					select {
-					case client.send <- data:
-					default:
-						close(client.send)
-						delete(h.clients, client.userID)
-					}
+			}
+			h.mu.RUnlock()
+
+			frames := make(map[string]*models.WebSocketNotification)
+			for _, client := range clients {
+				if frames[client.locale] == nil {
+					frames[client.locale] = notificationFrame(h.database, notification, client.locale)
 				}
 			}
+
+			h.mu.Lock()
+			for _, client := range clients {
+				h.sendFrame(client, frames[client.locale])
+			}
+			h.mu.Unlock()
+
+		case summary := <-h.summaries:
+			h.mu.RLock()
+			var clients []*NotificationClient
+			for client := range h.clients[summary.UserID] {
+				clients = append(clients, client)
+			}
 			h.mu.RUnlock()
+
+			frames := make(map[string]*models.WebSocketNotification)
+			for _, client := range clients {
+				if frames[client.locale] == nil {
+					frames[client.locale] = summaryFrame(h.database, summary, client.locale)
+				}
+			}
+
+			h.mu.Lock()
+			for _, client := range clients {
+				h.sendFrame(client, frames[client.locale])
+			}
+			h.mu.Unlock()
 		}
 	}
 }
 
+// summaryFrame renders a quiet-hours summary in a locale
+func summaryFrame(database *sql.DB, summary *models.QuietSummary, locale string) *models.WebSocketNotification {
+	rendered := *summary
+	rendered.Notifications = append([]models.Notification{}, summary.Notifications...)
+	templates.RenderNotifications(database, rendered.Notifications, locale)
+
+	return &models.WebSocketNotification{
+		Type:    "summary",
+		EventID: summary.LastEventID,
+		Summary: &rendered,
+	}
+}
+
+// notificationFrame renders a notification in a locale
+func notificationFrame(database *sql.DB, notification *models.Notification, locale string) *models.WebSocketNotification {
+	rendered := templates.RenderNotification(database, *notification, locale)
+	return &models.WebSocketNotification{
+		Type:         "notification",
+		EventID:      notification.ID,
+		Notification: &rendered,
+	}
+}
+
+// replay sends a reconnecting client everything after its last_event_id,
+// and records how far it got so live broadcasts of those are skipped.
+// Notifications quiet hours hold back stay held: the quiet summary covers
+// them. Only called from the hub loop.
+func (h *NotificationHub) replay(client *NotificationClient) {
+	missed, err := db.GetNotificationsAfter(h.database, client.userID, client.lastEventID, maxReplay+1)
+	if err != nil {
+		log.Printf("Error replaying notifications for user %d: %v", client.userID, err)
+		h.mu.Lock()
+		h.sendFrame(client, &models.WebSocketNotification{Type: "resync"})
+		h.mu.Unlock()
+		return
+	}
+
+	if len(missed) > maxReplay {
+		// Too far behind, the client reloads its list instead
+		h.mu.Lock()
+		if h.sendFrame(client, &models.WebSocketNotification{Type: "resync"}) {
+			client.replayedThrough = missed[len(missed)-1].ID
+		}
+		h.mu.Unlock()
+		return
+	}
+
+	prefs, err := db.FindPreferences(h.database, client.userID)
+	if err != nil {
+		log.Printf("Error loading preferences of user %d: %v", client.userID, err)
+		prefs = nil // Fail open, like the gate
+	}
+
+	now := time.Now()
+	var replayed []*models.Notification
+	var frames []*models.WebSocketNotification
+	for i := range missed {
+		if h.quiet.Holds(prefs, &missed[i], now) {
+			continue
+		}
+		replayed = append(replayed, &missed[i])
+		frames = append(frames, notificationFrame(h.database, &missed[i], client.locale))
+	}
+
+	h.mu.Lock()
+	for i, frame := range frames {
+		if !h.sendFrame(client, frame) {
+			break
+		}
+		client.replayedThrough = replayed[i].ID
+	}
+	h.mu.Unlock()
+
+	if len(frames) > 0 {
+		log.Printf("Replayed %d notifications to user %d", len(frames), client.userID)
+	}
+}
+
+// sendFrame queues a frame, dropping the client if its buffer is full.
+// Caller must hold h.mu.
+func (h *NotificationHub) sendFrame(client *NotificationClient, frame *models.WebSocketNotification) bool {
+	if !h.clients[client.userID][client] {
+		return false // Dropped earlier, send is closed
+	}
+
+	select {
+	case client.send <- frame:
+		return true
+	default:
+		h.removeClient(client)
+		return false
+	}
+}
+
+// removeClient unregisters one connection and closes its send channel.
+// Caller must hold h.mu.
+func (h *NotificationHub) removeClient(client *NotificationClient) {
+	conns, ok := h.clients[client.userID]
+	if !ok || !conns[client] {
+		return // Already removed (e.g. dropped for being slow)
+	}
+
+	delete(conns, client)
+	if len(conns) == 0 {
+		delete(h.clients, client.userID)
+	}
+	close(client.send)
+	log.Printf("Client unregistered from notifications: user %d (%d connections left)", client.userID, len(conns))
+}
+
 // BroadcastNotification sends a notification to a specific user if online
 func (h *NotificationHub) BroadcastNotification(notification *models.Notification) {
 	h.broadcast <- notification
 }
 
+// BroadcastSummary sends a quiet-hours summary to a specific user if online
+func (h *NotificationHub) BroadcastSummary(summary *models.QuietSummary) {
+	h.summaries <- summary
+}
+
 // HandleWebSocket handles WebSocket connections for notifications
 func (h *NotificationHub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context (set by auth middleware)
@@ -110,12 +277,20 @@ func (h *NotificationHub) HandleWebSocket(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// A reconnecting client passes the last event ID it saw to get missed notifications
+	lastEventID, _ := strconv.Atoi(r.URL.Query().Get("last_event_id"))
+	if lastEventID < 0 {
+		lastEventID = 0
+	}
+
 	// Create new client
 	client := &NotificationClient{
-		hub:    h,
-		conn:   conn,
-		send:   make(chan []byte, 256),
-		userID: userID,
+		hub:         h,
+		conn:        conn,
+		send:        make(chan *models.WebSocketNotification, 256),
+		userID:      userID,
+		locale:      templates.Locale(r),
+		lastEventID: lastEventID,
 	}
 
 	// Register client
@@ -160,27 +335,22 @@ func (c *NotificationClient) writePump() {
 
 	for {
 		select {
-		case message, ok := <-c.send:
+		case frame, ok := <-c.send:
 			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if !ok {
 				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
+			message, err := json.Marshal(frame)
 			if err != nil {
-				return
-			}
-			w.Write(message)
-
-			// Add queued messages
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
+				log.Printf("Error encoding notification frame: %v", err)
+				continue
 			}
 
-			if err := w.Close(); err != nil {
+			// One frame per message: clients JSON.parse each frame, so
+			// queued messages (e.g. a replay burst) can't be batched together
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
 				return
 			}
 
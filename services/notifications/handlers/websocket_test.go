@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"social-network/services/common/testdb"
+	"social-network/services/notifications/db"
+	"social-network/services/notifications/models"
+)
+
+// replayed registers a client that last saw lastEventID, replays to it and
+// returns the event IDs it was sent
+func replayed(t *testing.T, hub *NotificationHub, userID, lastEventID int) []int {
+	t.Helper()
+	client := &NotificationClient{
+		hub:         hub,
+		send:        make(chan *models.WebSocketNotification, 16),
+		userID:      userID,
+		locale:      "en",
+		lastEventID: lastEventID,
+	}
+	hub.clients[userID] = map[*NotificationClient]bool{client: true}
+
+	hub.replay(client)
+
+	ids := []int{}
+	for len(client.send) > 0 {
+		frame := <-client.send
+		if frame.Type != "notification" {
+			t.Fatalf("replayed a %q frame", frame.Type)
+		}
+		ids = append(ids, frame.EventID)
+	}
+	return ids
+}
+
+func createNotification(t *testing.T, database *sql.DB, userID int, notificationType string) int {
+	t.Helper()
+	notification, err := db.CreateNotification(database, &models.CreateNotificationRequest{
+		UserID:  userID,
+		Type:    notificationType,
+		Content: "Something happened",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return notification.ID
+}
+
+// Reconnecting during DND doesn't deliver the notifications it held back,
+// only those a bypass rule lets through
+func TestReplaySkipsHeldNotifications(t *testing.T) {
+	database := testdb.Open(t)
+	userID := testdb.User(t, database, "alice")
+	hub := NewNotificationHub(database)
+
+	seen := createNotification(t, database, userID, "follow")
+
+	prefs, err := db.GetPreferences(database, userID, models.DigestOff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dndUntil := time.Now().Add(time.Hour)
+	prefs.DNDUntil = &dndUntil
+	prefs.QuietBypass = []models.QuietBypassRule{{Type: "message", From: models.BypassFromAnyone}}
+	if err := db.UpdatePreferences(database, prefs); err != nil {
+		t.Fatal(err)
+	}
+
+	createNotification(t, database, userID, "follow")
+	urgent := createNotification(t, database, userID, "message")
+	createNotification(t, database, userID, "comment")
+
+	if got, want := replayed(t, hub, userID, seen), []int{urgent}; !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed during DND = %v, want %v", got, want)
+	}
+
+	// Once DND is over, reconnecting replays everything missed
+	prefs.DNDUntil = nil
+	if err := db.UpdatePreferences(database, prefs); err != nil {
+		t.Fatal(err)
+	}
+	if got := replayed(t, hub, userID, seen); len(got) != 3 {
+		t.Errorf("replayed after DND = %v, want all 3", got)
+	}
+}
@@ -2,15 +2,27 @@ package main
 
 import (
 	"database/sql"
+	"expvar"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"social-network/services/common/authcache"
+	"social-network/services/notifications/digest"
+	"social-network/services/notifications/email"
 	"social-network/services/notifications/handlers"
 	"social-network/services/notifications/middleware"
+	"social-network/services/notifications/models"
+	"social-network/services/notifications/push"
+	"social-network/services/notifications/quiet"
+	"social-network/services/notifications/retention"
+	"social-network/services/notifications/webhook"
 
 	_ "github.com/mattn/go-sqlite3"
+
+	// Embed the timezone database for quiet hours (the alpine image has none)
+	_ "time/tzdata"
 )
 
 func main() {
@@ -39,8 +51,63 @@ func main() {
 	hub := handlers.NewNotificationHub(database)
 	go hub.Run()
 
+	// Start email digest job (disabled when no sender is configured)
+	digestConfig := digest.LoadConfig()
+	sender, err := email.NewSenderFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid email configuration: %v", err)
+	}
+	if sender != nil {
+		go digest.NewScheduler(database, sender, digestConfig).Run()
+	} else {
+		log.Printf("EMAIL_SENDER not set, email digests disabled")
+	}
+
+	// Start retention job (deletes old read notifications, caps per-user totals)
+	retentionConfig, err := retention.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid retention configuration: %v", err)
+	}
+	go retention.NewJob(database, retentionConfig).Run()
+
+	// Web Push sender (disabled when no VAPID key is configured)
+	pushConfig, err := push.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid push configuration: %v", err)
+	}
+	var pusher *push.Sender
+	if pushConfig != nil {
+		pusher = push.NewSender(database, pushConfig)
+		if pushConfig.EndpointOverride != "" {
+			log.Printf("Web Push enabled, sending all pushes to %s", pushConfig.EndpointOverride)
+		} else {
+			log.Printf("Web Push enabled")
+		}
+	} else {
+		log.Printf("VAPID_PRIVATE_KEY not set, web push disabled")
+	}
+
+	// Deliver quiet-hours summaries once each user's quiet period ends
+	go quiet.NewSummarizer(database, time.Minute, func(summary models.QuietSummary) {
+		hub.BroadcastSummary(&summary)
+		if pusher != nil {
+			pusher.NotifySummary(summary)
+		}
+	}).Run()
+
+	// Start webhook delivery worker (retries with backoff, disables failing webhooks)
+	webhookConfig, err := webhook.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid webhook configuration: %v", err)
+	}
+	webhooks := webhook.NewWorker(database, webhookConfig)
+	go webhooks.Run()
+
 	// Create handlers
-	notifHandlers := handlers.NewNotificationHandlers(database, hub)
+	notifHandlers := handlers.NewNotificationHandlers(database, hub, pusher, webhooks)
+	prefHandlers := handlers.NewPreferenceHandlers(database, digestConfig.DefaultFrequency)
+	pushHandlers := handlers.NewPushHandlers(database, pusher)
+	webhookHandlers := handlers.NewWebhookHandlers(database, webhookConfig.AllowPrivate)
 
 	// Create auth middleware and rate limiter
 	authMiddleware := authcache.AuthMiddleware(authServiceURL)
@@ -72,14 +139,47 @@ func main() {
 	// Delete notification (auth required + rate limited)
 	mux.Handle("/notifications/delete/", authMiddleware(rateLimiter.RateLimit(http.HandlerFunc(notifHandlers.DeleteNotification))))
 
+	// Notification preferences (auth required)
+	mux.Handle("/notifications/preferences", authMiddleware(http.HandlerFunc(prefHandlers.Preferences)))
+
+	// One-click unsubscribe from email digests (token in link, no auth)
+	mux.Handle("/notifications/unsubscribe", rateLimiter.RateLimit(http.HandlerFunc(prefHandlers.Unsubscribe)))
+
+	// Web Push key and subscriptions (auth required)
+	mux.Handle("/notifications/push/public-key", authMiddleware(http.HandlerFunc(pushHandlers.PublicKey)))
+	mux.Handle("/notifications/push/subscriptions", authMiddleware(rateLimiter.RateLimit(http.HandlerFunc(pushHandlers.Subscriptions))))
+
+	// Outbound webhooks and their delivery logs (auth required)
+	mux.Handle("/notifications/webhooks", authMiddleware(rateLimiter.RateLimit(http.HandlerFunc(webhookHandlers.Webhooks))))
+	mux.Handle("/notifications/webhooks/", authMiddleware(rateLimiter.RateLimit(http.HandlerFunc(webhookHandlers.Webhook))))
+
 	// WebSocket endpoint (auth required via query param)
 	mux.Handle("/ws", authMiddleware(http.HandlerFunc(hub.HandleWebSocket)))
 
+	// Server-Sent Events stream, same events as /ws (auth via query param, header or cookie)
+	mux.Handle("/notifications/stream", authMiddleware(http.HandlerFunc(hub.HandleStream)))
+
 	// Apply common middleware (CORS and Logging)
 	handler := middleware.CORS(
 		middleware.Logging(mux),
 	)
 
+	// Metrics, including retention counters, on their own listener so they
+	// are never reachable through the public port (expvar exposes memstats
+	// and the command line). Loopback only unless METRICS_ADDR says otherwise.
+	metricsAddr := os.Getenv("METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = "127.0.0.1:9086"
+	}
+	go func() {
+		metrics := http.NewServeMux()
+		metrics.Handle("/debug/vars", expvar.Handler())
+		log.Printf("Metrics listening on %s", metricsAddr)
+		if err := http.ListenAndServe(metricsAddr, metrics); err != nil {
+			log.Printf("Metrics listener stopped: %v", err)
+		}
+	}()
+
 	// Start server
 	log.Println("Notification Service starting on port :8086")
 	log.Fatal(http.ListenAndServe(":8086", handler))
@@ -1,24 +1,82 @@
 package models
 
-import "time"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
 
 // Notification represents a user notification
 type Notification struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Type      string    `json:"type"`
-	RelatedID int       `json:"related_id"`
-	Content   string    `json:"content"`
-	IsRead    bool      `json:"is_read"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         int                    `json:"id"`
+	UserID     int                    `json:"user_id"`
+	Type       string                 `json:"type"`
+	RelatedID  int                    `json:"related_id"`
+	Content    string                 `json:"content"`
+	ActorID    *int                   `json:"actor_id,omitempty"`
+	TargetType *string                `json:"target_type,omitempty"`
+	TargetID   *int                   `json:"target_id,omitempty"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+	IsRead     bool                   `json:"is_read"`
+	CreatedAt  time.Time              `json:"created_at"`
 }
 
 // CreateNotificationRequest is the request body for creating a notification
 type CreateNotificationRequest struct {
-	UserID    int    `json:"user_id"`
-	Type      string `json:"type"`
-	RelatedID int    `json:"related_id"`
-	Content   string `json:"content"`
+	UserID     int                    `json:"user_id"`
+	Type       string                 `json:"type"`
+	RelatedID  int                    `json:"related_id"`
+	Content    string                 `json:"content"`
+	ActorID    int                    `json:"actor_id,omitempty"`
+	TargetType string                 `json:"target_type,omitempty"`
+	TargetID   int                    `json:"target_id,omitempty"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+}
+
+// NotificationFilter selects a page of a user's notifications
+type NotificationFilter struct {
+	Types  []string            // Only these types (all when empty)
+	IsRead *bool               // Only read / unread notifications (all when nil)
+	Since  *time.Time          // created_at >= Since
+	Until  *time.Time          // created_at < Until
+	Cursor *NotificationCursor // Start after this position (newest first)
+	Offset int                 // Legacy offset pagination, ignored when Cursor is set
+	Limit  int
+}
+
+// NotificationCursor is a position in the (created_at DESC, id DESC) ordering.
+// Clients only ever see it encoded, as an opaque string.
+type NotificationCursor struct {
+	CreatedAt string `json:"t"` // As stored, "YYYY-MM-DD HH:MM:SS"
+	ID        int    `json:"id"`
+}
+
+// ErrInvalidCursor is returned when a cursor string can't be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Encode returns the opaque string form of the cursor
+func (c NotificationCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeNotificationCursor parses a cursor produced by Encode
+func DecodeNotificationCursor(s string) (*NotificationCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c NotificationCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := time.Parse("2006-01-02 15:04:05", c.CreatedAt); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
 }
 
 // NotificationTypes constants
@@ -27,14 +85,32 @@ const (
 	TypeFollowRequest = "follow_request"
 	TypeGroupInvite   = "group_invite"
 	TypeGroupRequest  = "group_request"
+	TypeGroupActivity = "group_activity"
 	TypeEvent         = "event"
 	TypeMessage       = "message"
 	TypeComment       = "comment"
 	TypePost          = "post"
+	TypeReaction      = "reaction"
+	TypeMention       = "mention"
+	TypeRepost        = "repost"
+)
+
+// Target types constants (what a notification deep-links to)
+const (
+	TargetUser    = "user"
+	TargetGroup   = "group"
+	TargetEvent   = "event"
+	TargetPost    = "post"
+	TargetComment = "comment"
+	TargetMessage = "message"
 )
 
-// WebSocketNotification represents a notification sent via WebSocket
+// WebSocketNotification represents a notification sent via WebSocket.
+// EventID increases monotonically per user; a reconnecting client passes the
+// last one it saw as ?last_event_id= to have missed notifications replayed.
 type WebSocketNotification struct {
-	Type         string       `json:"type"`
-	Notification Notification `json:"notification"`
+	Type         string        `json:"type"` // "notification", "summary" or "resync"
+	EventID      int           `json:"event_id,omitempty"`
+	Notification *Notification `json:"notification,omitempty"`
+	Summary      *QuietSummary `json:"summary,omitempty"`
 }
@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// Digest frequencies
+const (
+	DigestOff    = "off"
+	DigestHourly = "hourly"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// Quiet-hours bypass scopes
+const (
+	BypassFromAnyone  = "anyone"
+	BypassFromMutuals = "mutuals" // Actor and recipient follow each other
+)
+
+// Preferences holds a user's notification delivery settings
+type Preferences struct {
+	UserID            int               `json:"user_id"`
+	DigestFrequency   string            `json:"digest_frequency"` // "off", "hourly", "daily", "weekly"
+	Locale            string            `json:"locale"`
+	PushEnabled       bool              `json:"push_enabled"`
+	QuietHoursEnabled bool              `json:"quiet_hours_enabled"`
+	QuietStart        string            `json:"quiet_start"` // Local "HH:MM"
+	QuietEnd          string            `json:"quiet_end"`   // Local "HH:MM", may be before QuietStart (overnight)
+	Timezone          string            `json:"timezone"`    // IANA name, e.g. "Europe/Paris"
+	DNDUntil          *time.Time        `json:"dnd_until"`   // Manual do-not-disturb expiry, nil when off
+	QuietBypass       []QuietBypassRule `json:"quiet_bypass"`
+	UnsubscribeToken  string            `json:"-"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+}
+
+// QuietBypassRule lets a notification type through quiet hours and DND
+type QuietBypassRule struct {
+	Type string `json:"type"`
+	From string `json:"from"` // "anyone" (default) or "mutuals"
+}
+
+// UpdatePreferencesRequest is the request body for updating preferences
+type UpdatePreferencesRequest struct {
+	DigestFrequency   *string            `json:"digest_frequency,omitempty"`
+	Locale            *string            `json:"locale,omitempty"`
+	PushEnabled       *bool              `json:"push_enabled,omitempty"`
+	QuietHoursEnabled *bool              `json:"quiet_hours_enabled,omitempty"`
+	QuietStart        *string            `json:"quiet_start,omitempty"`
+	QuietEnd          *string            `json:"quiet_end,omitempty"`
+	Timezone          *string            `json:"timezone,omitempty"`
+	DNDMinutes        *int               `json:"dnd_minutes,omitempty"` // Turn DND on for N minutes, 0 turns it off
+	QuietBypass       *[]QuietBypassRule `json:"quiet_bypass,omitempty"`
+}
+
+// QuietSummary is delivered once a user's quiet period ends
+type QuietSummary struct {
+	UserID        int            `json:"user_id"`
+	Count         int            `json:"count"`
+	LastEventID   int            `json:"last_event_id"`
+	Notifications []Notification `json:"notifications"` // Newest first, capped
+}
+
+// DigestRecipient is a user due for an email digest
+type DigestRecipient struct {
+	UserID    int
+	Username  string
+	Email     string
+	Frequency string
+}
+
+// PushSubscription is a browser Web Push subscription
+type PushSubscription struct {
+	ID            int        `json:"id"`
+	UserID        int        `json:"user_id"`
+	Endpoint      string     `json:"endpoint"`
+	P256dh        string     `json:"-"`
+	Auth          string     `json:"-"`
+	UserAgent     string     `json:"user_agent,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+}
+
+// SubscribePushRequest matches the browser's PushSubscription.toJSON()
+type SubscribePushRequest struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// UnsubscribePushRequest is the request body for removing a push subscription
+type UnsubscribePushRequest struct {
+	Endpoint string `json:"endpoint"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is a user-defined endpoint notified of selected notification types
+type Webhook struct {
+	ID                  int       `json:"id"`
+	UserID              int       `json:"user_id"`
+	URL                 string    `json:"url"`
+	Secret              string    `json:"secret,omitempty"` // Only returned when the webhook is created
+	Types               []string  `json:"types"`
+	Active              bool      `json:"active"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	DisabledReason      *string   `json:"disabled_reason,omitempty"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// CreateWebhookRequest is the request body for creating a webhook
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"` // Generated when empty
+	Types  []string `json:"types"`
+}
+
+// UpdateWebhookRequest is the request body for updating a webhook.
+// Setting active to true re-enables a webhook that was disabled after failures.
+type UpdateWebhookRequest struct {
+	URL          *string   `json:"url,omitempty"`
+	Types        *[]string `json:"types,omitempty"`
+	Active       *bool     `json:"active,omitempty"`
+	RotateSecret bool      `json:"rotate_secret,omitempty"`
+}
+
+// WebhookDelivery is one entry of a webhook's delivery log
+type WebhookDelivery struct {
+	ID             int        `json:"id"`
+	WebhookID      int        `json:"webhook_id"`
+	NotificationID *int       `json:"notification_id,omitempty"`
+	EventType      string     `json:"event_type"`
+	Payload        string     `json:"-"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode *int       `json:"last_status_code,omitempty"`
+	LastError      *string    `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
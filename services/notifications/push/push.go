@@ -0,0 +1,238 @@
+package push
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"social-network/services/notifications/db"
+	"social-network/services/notifications/models"
+	"social-network/services/notifications/templates"
+	"social-network/services/notifications/webhook"
+	"strconv"
+	"time"
+)
+
+// maxContentRunes keeps the rendered content well inside MaxPayloadSize
+const maxContentRunes = 1000
+
+// Config holds Web Push settings
+type Config struct {
+	Keys             *VAPIDKeys
+	Subject          string        // VAPID "sub" claim (mailto: or https: contact)
+	TTL              time.Duration // How long the push service keeps undelivered messages
+	EndpointOverride string        // If set, every push is sent here instead of the subscription endpoint
+}
+
+// LoadConfig reads the Web Push settings from the environment:
+//
+//	VAPID_PRIVATE_KEY       base64url raw P-256 private key (required, push is disabled without it)
+//	VAPID_SUBJECT           contact for the push service (default mailto:admin@social-network.local)
+//	PUSH_TTL                message TTL (default 24h)
+//	PUSH_ENDPOINT_OVERRIDE  send all pushes to this URL, e.g. a local stand-in push service
+//
+// Returns nil when VAPID_PRIVATE_KEY is unset.
+func LoadConfig() (*Config, error) {
+	privateKey := os.Getenv("VAPID_PRIVATE_KEY")
+	if privateKey == "" {
+		return nil, nil
+	}
+
+	keys, err := ParseVAPIDKeys(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		Keys:             keys,
+		Subject:          "mailto:admin@social-network.local",
+		TTL:              24 * time.Hour,
+		EndpointOverride: os.Getenv("PUSH_ENDPOINT_OVERRIDE"),
+	}
+
+	if v := os.Getenv("VAPID_SUBJECT"); v != "" {
+		cfg.Subject = v
+	}
+	if v := os.Getenv("PUSH_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.TTL = d
+		} else {
+			log.Printf("[Push] Ignoring invalid PUSH_TTL %q", v)
+		}
+	}
+
+	return cfg, nil
+}
+
+// Sender delivers notifications to users' push subscriptions
+type Sender struct {
+	database *sql.DB
+	config   *Config
+	client   *http.Client
+}
+
+// NewSender creates a new push sender. Subscription endpoints come from
+// browsers, so internal addresses are refused like webhook URLs; only an
+// operator-set EndpointOverride may point inside the network.
+func NewSender(database *sql.DB, config *Config) *Sender {
+	return &Sender{
+		database: database,
+		config:   config,
+		client:   webhook.NewClient(10*time.Second, config.EndpointOverride != ""),
+	}
+}
+
+// PublicKey returns the VAPID public key browsers subscribe with
+func (s *Sender) PublicKey() string {
+	return s.config.Keys.PublicKey
+}
+
+// payload is the JSON the service worker receives
+type payload struct {
+	ID         int     `json:"id,omitempty"`
+	Type       string  `json:"type"` // Notification type, or "summary" after quiet hours
+	Content    string  `json:"content"`
+	RelatedID  int     `json:"related_id,omitempty"`
+	TargetType *string `json:"target_type,omitempty"`
+	TargetID   *int    `json:"target_id,omitempty"`
+	Count      int     `json:"count,omitempty"` // Held notifications, for summaries
+	CreatedAt  string  `json:"created_at"`
+}
+
+// Notify pushes a notification to every subscription of its recipient
+func (s *Sender) Notify(notification models.Notification) {
+	s.notifyUser(notification.UserID, func(locale string) payload {
+		rendered := templates.RenderNotification(s.database, notification, locale)
+		return payload{
+			ID:         rendered.ID,
+			Type:       rendered.Type,
+			Content:    rendered.Content,
+			RelatedID:  rendered.RelatedID,
+			TargetType: rendered.TargetType,
+			TargetID:   rendered.TargetID,
+			CreatedAt:  rendered.CreatedAt.UTC().Format(time.RFC3339),
+		}
+	})
+}
+
+// NotifySummary pushes a single "N notifications during quiet hours" message
+func (s *Sender) NotifySummary(summary models.QuietSummary) {
+	s.notifyUser(summary.UserID, func(locale string) payload {
+		content, err := templates.Render(locale, "quiet_summary", map[string]interface{}{"count": summary.Count})
+		if err != nil {
+			log.Printf("[Push] Error rendering summary: %v", err)
+		}
+		return payload{
+			Type:      "summary",
+			Content:   content,
+			Count:     summary.Count,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		}
+	})
+}
+
+// notifyUser builds the payload in the user's locale and sends it to all of
+// their subscriptions, unless they turned push off in their preferences
+func (s *Sender) notifyUser(userID int, build func(locale string) payload) {
+	enabled, locale, err := db.GetPushSettings(s.database, userID)
+	if err != nil {
+		log.Printf("[Push] Error loading settings for user %d: %v", userID, err)
+		return
+	}
+	if !enabled {
+		return
+	}
+
+	subs, err := db.GetPushSubscriptions(s.database, userID)
+	if err != nil {
+		log.Printf("[Push] Error loading subscriptions for user %d: %v", userID, err)
+		return
+	}
+	if len(subs) == 0 {
+		return
+	}
+
+	p := build(locale)
+	if content := []rune(p.Content); len(content) > maxContentRunes {
+		p.Content = string(append(content[:maxContentRunes-1], '…'))
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		log.Printf("[Push] Error encoding payload for user %d: %v", userID, err)
+		return
+	}
+
+	for _, sub := range subs {
+		s.deliver(sub, data)
+	}
+}
+
+// deliver sends one encrypted message and prunes the subscription if the
+// push service says it no longer exists
+func (s *Sender) deliver(sub models.PushSubscription, data []byte) {
+	status, err := s.Send(sub, data)
+	if err != nil {
+		log.Printf("[Push] Failed to deliver to subscription %d: %v", sub.ID, err)
+		return
+	}
+
+	switch {
+	case status == http.StatusNotFound || status == http.StatusGone:
+		if err := db.PrunePushSubscription(s.database, sub.ID); err != nil {
+			log.Printf("[Push] Failed to prune subscription %d: %v", sub.ID, err)
+			return
+		}
+		log.Printf("[Push] Pruned expired subscription %d of user %d (status %d)", sub.ID, sub.UserID, status)
+
+	case status >= 200 && status < 300:
+		if err := db.MarkPushSuccess(s.database, sub.ID, time.Now()); err != nil {
+			log.Printf("[Push] Failed to record delivery for subscription %d: %v", sub.ID, err)
+		}
+
+	default:
+		log.Printf("[Push] Push service rejected message for subscription %d (status %d)", sub.ID, status)
+	}
+}
+
+// Send encrypts data for sub and POSTs it to the push service.
+// Returns the push service's HTTP status.
+func (s *Sender) Send(sub models.PushSubscription, data []byte) (int, error) {
+	body, err := Encrypt(data, sub.P256dh, sub.Auth)
+	if err != nil {
+		return 0, err
+	}
+
+	endpoint := sub.Endpoint
+	if s.config.EndpointOverride != "" {
+		endpoint = s.config.EndpointOverride
+	}
+
+	authorization, err := s.config.Keys.authorization(endpoint, s.config.Subject, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(s.config.TTL.Seconds())))
+	req.Header.Set("Urgency", "normal")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,228 @@
+package push
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// recordSize is the aes128gcm record size; the whole payload must fit in one record
+const recordSize = 4096
+
+// MaxPayloadSize is the largest plaintext that fits in one record
+// (recordSize - 16 byte GCM tag - 1 byte padding delimiter)
+const MaxPayloadSize = recordSize - 16 - 1
+
+// ============================================
+// VAPID (RFC 8292)
+// ============================================
+
+// VAPIDKeys is the application server key pair used to sign push requests
+type VAPIDKeys struct {
+	private   *ecdsa.PrivateKey
+	PublicKey string // Uncompressed P-256 point, base64url (the browser's applicationServerKey)
+}
+
+// ParseVAPIDKeys loads a key pair from a base64url encoded raw P-256 private key
+func ParseVAPIDKeys(privateKey string) (*VAPIDKeys, error) {
+	d, err := DecodeKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+
+	key, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+
+	pub := key.PublicKey().Bytes()
+	private := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(pub[1:33]),
+			Y:     new(big.Int).SetBytes(pub[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}
+
+	return &VAPIDKeys{
+		private:   private,
+		PublicKey: base64.RawURLEncoding.EncodeToString(pub),
+	}, nil
+}
+
+// GenerateVAPIDKeys creates a new key pair and returns the encoded private key
+func GenerateVAPIDKeys() (*VAPIDKeys, string, error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, "", err
+	}
+
+	privateKey := base64.RawURLEncoding.EncodeToString(key.Bytes())
+	keys, err := ParseVAPIDKeys(privateKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return keys, privateKey, nil
+}
+
+// authorization builds the "vapid t=..., k=..." Authorization header value
+// for a push service origin
+func (k *VAPIDKeys) authorization(endpoint, subject string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": now.Add(12 * time.Hour).Unix(),
+		"sub": subject,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	r, s, err := ecdsa.Sign(rand.Reader, k.private, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	// JWS ES256 signatures are the fixed-width concatenation r || s
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	token := signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+	return "vapid t=" + token + ", k=" + k.PublicKey, nil
+}
+
+// ============================================
+// PAYLOAD ENCRYPTION (RFC 8291 / RFC 8188)
+// ============================================
+
+// Encrypt encrypts payload for a subscription's p256dh and auth keys using
+// the aes128gcm content coding. The result is the complete request body.
+func Encrypt(payload []byte, p256dh, auth string) ([]byte, error) {
+	if len(payload) > MaxPayloadSize {
+		return nil, fmt.Errorf("payload too large (%d bytes)", len(payload))
+	}
+
+	uaPublicBytes, err := DecodeKey(p256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh: %w", err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh: %w", err)
+	}
+
+	authSecret, err := DecodeKey(auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth secret: %w", err)
+	}
+
+	// Ephemeral application server key, one per message
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublicBytes := asPrivate.PublicKey().Bytes()
+
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	// IKM = HKDF(auth_secret, ecdh_secret, "WebPush: info" || 0x00 || ua_public || as_public)
+	keyInfo := "WebPush: info\x00" + string(uaPublicBytes) + string(asPublicBytes)
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Single (last) record: payload followed by the 0x02 padding delimiter
+	plaintext := append(append([]byte{}, payload...), 0x02)
+
+	// Header: salt (16) | rs (4) | idlen (1) | keyid (the as_public key)
+	var body bytes.Buffer
+	body.Write(salt)
+	binary.Write(&body, binary.BigEndian, uint32(recordSize))
+	body.WriteByte(byte(len(asPublicBytes)))
+	body.Write(asPublicBytes)
+	body.Write(gcm.Seal(nil, nonce, plaintext, nil))
+
+	return body.Bytes(), nil
+}
+
+// ValidateKeys checks that a subscription's keys can be encrypted to
+func ValidateKeys(p256dh, auth string) error {
+	key, err := DecodeKey(p256dh)
+	if err != nil {
+		return err
+	}
+	if _, err := ecdh.P256().NewPublicKey(key); err != nil {
+		return err
+	}
+
+	secret, err := DecodeKey(auth)
+	if err != nil {
+		return err
+	}
+	if len(secret) != 16 {
+		return fmt.Errorf("auth secret must be 16 bytes, got %d", len(secret))
+	}
+
+	return nil
+}
+
+// DecodeKey decodes a base64url key, with or without padding
+func DecodeKey(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,210 @@
+package quiet
+
+import (
+	"database/sql"
+	"log"
+	"social-network/services/notifications/db"
+	"social-network/services/notifications/models"
+	"time"
+)
+
+// maxSummaryItems caps how many notifications a summary lists
+const maxSummaryItems = 10
+
+// ============================================
+// SCHEDULE
+// ============================================
+
+// IsActive reports whether live delivery is paused for prefs at now,
+// either by manual DND or by the daily quiet window
+func IsActive(prefs *models.Preferences, now time.Time) bool {
+	if prefs == nil {
+		return false
+	}
+	if prefs.DNDUntil != nil && now.Before(*prefs.DNDUntil) {
+		return true
+	}
+	if !prefs.QuietHoursEnabled {
+		return false
+	}
+
+	start, okStart := ParseClock(prefs.QuietStart)
+	end, okEnd := ParseClock(prefs.QuietEnd)
+	if !okStart || !okEnd || start == end {
+		return false
+	}
+
+	loc, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+
+	if start < end {
+		return minute >= start && minute < end
+	}
+	// Window wraps past midnight, e.g. 22:00-07:00
+	return minute >= start || minute < end
+}
+
+// ParseClock parses "HH:MM" into minutes since midnight
+func ParseClock(s string) (int, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// ValidTimezone reports whether tz is a known IANA timezone
+func ValidTimezone(tz string) bool {
+	_, err := time.LoadLocation(tz)
+	return err == nil && tz != "" && tz != "Local"
+}
+
+// ============================================
+// DELIVERY GATE
+// ============================================
+
+// Gate decides whether a new notification is delivered live
+type Gate struct {
+	database *sql.DB
+}
+
+// NewGate creates a new quiet-hours gate
+func NewGate(database *sql.DB) *Gate {
+	return &Gate{database: database}
+}
+
+// Hold reports whether live delivery of notification should be held back.
+// Held notifications are stored as usual and counted towards the summary
+// sent when the recipient's quiet period ends.
+func (g *Gate) Hold(notification *models.Notification, now time.Time) bool {
+	prefs, err := db.FindPreferences(g.database, notification.UserID)
+	if err != nil {
+		log.Printf("[Quiet] Error loading preferences for user %d: %v", notification.UserID, err)
+		return false // Fail open, a late-night ping beats a lost one
+	}
+	if !g.Holds(prefs, notification, now) {
+		return false
+	}
+
+	if err := db.RecordQuietSuppressed(g.database, notification.UserID, notification.ID); err != nil {
+		log.Printf("[Quiet] Error recording held notification %d: %v", notification.ID, err)
+	}
+	return true
+}
+
+// Holds reports whether notification is held back for prefs at now, like
+// Hold but without counting it towards the summary
+func (g *Gate) Holds(prefs *models.Preferences, notification *models.Notification, now time.Time) bool {
+	return IsActive(prefs, now) && !g.bypasses(prefs, notification)
+}
+
+// bypasses reports whether one of the user's bypass rules lets notification through
+func (g *Gate) bypasses(prefs *models.Preferences, notification *models.Notification) bool {
+	for _, rule := range prefs.QuietBypass {
+		if rule.Type != notification.Type {
+			continue
+		}
+		if rule.From != models.BypassFromMutuals {
+			return true
+		}
+		if notification.ActorID == nil {
+			continue
+		}
+
+		mutual, err := db.IsMutualFollow(g.database, notification.UserID, *notification.ActorID)
+		if err != nil {
+			log.Printf("[Quiet] Error checking follows for user %d: %v", notification.UserID, err)
+			continue
+		}
+		if mutual {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================
+// SUMMARIES
+// ============================================
+
+// Summarizer sends a summary to users whose quiet period has ended
+type Summarizer struct {
+	database *sql.DB
+	interval time.Duration
+	deliver  func(models.QuietSummary)
+}
+
+// NewSummarizer creates a summarizer that checks every interval and hands
+// each summary to deliver (which fans it out to the live channels)
+func NewSummarizer(database *sql.DB, interval time.Duration, deliver func(models.QuietSummary)) *Summarizer {
+	return &Summarizer{
+		database: database,
+		interval: interval,
+		deliver:  deliver,
+	}
+}
+
+// Run checks for ended quiet periods every interval (blocks forever)
+func (s *Summarizer) Run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunOnce(time.Now())
+		<-ticker.C
+	}
+}
+
+// RunOnce sends every summary that is due at now
+func (s *Summarizer) RunOnce(now time.Time) {
+	userIDs, err := db.GetQuietPendingUsers(s.database)
+	if err != nil {
+		log.Printf("[Quiet] Error finding pending summaries: %v", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		prefs, err := db.FindPreferences(s.database, userID)
+		if err != nil {
+			log.Printf("[Quiet] Error loading preferences for user %d: %v", userID, err)
+			continue
+		}
+		if IsActive(prefs, now) {
+			continue // Still quiet
+		}
+
+		if err := s.summarize(userID); err != nil {
+			log.Printf("[Quiet] Error sending summary to user %d: %v", userID, err)
+		}
+	}
+}
+
+// summarize claims a user's held notifications and delivers the summary
+func (s *Summarizer) summarize(userID int) error {
+	fromID, count, err := db.ClaimQuietSummary(s.database, userID)
+	if err != nil || count == 0 {
+		return err
+	}
+
+	notifications, err := db.GetNotificationsFromID(s.database, userID, fromID, maxSummaryItems)
+	if err != nil {
+		return err
+	}
+
+	summary := models.QuietSummary{
+		UserID:        userID,
+		Count:         count,
+		Notifications: notifications,
+	}
+	if len(notifications) > 0 {
+		summary.LastEventID = notifications[0].ID
+	}
+
+	s.deliver(summary)
+	log.Printf("[Quiet] Sent summary of %d held notifications to user %d", count, userID)
+	return nil
+}
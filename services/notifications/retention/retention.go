@@ -0,0 +1,266 @@
+package retention
+
+import (
+	"database/sql"
+	"expvar"
+	"fmt"
+	"log"
+	"os"
+	"social-network/services/notifications/db"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metrics are published through expvar (GET /debug/vars on the internal
+// METRICS_ADDR listener) under "notification_retention"
+var (
+	metrics         = expvar.NewMap("notification_retention")
+	prunedReadAge   = new(expvar.Int) // Read notifications removed by age rules
+	prunedUserCap   = new(expvar.Int) // Notifications removed by the per-user cap
+	prunedWebhooks  = new(expvar.Int) // Finished webhook deliveries removed from the log
+	runs            = new(expvar.Int)
+	runErrors       = new(expvar.Int)
+	lastRunUnix     = new(expvar.Int)
+	lastRunDuration = new(expvar.Float) // Seconds
+	lastRunPruned   = new(expvar.Int)
+)
+
+func init() {
+	metrics.Set("pruned_read_age_total", prunedReadAge)
+	metrics.Set("pruned_user_cap_total", prunedUserCap)
+	metrics.Set("pruned_webhook_deliveries_total", prunedWebhooks)
+	metrics.Set("runs_total", runs)
+	metrics.Set("errors_total", runErrors)
+	metrics.Set("last_run_unix", lastRunUnix)
+	metrics.Set("last_run_duration_seconds", lastRunDuration)
+	metrics.Set("last_run_pruned", lastRunPruned)
+}
+
+// Config holds the retention rules
+type Config struct {
+	Interval   time.Duration            // How often the job runs
+	ReadMaxAge time.Duration            // Delete read notifications older than this (0 = never)
+	TypeMaxAge map[string]time.Duration // Per-type override of ReadMaxAge (0 = never)
+	MaxPerUser int                      // Keep at most this many notifications per user (0 = no cap)
+	WebhookLog time.Duration            // Delete finished webhook deliveries older than this (0 = never)
+	BatchSize  int                      // Rows deleted per transaction
+	BatchPause time.Duration            // Pause between batches so other writers get the lock
+}
+
+// LoadConfig reads the retention rules from the environment:
+//
+//	RETENTION_INTERVAL       how often to run (default 1h)
+//	RETENTION_READ_DAYS      delete read notifications older than N days (default 30, 0 = never)
+//	RETENTION_TYPE_DAYS      per-type overrides, e.g. "follow_request=180,group_invite=0"
+//	RETENTION_MAX_PER_USER   keep the newest N notifications per user (default 1000, 0 = no cap)
+//	RETENTION_WEBHOOK_DAYS   keep the webhook delivery log for N days (default 14, 0 = forever)
+//	RETENTION_BATCH_SIZE     rows per delete transaction (default 500)
+//	RETENTION_BATCH_PAUSE    pause between batches (default 50ms)
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		Interval:   time.Hour,
+		ReadMaxAge: 30 * 24 * time.Hour,
+		TypeMaxAge: map[string]time.Duration{},
+		MaxPerUser: 1000,
+		WebhookLog: 14 * 24 * time.Hour,
+		BatchSize:  500,
+		BatchPause: 50 * time.Millisecond,
+	}
+
+	if v := os.Getenv("RETENTION_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("invalid RETENTION_INTERVAL %q", v)
+		}
+		cfg.Interval = d
+	}
+	if v := os.Getenv("RETENTION_READ_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 {
+			return cfg, fmt.Errorf("invalid RETENTION_READ_DAYS %q", v)
+		}
+		cfg.ReadMaxAge = time.Duration(days) * 24 * time.Hour
+	}
+	if v := os.Getenv("RETENTION_TYPE_DAYS"); v != "" {
+		for _, rule := range strings.Split(v, ",") {
+			notifType, daysStr, ok := strings.Cut(strings.TrimSpace(rule), "=")
+			days, err := strconv.Atoi(daysStr)
+			if !ok || notifType == "" || err != nil || days < 0 {
+				return cfg, fmt.Errorf("invalid RETENTION_TYPE_DAYS rule %q (use type=days)", rule)
+			}
+			cfg.TypeMaxAge[notifType] = time.Duration(days) * 24 * time.Hour
+		}
+	}
+	if v := os.Getenv("RETENTION_MAX_PER_USER"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid RETENTION_MAX_PER_USER %q", v)
+		}
+		cfg.MaxPerUser = n
+	}
+	if v := os.Getenv("RETENTION_WEBHOOK_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 {
+			return cfg, fmt.Errorf("invalid RETENTION_WEBHOOK_DAYS %q", v)
+		}
+		cfg.WebhookLog = time.Duration(days) * 24 * time.Hour
+	}
+	if v := os.Getenv("RETENTION_BATCH_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("invalid RETENTION_BATCH_SIZE %q", v)
+		}
+		cfg.BatchSize = n
+	}
+	if v := os.Getenv("RETENTION_BATCH_PAUSE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("invalid RETENTION_BATCH_PAUSE %q", v)
+		}
+		cfg.BatchPause = d
+	}
+
+	return cfg, nil
+}
+
+// Job periodically deletes notifications according to the retention rules
+type Job struct {
+	database *sql.DB
+	config   Config
+}
+
+// NewJob creates a new retention job
+func NewJob(database *sql.DB, config Config) *Job {
+	return &Job{
+		database: database,
+		config:   config,
+	}
+}
+
+// Run applies the rules every Interval (blocks forever)
+func (j *Job) Run() {
+	log.Printf("[Retention] Job started (interval %v, read max age %v, max per user %d)",
+		j.config.Interval, j.config.ReadMaxAge, j.config.MaxPerUser)
+
+	ticker := time.NewTicker(j.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		j.RunOnce(time.Now())
+		<-ticker.C
+	}
+}
+
+// RunOnce applies every rule once and returns the number of rows deleted
+func (j *Job) RunOnce(now time.Time) int {
+	start := time.Now()
+	runs.Add(1)
+
+	byAge, err := j.pruneReadByAge(now)
+	if err != nil {
+		runErrors.Add(1)
+		log.Printf("[Retention] Error pruning read notifications: %v", err)
+	}
+	prunedReadAge.Add(int64(byAge))
+
+	byCap, err := j.pruneOverCap()
+	if err != nil {
+		runErrors.Add(1)
+		log.Printf("[Retention] Error applying per-user cap: %v", err)
+	}
+	prunedUserCap.Add(int64(byCap))
+
+	// The delivery log isn't counted in the notification totals below
+	if j.config.WebhookLog > 0 {
+		n, err := j.drain(func() (int, error) {
+			return db.PruneWebhookDeliveries(j.database, now.Add(-j.config.WebhookLog), j.config.BatchSize)
+		})
+		if err != nil {
+			runErrors.Add(1)
+			log.Printf("[Retention] Error pruning webhook deliveries: %v", err)
+		}
+		prunedWebhooks.Add(int64(n))
+	}
+
+	total := byAge + byCap
+	lastRunUnix.Set(now.Unix())
+	lastRunDuration.Set(time.Since(start).Seconds())
+	lastRunPruned.Set(int64(total))
+
+	if total > 0 {
+		log.Printf("[Retention] Pruned %d notifications (%d by age, %d by per-user cap) in %v",
+			total, byAge, byCap, time.Since(start).Round(time.Millisecond))
+	}
+
+	return total
+}
+
+// pruneReadByAge deletes read notifications past their type's max age
+func (j *Job) pruneReadByAge(now time.Time) (int, error) {
+	total := 0
+	overridden := make([]string, 0, len(j.config.TypeMaxAge))
+
+	for notifType, maxAge := range j.config.TypeMaxAge {
+		overridden = append(overridden, notifType)
+		if maxAge == 0 {
+			continue // Kept forever
+		}
+
+		n, err := j.drain(func() (int, error) {
+			return db.PruneReadBefore(j.database, now.Add(-maxAge), []string{notifType}, nil, j.config.BatchSize)
+		})
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+
+	if j.config.ReadMaxAge == 0 {
+		return total, nil
+	}
+
+	n, err := j.drain(func() (int, error) {
+		return db.PruneReadBefore(j.database, now.Add(-j.config.ReadMaxAge), nil, overridden, j.config.BatchSize)
+	})
+	return total + n, err
+}
+
+// pruneOverCap trims users above MaxPerUser down to their newest notifications
+func (j *Job) pruneOverCap() (int, error) {
+	if j.config.MaxPerUser == 0 {
+		return 0, nil
+	}
+
+	userIDs, err := db.GetUsersOverCap(j.database, j.config.MaxPerUser)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, userID := range userIDs {
+		n, err := j.drain(func() (int, error) {
+			return db.PruneUserOverCap(j.database, userID, j.config.MaxPerUser, j.config.BatchSize)
+		})
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// drain runs deleteBatch until it deletes less than a full batch, pausing
+// between batches so the write lock is never held for long
+func (j *Job) drain(deleteBatch func() (int, error)) (int, error) {
+	total := 0
+	for {
+		n, err := deleteBatch()
+		total += n
+		if err != nil || n < j.config.BatchSize {
+			return total, err
+		}
+		time.Sleep(j.config.BatchPause)
+	}
+}
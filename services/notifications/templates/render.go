@@ -0,0 +1,92 @@
+package templates
+
+import (
+	"database/sql"
+	"log"
+	"social-network/services/notifications/db"
+	"social-network/services/notifications/models"
+)
+
+// RenderNotifications re-renders notification content in the given locale.
+// Actor and group names are looked up now rather than taken from the params
+// stored at creation time, so renames show up in old notifications.
+// Legacy notifications without params keep their stored content.
+func RenderNotifications(database *sql.DB, notifications []models.Notification, locale string) {
+	var actorIDs, groupIDs []int
+	for _, n := range notifications {
+		if n.Params == nil {
+			continue
+		}
+		if n.ActorID != nil {
+			actorIDs = append(actorIDs, *n.ActorID)
+		}
+		if groupID := notificationGroupID(&n); groupID > 0 {
+			groupIDs = append(groupIDs, groupID)
+		}
+	}
+
+	actorNames, err := db.GetUsernames(database, actorIDs)
+	if err != nil {
+		log.Printf("Error loading actor names for rendering: %v", err)
+		actorNames = map[int]string{}
+	}
+	groupNames, err := db.GetGroupNames(database, groupIDs)
+	if err != nil {
+		log.Printf("Error loading group names for rendering: %v", err)
+		groupNames = map[int]string{}
+	}
+
+	for i := range notifications {
+		n := &notifications[i]
+		if n.Params == nil {
+			continue
+		}
+
+		// Copy params so the current names don't leak into the stored blob
+		params := make(map[string]interface{}, len(n.Params)+2)
+		for k, v := range n.Params {
+			params[k] = v
+		}
+		if n.ActorID != nil {
+			if name, ok := actorNames[*n.ActorID]; ok {
+				params["actor_name"] = name
+			}
+		}
+		if name, ok := groupNames[notificationGroupID(n)]; ok {
+			params["group_name"] = name
+		}
+
+		content, err := Render(locale, Key(n.Type, params), params)
+		if err != nil {
+			log.Printf("Error rendering notification %d: %v", n.ID, err)
+			continue
+		}
+		n.Content = content
+	}
+}
+
+// RenderNotification renders a single notification (see RenderNotifications)
+func RenderNotification(database *sql.DB, notification models.Notification, locale string) models.Notification {
+	batch := []models.Notification{notification}
+	RenderNotifications(database, batch, locale)
+	return batch[0]
+}
+
+// notificationGroupID returns the group a notification refers to, if any
+func notificationGroupID(n *models.Notification) int {
+	if n.TargetType != nil && *n.TargetType == models.TargetGroup && n.TargetID != nil {
+		return *n.TargetID
+	}
+	return intParam(n.Params, "group_id")
+}
+
+// intParam reads an integer param (JSON numbers decode as float64)
+func intParam(params map[string]interface{}, key string) int {
+	switch v := params[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
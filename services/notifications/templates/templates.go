@@ -0,0 +1,182 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// DefaultLocale is used when the client asks for nothing we support
+const DefaultLocale = "en"
+
+// catalogs maps locale -> template key -> template text.
+// Keys are the notification type, or "type.verb" when params carry a verb.
+var catalogs = map[string]map[string]string{
+	"en": {
+		"follow_request":                     "{{.actor_name}} sent you a follow request",
+		"follow":                             "{{.actor_name}} started following you",
+		"follow.accepted":                    "{{.actor_name}} accepted your follow request",
+		"group_invite":                       "{{.actor_name}} invited you to join {{.group_name}}",
+		"group_request":                      "{{.actor_name}} wants to join your group {{.group_name}}",
+		"group_activity.request_accepted":    "Your request to join {{.group_name}} was accepted",
+		"group_activity.request_rejected":    "Your request to join {{.group_name}} was declined",
+		"group_activity.member_joined":       "{{.actor_name}} joined your group {{.group_name}}",
+		"group_activity.invitation_accepted": "{{.actor_name}} accepted your invitation to {{.group_name}}",
+		"group_activity.invitation_declined": "{{.actor_name}} declined your invitation to {{.group_name}}",
+		"post":                               "{{.actor_name}} shared a new post",
+		"post.group_post":                    "{{.actor_name}} posted in {{.group_name}}",
+		"event.created":                      "{{.actor_name}} created event {{.event_title}} in {{.group_name}}",
+		"event.response_going":               "{{.actor_name}} is going to {{.event_title}}",
+		"event.response_not_going":           "{{.actor_name}} is not going to {{.event_title}}",
+		"event.response_interested":          "{{.actor_name}} is interested in {{.event_title}}",
+		"comment":                            "{{.actor_name}} commented on your post: '{{.preview}}'",
+		"comment.reply":                      "{{.actor_name}} replied to your comment: '{{.preview}}'",
+		"reaction":                           "{{.actor_name}} reacted {{.emoji}} to your post",
+		"reaction.aggregated":                "{{.actor_name}} and {{.others}} other(s) reacted to your post",
+		"reaction.comment":                   "{{.actor_name}} reacted {{.emoji}} to your comment",
+		"reaction.comment_aggregated":        "{{.actor_name}} and {{.others}} other(s) reacted to your comment",
+		"mention":                            "{{.actor_name}} mentioned you in a post: '{{.preview}}'",
+		"mention.comment":                    "{{.actor_name}} mentioned you in a comment: '{{.preview}}'",
+		"repost":                             "{{.actor_name}} reposted your post",
+		"repost.quote":                       "{{.actor_name}} quoted your post",
+		"message":                            "New message from {{.actor_name}}",
+		"message.group_message":              "{{.actor_name}} sent a message in {{.group_name}}",
+		"quiet_summary":                      "You received {{.count}} notification(s) during quiet hours",
+	},
+	"fr": {
+		"follow_request":                     "{{.actor_name}} vous a envoyé une demande d'abonnement",
+		"follow":                             "{{.actor_name}} s'est abonné(e) à vous",
+		"follow.accepted":                    "{{.actor_name}} a accepté votre demande d'abonnement",
+		"group_invite":                       "{{.actor_name}} vous a invité(e) à rejoindre {{.group_name}}",
+		"group_request":                      "{{.actor_name}} souhaite rejoindre votre groupe {{.group_name}}",
+		"group_activity.request_accepted":    "Votre demande pour rejoindre {{.group_name}} a été acceptée",
+		"group_activity.request_rejected":    "Votre demande pour rejoindre {{.group_name}} a été refusée",
+		"group_activity.member_joined":       "{{.actor_name}} a rejoint votre groupe {{.group_name}}",
+		"group_activity.invitation_accepted": "{{.actor_name}} a accepté votre invitation à {{.group_name}}",
+		"group_activity.invitation_declined": "{{.actor_name}} a refusé votre invitation à {{.group_name}}",
+		"post":                               "{{.actor_name}} a publié un nouveau message",
+		"post.group_post":                    "{{.actor_name}} a publié dans {{.group_name}}",
+		"event.created":                      "{{.actor_name}} a créé l'événement {{.event_title}} dans {{.group_name}}",
+		"event.response_going":               "{{.actor_name}} participera à {{.event_title}}",
+		"event.response_not_going":           "{{.actor_name}} ne participera pas à {{.event_title}}",
+		"event.response_interested":          "{{.actor_name}} est intéressé(e) par {{.event_title}}",
+		"comment":                            "{{.actor_name}} a commenté votre publication : « {{.preview}} »",
+		"comment.reply":                      "{{.actor_name}} a répondu à votre commentaire : « {{.preview}} »",
+		"reaction":                           "{{.actor_name}} a réagi {{.emoji}} à votre publication",
+		"reaction.aggregated":                "{{.actor_name}} et {{.others}} autre(s) personne(s) ont réagi à votre publication",
+		"reaction.comment":                   "{{.actor_name}} a réagi {{.emoji}} à votre commentaire",
+		"reaction.comment_aggregated":        "{{.actor_name}} et {{.others}} autre(s) personne(s) ont réagi à votre commentaire",
+		"mention":                            "{{.actor_name}} vous a mentionné(e) dans une publication : « {{.preview}} »",
+		"mention.comment":                    "{{.actor_name}} vous a mentionné(e) dans un commentaire : « {{.preview}} »",
+		"repost":                             "{{.actor_name}} a republié votre publication",
+		"repost.quote":                       "{{.actor_name}} a cité votre publication",
+		"message":                            "Nouveau message de {{.actor_name}}",
+		"message.group_message":              "{{.actor_name}} a envoyé un message dans {{.group_name}}",
+		"quiet_summary":                      "Vous avez reçu {{.count}} notification(s) pendant vos heures calmes",
+	},
+}
+
+// parsed holds the compiled templates, built once at startup
+var parsed = make(map[string]map[string]*template.Template)
+
+func init() {
+	for locale, catalog := range catalogs {
+		parsed[locale] = make(map[string]*template.Template)
+		for key, text := range catalog {
+			// missingkey=error makes Render fail instead of printing "<no value>"
+			parsed[locale][key] = template.Must(
+				template.New(locale + ":" + key).Option("missingkey=error").Parse(text),
+			)
+		}
+	}
+}
+
+// Key returns the catalog key for a notification type and its params
+func Key(notifType string, params map[string]interface{}) string {
+	if verb, ok := params["verb"].(string); ok && verb != "" {
+		return notifType + "." + verb
+	}
+	return notifType
+}
+
+// Render renders the template for key in the given locale.
+// Falls back to DefaultLocale when the locale has no translation for key.
+func Render(locale, key string, params map[string]interface{}) (string, error) {
+	tmpl, ok := parsed[locale][key]
+	if !ok {
+		tmpl, ok = parsed[DefaultLocale][key]
+		if !ok {
+			return "", fmt.Errorf("no template for %q", key)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, params); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// IsSupported reports whether a locale has a catalog
+func IsSupported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// Locale picks the locale for a request.
+// Priority: ?locale= query parameter, then the Accept-Language header.
+func Locale(r *http.Request) string {
+	if locale := normalize(r.URL.Query().Get("locale")); IsSupported(locale) {
+		return locale
+	}
+	return FromAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
+// FromAcceptLanguage picks the highest-weighted supported language
+// from an Accept-Language header value (e.g. "fr-CA,fr;q=0.9,en;q=0.8")
+func FromAcceptLanguage(header string) string {
+	type candidate struct {
+		locale string
+		q      float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if locale := normalize(fields[0]); IsSupported(locale) && q > 0 {
+			candidates = append(candidates, candidate{locale: locale, q: q})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return DefaultLocale
+	}
+
+	// Stable sort keeps header order for equal weights
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].locale
+}
+
+// normalize reduces a language tag to its primary subtag ("fr-CA" -> "fr")
+func normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
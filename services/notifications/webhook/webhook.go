@@ -0,0 +1,458 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"social-network/services/notifications/db"
+	"social-network/services/notifications/models"
+	"social-network/services/notifications/templates"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Request headers sent with every delivery
+const (
+	HeaderSignature = "X-Webhook-Signature" // "t=<unix>,v1=<hex HMAC-SHA256>"
+	HeaderID        = "X-Webhook-Id"        // Delivery ID, stable across retries
+	HeaderEvent     = "X-Webhook-Event"     // Notification type
+)
+
+const (
+	batchSize   = 50               // Due deliveries fetched per poll
+	concurrency = 4                // Deliveries in flight at once
+	firstRetry  = 30 * time.Second // Backoff before the first retry, x4 per attempt
+	maxBackoff  = 6 * time.Hour
+	maxErrorLen = 500 // Bytes of response body kept in the delivery log
+)
+
+// ErrPrivateAddress is returned when a webhook URL resolves to an internal address
+var ErrPrivateAddress = errors.New("webhook URL points to a private address")
+
+// Config holds webhook delivery settings
+type Config struct {
+	PollInterval time.Duration // How often due retries are picked up
+	Timeout      time.Duration // Per-request timeout
+	MaxAttempts  int           // Attempts per delivery before it is marked failed
+	DisableAfter int           // Consecutive failed attempts before the webhook is disabled
+	AllowPrivate bool          // Allow loopback and private addresses, e.g. a local stand-in
+}
+
+// LoadConfig reads the webhook settings from the environment:
+//
+//	WEBHOOK_POLL_INTERVAL   how often to look for due retries (default 5s)
+//	WEBHOOK_TIMEOUT         per-request timeout (default 10s)
+//	WEBHOOK_MAX_ATTEMPTS    attempts per delivery (default 6, about 3h of retries)
+//	WEBHOOK_DISABLE_AFTER   consecutive failed attempts before disabling (default 15)
+//	WEBHOOK_ALLOW_PRIVATE   "true" to allow loopback/private URLs (development only)
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		PollInterval: 5 * time.Second,
+		Timeout:      10 * time.Second,
+		MaxAttempts:  6,
+		DisableAfter: 15,
+	}
+
+	if v := os.Getenv("WEBHOOK_POLL_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("invalid WEBHOOK_POLL_INTERVAL %q", v)
+		}
+		cfg.PollInterval = d
+	}
+	if v := os.Getenv("WEBHOOK_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("invalid WEBHOOK_TIMEOUT %q", v)
+		}
+		cfg.Timeout = d
+	}
+	if v := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS %q", v)
+		}
+		cfg.MaxAttempts = n
+	}
+	if v := os.Getenv("WEBHOOK_DISABLE_AFTER"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("invalid WEBHOOK_DISABLE_AFTER %q", v)
+		}
+		cfg.DisableAfter = n
+	}
+	if v := os.Getenv("WEBHOOK_ALLOW_PRIVATE"); v != "" {
+		allow, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid WEBHOOK_ALLOW_PRIVATE %q", v)
+		}
+		cfg.AllowPrivate = allow
+	}
+
+	return cfg, nil
+}
+
+// ============================================
+// PAYLOAD AND SIGNATURE
+// ============================================
+
+// Payload is the JSON body POSTed to webhooks
+type Payload struct {
+	Event        string       `json:"event"` // Notification type
+	CreatedAt    string       `json:"created_at"`
+	Notification Notification `json:"notification"`
+}
+
+// Notification is the notification as rendered in the recipient's locale
+type Notification struct {
+	ID         int     `json:"id"`
+	Type       string  `json:"type"`
+	Content    string  `json:"content"`
+	RelatedID  int     `json:"related_id,omitempty"`
+	ActorID    *int    `json:"actor_id,omitempty"`
+	TargetType *string `json:"target_type,omitempty"`
+	TargetID   *int    `json:"target_id,omitempty"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+// Sign returns the signature header value for body sent at timestamp.
+// Receivers recompute HMAC-SHA256(secret, "<t>.<body>") and compare.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret generates a random signing secret
+func NewSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// ValidateURL checks that rawURL is an absolute http(s) URL and, unless
+// allowPrivate is set, that it doesn't name an internal host. Hostnames are
+// checked again after DNS resolution when delivering.
+func ValidateURL(rawURL string, allowPrivate bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("URL must be an absolute http or https URL")
+	}
+	if u.User != nil {
+		return errors.New("URL must not contain credentials")
+	}
+	if allowPrivate {
+		return nil
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".internal") {
+		return ErrPrivateAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && isPrivate(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// isPrivate reports whether ip is loopback, private, link-local or unspecified
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast()
+}
+
+// ============================================
+// WORKER
+// ============================================
+
+// Worker queues notifications for their recipients' webhooks and delivers
+// them, retrying failed attempts with exponential backoff
+type Worker struct {
+	database *sql.DB
+	config   Config
+	client   *http.Client
+	wake     chan struct{}
+}
+
+// NewWorker creates a new webhook worker
+func NewWorker(database *sql.DB, config Config) *Worker {
+	return &Worker{
+		database: database,
+		config:   config,
+		client:   NewClient(config.Timeout, config.AllowPrivate),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// NewClient returns an HTTP client for user-supplied URLs (webhooks, push
+// endpoints). Unless allowPrivate is set it refuses to connect to internal
+// addresses, and it never follows redirects: a 3xx is returned as is.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		// Checked on the resolved address so DNS can't point us inside the network
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivate(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: timeout,
+		MaxIdleConnsPerHost: 2,
+		Proxy:               nil, // A proxy would bypass the address check
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		// Redirects are not followed, a 3xx counts as a failed attempt
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Enqueue queues notification for every active webhook of its recipient
+// subscribed to its type. The payload is rendered now, in the recipient's
+// locale, so retries send exactly the same body.
+func (w *Worker) Enqueue(notification models.Notification) {
+	hooks, err := db.GetActiveWebhooksForType(w.database, notification.UserID, notification.Type)
+	if err != nil {
+		log.Printf("[Webhook] Error loading webhooks for user %d: %v", notification.UserID, err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	locale := templates.DefaultLocale
+	if prefs, err := db.FindPreferences(w.database, notification.UserID); err != nil {
+		log.Printf("[Webhook] Error loading preferences for user %d: %v", notification.UserID, err)
+	} else if prefs != nil {
+		locale = prefs.Locale
+	}
+
+	rendered := templates.RenderNotification(w.database, notification, locale)
+	body, err := json.Marshal(Payload{
+		Event:     rendered.Type,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Notification: Notification{
+			ID:         rendered.ID,
+			Type:       rendered.Type,
+			Content:    rendered.Content,
+			RelatedID:  rendered.RelatedID,
+			ActorID:    rendered.ActorID,
+			TargetType: rendered.TargetType,
+			TargetID:   rendered.TargetID,
+			CreatedAt:  rendered.CreatedAt.UTC().Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		log.Printf("[Webhook] Error encoding payload for notification %d: %v", notification.ID, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if err := db.EnqueueDelivery(w.database, hook.ID, notification.ID, notification.Type, string(body)); err != nil {
+			log.Printf("[Webhook] Error queueing delivery to webhook %d: %v", hook.ID, err)
+		}
+	}
+
+	// Deliver now rather than at the next poll
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers due deliveries every PollInterval, or as soon as something is
+// enqueued (blocks forever)
+func (w *Worker) Run() {
+	log.Printf("[Webhook] Worker started (poll %v, %d attempts, disable after %d failures)",
+		w.config.PollInterval, w.config.MaxAttempts, w.config.DisableAfter)
+
+	ticker := time.NewTicker(w.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for w.RunOnce(time.Now()) == batchSize {
+			// Full batch, more may be due
+		}
+
+		select {
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// RunOnce attempts up to one batch of deliveries due at now and returns how
+// many were attempted
+func (w *Worker) RunOnce(now time.Time) int {
+	deliveries, err := db.GetDueDeliveries(w.database, now, batchSize)
+	if err != nil {
+		log.Printf("[Webhook] Error loading due deliveries: %v", err)
+		return 0
+	}
+
+	hooks := map[int]*models.Webhook{}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i := range deliveries {
+		d := &deliveries[i]
+
+		hook, ok := hooks[d.WebhookID]
+		if !ok {
+			hook, err = db.GetWebhookByID(w.database, d.WebhookID)
+			if err != nil {
+				log.Printf("[Webhook] Error loading webhook %d: %v", d.WebhookID, err)
+				continue
+			}
+			hooks[d.WebhookID] = hook
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			w.attempt(hook, d)
+		}()
+	}
+
+	wg.Wait()
+	return len(deliveries)
+}
+
+// attempt sends one delivery and records the outcome
+func (w *Worker) attempt(hook *models.Webhook, d *models.WebhookDelivery) {
+	if !hook.Active {
+		reason := "Webhook is disabled"
+		d.Status = models.DeliveryFailed
+		d.LastError = &reason
+		if err := db.RecordDeliveryAttempt(w.database, d); err != nil {
+			log.Printf("[Webhook] Error recording delivery %d: %v", d.ID, err)
+		}
+		return
+	}
+
+	status, err := w.send(hook, d)
+	d.Attempts++
+	d.LastStatusCode = nil
+	d.LastError = nil
+	if status != 0 {
+		d.LastStatusCode = &status
+	}
+
+	success := err == nil
+	switch {
+	case success:
+		d.Status = models.DeliverySucceeded
+	case d.Attempts >= w.config.MaxAttempts:
+		d.Status = models.DeliveryFailed
+	default:
+		d.Status = models.DeliveryPending
+		d.NextAttemptAt = time.Now().Add(Backoff(d.Attempts))
+	}
+	if err != nil {
+		msg := err.Error()
+		d.LastError = &msg
+	}
+
+	if err := db.RecordDeliveryAttempt(w.database, d); err != nil {
+		log.Printf("[Webhook] Error recording delivery %d: %v", d.ID, err)
+	}
+
+	disabled, err := db.RecordWebhookResult(w.database, hook.ID, success, w.config.DisableAfter)
+	if err != nil {
+		log.Printf("[Webhook] Error recording result for webhook %d: %v", hook.ID, err)
+		return
+	}
+	if disabled {
+		if err := db.FailPendingDeliveries(w.database, hook.ID, "Webhook was disabled"); err != nil {
+			log.Printf("[Webhook] Error failing pending deliveries of webhook %d: %v", hook.ID, err)
+		}
+		log.Printf("[Webhook] Disabled webhook %d of user %d after %d consecutive failures",
+			hook.ID, hook.UserID, w.config.DisableAfter)
+	}
+}
+
+// send POSTs the delivery's payload to the webhook. Returns the response
+// status (0 if none was received) and an error unless it was a 2xx.
+func (w *Worker) send(hook *models.Webhook, d *models.WebhookDelivery) (int, error) {
+	body := []byte(d.Payload)
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "social-network-webhooks/1.0")
+	req.Header.Set(HeaderID, strconv.Itoa(d.ID))
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, time.Now(), body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLen))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, nil
+	}
+
+	msg := fmt.Sprintf("HTTP %d", resp.StatusCode)
+	if text := strings.TrimSpace(string(snippet)); text != "" {
+		msg += ": " + text
+	}
+	return resp.StatusCode, errors.New(msg)
+}
+
+// Backoff returns the wait before retrying after the given number of
+// attempts: 30s, 2m, 8m, 32m, ~2h, then 6h
+func Backoff(attempts int) time.Duration {
+	wait := firstRetry
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 4
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
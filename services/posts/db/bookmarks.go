@@ -0,0 +1,253 @@
+package db
+
+import (
+	"database/sql"
+	"social-network/services/posts/models"
+)
+
+// bookmarkVisible is true when the user can still access the bookmarked post
+// (aliased as o), like CheckPostAccess, and is still a member of its group
+// if it has one. Parameters: see bookmarkVisibleArgs.
+const bookmarkVisible = `EXISTS (SELECT 1 FROM posts p WHERE p.id = o.id AND ` + postAccess + ` AND (
+		p.group_id IS NULL OR EXISTS (
+			SELECT 1 FROM group_members bg
+			WHERE bg.group_id = p.group_id AND bg.user_id = ? AND bg.status = 'accepted'
+		)
+	))`
+
+// bookmarkVisibleArgs returns the parameters of bookmarkVisible
+func bookmarkVisibleArgs(userID int) []interface{} {
+	return append(postAccessArgs(userID), userID)
+}
+
+// SaveBookmark bookmarks a post, or moves an existing bookmark to another
+// collection. The bookmark keeps its place in listings when moved.
+func SaveBookmark(db *sql.DB, bookmark *models.Bookmark) error {
+	err := db.QueryRow(`
+		INSERT INTO bookmarks (user_id, post_id, collection_id, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, post_id) DO UPDATE SET collection_id = excluded.collection_id
+		RETURNING id, created_at
+	`, bookmark.UserID, bookmark.PostID, bookmark.CollectionID, bookmark.CreatedAt.UTC().Format(models.TimeLayout)).
+		Scan(&bookmark.ID, &bookmark.CreatedAt)
+	return err
+}
+
+// DeleteBookmark removes the user's bookmark of a post. Returns false if
+// there was none.
+func DeleteBookmark(db *sql.DB, userID, postID int) (bool, error) {
+	result, err := db.Exec(`DELETE FROM bookmarks WHERE user_id = ? AND post_id = ?`, userID, postID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// GetBookmarks retrieves one page of the user's bookmarks with their posts,
+// newest first, and whether more remain. Bookmarks of posts the user can no
+// longer access are left out.
+func GetBookmarks(db *sql.DB, userID int, page models.BookmarkPage) ([]*models.Bookmark, bool, error) {
+	keyset, args := "1 = 1", []interface{}{}
+	if page.Before != nil {
+		keyset = "(b.created_at < ? OR (b.created_at = ? AND b.id < ?))"
+		args = append(args, page.Before.CreatedAt, page.Before.CreatedAt, page.Before.ID)
+	}
+	collection := "1 = 1"
+	if page.CollectionID != nil {
+		collection = "b.collection_id = ?"
+		args = append(args, *page.CollectionID)
+	}
+
+	query := `
+		SELECT b.id, b.post_id, b.collection_id, b.created_at, ` + postColumns + `
+		FROM bookmarks b
+		INNER JOIN posts o ON o.id = b.post_id
+		INNER JOIN users u ON o.user_id = u.id
+		WHERE b.user_id = ? AND ` + keyset + ` AND ` + collection + ` AND ` + bookmarkVisible + `
+		ORDER BY b.created_at DESC, b.id DESC
+		LIMIT ?
+	`
+	args = append([]interface{}{userID}, args...)
+	args = append(append(args, bookmarkVisibleArgs(userID)...), page.Limit+1)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	bookmarks := []*models.Bookmark{}
+	for rows.Next() {
+		bookmark := &models.Bookmark{UserID: userID}
+		post, err := scanPost(bookmarkRow{rows, bookmark})
+		if err != nil {
+			return nil, false, err
+		}
+		bookmark.Post = post
+		bookmarks = append(bookmarks, bookmark)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(bookmarks) > page.Limit
+	if hasMore {
+		bookmarks = bookmarks[:page.Limit]
+	}
+	return bookmarks, hasMore, nil
+}
+
+// bookmarkRow scans the bookmark columns in front of a post's, so that the
+// post can be read by scanPost
+type bookmarkRow struct {
+	rows     *sql.Rows
+	bookmark *models.Bookmark
+}
+
+func (r bookmarkRow) Scan(dest ...interface{}) error {
+	b := r.bookmark
+	return r.rows.Scan(append([]interface{}{&b.ID, &b.PostID, &b.CollectionID, &b.CreatedAt}, dest...)...)
+}
+
+// GetBookmarkedPostIDs returns which of postIDs the user bookmarked
+func GetBookmarkedPostIDs(db *sql.DB, userID int, postIDs []int) (map[int]bool, error) {
+	bookmarked := make(map[int]bool)
+	if len(postIDs) == 0 {
+		return bookmarked, nil
+	}
+
+	args := make([]interface{}, 0, len(postIDs)+1)
+	args = append(args, userID)
+	for _, id := range postIDs {
+		args = append(args, id)
+	}
+
+	rows, err := db.Query(`
+		SELECT post_id FROM bookmarks
+		WHERE user_id = ? AND post_id IN (`+placeholders(len(postIDs))+`)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		if err := rows.Scan(&postID); err != nil {
+			return nil, err
+		}
+		bookmarked[postID] = true
+	}
+	return bookmarked, rows.Err()
+}
+
+// CreateBookmarkCollection creates a collection. Returns false if the user
+// already has one with that name.
+func CreateBookmarkCollection(db *sql.DB, userID int, collection *models.BookmarkCollection) (bool, error) {
+	err := db.QueryRow(`
+		INSERT INTO bookmark_collections (user_id, name, created_at) VALUES (?, ?, ?)
+		ON CONFLICT (user_id, name) DO NOTHING
+		RETURNING id
+	`, userID, collection.Name, collection.CreatedAt.UTC().Format(models.TimeLayout)).Scan(&collection.ID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RenameBookmarkCollection renames one of the user's collections. Returns
+// false if the user already has one with that name.
+func RenameBookmarkCollection(db *sql.DB, userID, collectionID int, name string) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE OR IGNORE bookmark_collections SET name = ? WHERE id = ? AND user_id = ?
+	`, name, collectionID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteBookmarkCollection deletes one of the user's collections; its
+// bookmarks stay, unfiled. Returns false if there was no such collection.
+func DeleteBookmarkCollection(db *sql.DB, userID, collectionID int) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Unfile explicitly, without relying on the foreign key actions of the
+	// connection
+	_, err = tx.Exec(`UPDATE bookmarks SET collection_id = NULL WHERE collection_id = ? AND user_id = ?`, collectionID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM bookmark_collections WHERE id = ? AND user_id = ?`, collectionID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, tx.Commit()
+}
+
+// GetBookmarkCollection loads one of the user's collections
+func GetBookmarkCollection(db *sql.DB, userID, collectionID int) (*models.BookmarkCollection, error) {
+	collections, err := queryBookmarkCollections(db, userID, "c.id = ?", collectionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(collections) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return collections[0], nil
+}
+
+// GetBookmarkCollections lists the user's collections by name
+func GetBookmarkCollections(db *sql.DB, userID int) ([]*models.BookmarkCollection, error) {
+	return queryBookmarkCollections(db, userID, "1 = 1")
+}
+
+// queryBookmarkCollections lists the user's collections matching a
+// condition on c, with the number of bookmarks GetBookmarks would list
+func queryBookmarkCollections(db *sql.DB, userID int, condition string, args ...interface{}) ([]*models.BookmarkCollection, error) {
+	query := `
+		SELECT c.id, c.name, c.created_at, (
+			SELECT COUNT(*) FROM bookmarks b
+			INNER JOIN posts o ON o.id = b.post_id
+			WHERE b.collection_id = c.id AND ` + bookmarkVisible + `
+		)
+		FROM bookmark_collections c
+		WHERE c.user_id = ? AND ` + condition + `
+		ORDER BY c.name COLLATE NOCASE, c.id
+	`
+	args = append(append(bookmarkVisibleArgs(userID), userID), args...)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []*models.BookmarkCollection{}
+	for rows.Next() {
+		c := &models.BookmarkCollection{}
+		if err := rows.Scan(&c.ID, &c.Name, &c.CreatedAt, &c.BookmarkCount); err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+	return collections, rows.Err()
+}
+
+// CountBookmarkCollections returns how many collections the user has
+func CountBookmarkCollections(db *sql.DB, userID int) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM bookmark_collections WHERE user_id = ?`, userID).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,208 @@
+package db
+
+import (
+	"database/sql"
+	"social-network/services/posts/models"
+	"time"
+)
+
+// commentColumns is the select list read by scanComment, for comments
+// aliased as c joined with their author as u
+const commentColumns = `
+	c.id, c.post_id, c.parent_comment_id, c.depth, c.user_id, c.content, c.image_path,
+	c.created_at, c.edited_at, c.deleted_at IS NOT NULL,
+	(SELECT COUNT(*) FROM comments r WHERE r.parent_comment_id = c.id),
+	u.username, u.first_name, u.last_name, u.avatar_path`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanComment reads one row selected with commentColumns.
+// Tombstones come back without their author.
+func scanComment(row rowScanner) (*models.Comment, error) {
+	comment := &models.Comment{}
+	var parentID sql.NullInt64
+	var imagePath, username, firstName, lastName, avatar sql.NullString
+
+	err := row.Scan(
+		&comment.ID,
+		&comment.PostID,
+		&parentID,
+		&comment.Depth,
+		&comment.UserID,
+		&comment.Content,
+		&imagePath,
+		&comment.CreatedAt,
+		&comment.EditedAt,
+		&comment.Deleted,
+		&comment.ReplyCount,
+		&username,
+		&firstName,
+		&lastName,
+		&avatar,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle nullable fields
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		comment.ParentCommentID = &id
+	}
+	if imagePath.Valid {
+		comment.ImagePath = &imagePath.String
+	}
+
+	if comment.Deleted {
+		comment.UserID = 0
+		return comment, nil
+	}
+
+	// Add author information
+	comment.Author = &models.Author{
+		ID:         comment.UserID,
+		Username:   username.String,
+		FirstName:  firstName.String,
+		LastName:   lastName.String,
+		AvatarPath: avatar.String,
+	}
+
+	return comment, nil
+}
+
+// queryComments runs a query selecting commentColumns
+func queryComments(db *sql.DB, query string, args ...interface{}) ([]*models.Comment, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*models.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+// GetTopLevelComments retrieves one page of a post's top-level comments,
+// oldest first, and whether more remain
+func GetTopLevelComments(db *sql.DB, postID int, page models.CommentPage) ([]*models.Comment, bool, error) {
+	keyset, args := commentPageClause(page)
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments c
+		INNER JOIN users u ON c.user_id = u.id
+		WHERE c.post_id = ? AND c.parent_comment_id IS NULL AND ` + keyset + `
+		ORDER BY c.created_at ASC, c.id ASC
+		LIMIT ?
+	`
+	args = append([]interface{}{postID}, args...)
+	args = append(args, page.Limit+1)
+
+	comments, err := queryComments(db, query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	return finishCommentPage(comments, page)
+}
+
+// GetReplies retrieves one page of the direct replies to a comment, oldest
+// first, and whether more remain
+func GetReplies(db *sql.DB, commentID int, page models.CommentPage) ([]*models.Comment, bool, error) {
+	keyset, args := commentPageClause(page)
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments c
+		INNER JOIN users u ON c.user_id = u.id
+		WHERE c.parent_comment_id = ? AND ` + keyset + `
+		ORDER BY c.created_at ASC, c.id ASC
+		LIMIT ?
+	`
+	args = append([]interface{}{commentID}, args...)
+	args = append(args, page.Limit+1)
+
+	comments, err := queryComments(db, query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	return finishCommentPage(comments, page)
+}
+
+// commentPageClause returns the keyset condition for a page of comments
+// aliased as c, in (created_at ASC, id ASC) order
+func commentPageClause(page models.CommentPage) (string, []interface{}) {
+	if page.After == nil {
+		return "1 = 1", nil
+	}
+	return "(c.created_at > ? OR (c.created_at = ? AND c.id > ?))",
+		[]interface{}{page.After.CreatedAt, page.After.CreatedAt, page.After.ID}
+}
+
+// finishCommentPage trims the extra row fetched for a page of comments
+func finishCommentPage(comments []*models.Comment, page models.CommentPage) ([]*models.Comment, bool, error) {
+	hasMore := len(comments) > page.Limit
+	if hasMore {
+		comments = comments[:page.Limit]
+	}
+	return comments, hasMore, nil
+}
+
+// tombstoneComment clears a comment that still has replies, keeping its
+// place in the thread. Its reactions, mentions, edit history and media go
+// with the content.
+func tombstoneComment(tx *sql.Tx, commentID int, at time.Time) error {
+	_, err := tx.Exec(`
+		UPDATE comments SET content = '', image_path = NULL, deleted_at = ?
+		WHERE id = ?
+	`, at.UTC().Format(models.TimeLayout), commentID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM reactions WHERE target_type = 'comment' AND target_id = ?`, commentID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM comment_revisions WHERE comment_id = ?`, commentID); err != nil {
+		return err
+	}
+	// Detached media is garbage-collected, and the comment's files are no
+	// longer served
+	if _, err := tx.Exec(`UPDATE media SET comment_id = NULL WHERE comment_id = ?`, commentID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM uploads WHERE owner_type = 'comment' AND owner_id = ?`, commentID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM mentions WHERE target_type = 'comment' AND target_id = ?`, commentID)
+	return err
+}
+
+// pruneTombstones removes commentID if it is a tombstone with no replies
+// left, then does the same for its parent, up to the first comment that
+// stays
+func pruneTombstones(tx *sql.Tx, commentID sql.NullInt64) error {
+	for commentID.Valid {
+		var parentID sql.NullInt64
+		err := tx.QueryRow(`
+			DELETE FROM comments
+			WHERE id = ? AND deleted_at IS NOT NULL
+				AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_comment_id = comments.id)
+			RETURNING parent_comment_id
+		`, commentID.Int64).Scan(&parentID)
+		if err == sql.ErrNoRows {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		commentID = parentID
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package db
+
+import (
+	"database/sql"
+	"social-network/services/posts/models"
+	"time"
+)
+
+// formatTime converts an optional time to its stored form (TimeLayout,
+// UTC), or NULL
+func formatTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(models.TimeLayout)
+}
+
+// GetUnpublishedPosts lists an author's scheduled posts (soonest first)
+// followed by their drafts (newest first)
+func GetUnpublishedPosts(db *sql.DB, userID int) ([]*models.Post, error) {
+	query := `
+		SELECT ` + postColumns + `
+		FROM posts o
+		INNER JOIN users u ON o.user_id = u.id
+		WHERE o.user_id = ? AND o.status != 'published'
+		ORDER BY o.publish_at IS NULL, o.publish_at ASC, o.created_at DESC, o.id DESC
+	`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []*models.Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+// UpdateDraft updates an unpublished post in place; drafts keep no edit
+// history. Returns false if the post was published in the meantime.
+func UpdateDraft(db *sql.DB, post *models.Post) (bool, error) {
+	query := `
+		UPDATE posts
+		SET title = ?, content = ?, image_path = ?, privacy_level = ?
+		WHERE id = ? AND status != 'published'
+	`
+	result, err := db.Exec(query, post.Title, post.Content, post.ImagePath, post.PrivacyLevel, post.ID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// SchedulePost sets when an unpublished post is published. A nil publishAt
+// turns it back into a draft. Returns false if the post was published in
+// the meantime.
+func SchedulePost(db *sql.DB, postID int, publishAt *time.Time) (bool, error) {
+	status := models.PostStatusScheduled
+	if publishAt == nil {
+		status = models.PostStatusDraft
+	}
+
+	query := `
+		UPDATE posts
+		SET status = ?, publish_at = ?
+		WHERE id = ? AND status != 'published'
+	`
+	result, err := db.Exec(query, status, formatTime(publishAt), postID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetDuePostIDs lists the scheduled posts whose publish time has come,
+// oldest first
+func GetDuePostIDs(db *sql.DB, now time.Time, limit int) ([]int, error) {
+	query := `
+		SELECT id FROM posts
+		WHERE status = 'scheduled' AND publish_at <= ?
+		ORDER BY publish_at ASC, id ASC
+		LIMIT ?
+	`
+	return queryIDs(db, query, now.UTC().Format(models.TimeLayout), limit)
+}
+
+// ClaimPublication marks an unpublished post as published at publishedAt,
+// which becomes its created_at. Only one caller can claim a post: the
+// update is conditional on the post not being published yet, so it returns
+// false for everyone else, including a scheduler run after a restart.
+func ClaimPublication(db *sql.DB, postID int, publishedAt time.Time) (bool, error) {
+	query := `
+		UPDATE posts
+		SET status = 'published', publish_at = NULL, created_at = ?
+		WHERE id = ? AND status != 'published'
+	`
+	result, err := db.Exec(query, publishedAt.UTC().Format(models.TimeLayout), postID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// DeleteUnpublishedPost deletes a draft or scheduled post. Returns false if
+// it was published in the meantime, in which case it is kept.
+func DeleteUnpublishedPost(db *sql.DB, postID int) (bool, error) {
+	result, err := db.Exec(`DELETE FROM posts WHERE id = ? AND status != 'published'`, postID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetFollowerIDs lists the accepted followers of a user
+func GetFollowerIDs(db *sql.DB, userID int) ([]int, error) {
+	return queryIDs(db, `SELECT follower_id FROM follows WHERE following_id = ? AND status = 'accepted'`, userID)
+}
+
+// GetPostViewerIDs lists the users chosen to see a private post
+func GetPostViewerIDs(db *sql.DB, postID int) ([]int, error) {
+	return queryIDs(db, `SELECT user_id FROM post_viewers WHERE post_id = ?`, postID)
+}
+
+// GetGroupMemberIDs lists the accepted members of a group
+func GetGroupMemberIDs(db *sql.DB, groupID int) ([]int, error) {
+	return queryIDs(db, `SELECT user_id FROM group_members WHERE group_id = ? AND status = 'accepted'`, groupID)
+}
+
+// GetGroupName returns the name of a group
+func GetGroupName(db *sql.DB, groupID int) (string, error) {
+	var name string
+	err := db.QueryRow(`SELECT name FROM groups WHERE id = ?`, groupID).Scan(&name)
+	return name, err
+}
+
+// queryIDs runs a query selecting a single integer column
+func queryIDs(db *sql.DB, query string, args ...interface{}) ([]int, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,207 @@
+package db
+
+import (
+	"database/sql"
+	"social-network/services/common/storage"
+	"social-network/services/posts/models"
+	"time"
+)
+
+// mediaColumns is the select list read by scanMedia
+const mediaColumns = `id, user_id, path, content_type, width, height, size, content_hash, alt_text, position, created_at`
+
+// scanMedia reads one row selected with mediaColumns
+func scanMedia(row rowScanner) (*models.Media, error) {
+	media := &models.Media{}
+	err := row.Scan(&media.ID, &media.UserID, &media.Path, &media.ContentType, &media.Width, &media.Height,
+		&media.Size, &media.ContentHash, &media.AltText, &media.Position, &media.CreatedAt)
+	return media, err
+}
+
+// CreateMedia records an upload, unattached and owned by its uploader
+func CreateMedia(db *sql.DB, media *models.Media) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	createdAt := media.CreatedAt.UTC().Format(models.TimeLayout)
+	result, err := tx.Exec(`
+		INSERT INTO media (user_id, path, content_type, width, height, size, content_hash, alt_text, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, media.UserID, media.Path, media.ContentType, media.Width, media.Height, media.Size, media.ContentHash,
+		media.AltText, createdAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO uploads (path, user_id, created_at) VALUES (?, ?, ?)`,
+		storage.Key(media.Path), media.UserID, createdAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	media.ID = int(id)
+	return tx.Commit()
+}
+
+// GetUnattachedMedia loads the uploads among mediaIDs that the user made
+// and that aren't attached yet, keyed by ID
+func GetUnattachedMedia(db *sql.DB, userID int, mediaIDs []int) (map[int]*models.Media, error) {
+	media := make(map[int]*models.Media)
+	if len(mediaIDs) == 0 {
+		return media, nil
+	}
+
+	args := make([]interface{}, 0, len(mediaIDs)+1)
+	args = append(args, userID)
+	for _, id := range mediaIDs {
+		args = append(args, id)
+	}
+
+	rows, err := db.Query(`
+		SELECT `+mediaColumns+` FROM media
+		WHERE user_id = ? AND post_id IS NULL AND comment_id IS NULL AND id IN (`+placeholders(len(mediaIDs))+`)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m, err := scanMedia(rows)
+		if err != nil {
+			return nil, err
+		}
+		media[m.ID] = m
+	}
+	return media, rows.Err()
+}
+
+// AttachPostMedia attaches uploads to a post in the given order, see
+// attachMedia
+func AttachPostMedia(db *sql.DB, postID, userID int, media []*models.Media) (bool, error) {
+	return attachMedia(db, "post_id", postID, userID, media)
+}
+
+// AttachCommentMedia attaches uploads to a comment in the given order, see
+// attachMedia
+func AttachCommentMedia(db *sql.DB, commentID, userID int, media []*models.Media) (bool, error) {
+	return attachMedia(db, "comment_id", commentID, userID, media)
+}
+
+// attachMedia sets column (post_id or comment_id) of each upload, with its
+// position and alt text. Returns false, attaching nothing, if one of them
+// isn't an unattached upload of the user (anymore).
+func attachMedia(db *sql.DB, column string, targetID, userID int, media []*models.Media) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	for i, m := range media {
+		result, err := tx.Exec(`
+			UPDATE media SET `+column+` = ?, position = ?, alt_text = ?
+			WHERE id = ? AND user_id = ? AND post_id IS NULL AND comment_id IS NULL
+		`, targetID, i, m.AltText, m.ID, userID)
+		if err != nil {
+			return false, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return false, err
+		}
+		if affected == 0 {
+			return false, nil
+		}
+		m.Position = i
+	}
+
+	return true, tx.Commit()
+}
+
+// GetPostMedia loads the attachments of a list of posts, in order, keyed
+// by post ID
+func GetPostMedia(db *sql.DB, postIDs []int) (map[int][]*models.Media, error) {
+	return getAttachedMedia(db, "post_id", postIDs)
+}
+
+// GetCommentMedia loads the attachments of a list of comments, in order,
+// keyed by comment ID
+func GetCommentMedia(db *sql.DB, commentIDs []int) (map[int][]*models.Media, error) {
+	return getAttachedMedia(db, "comment_id", commentIDs)
+}
+
+// getAttachedMedia loads the media attached through column to targetIDs
+func getAttachedMedia(db *sql.DB, column string, targetIDs []int) (map[int][]*models.Media, error) {
+	media := make(map[int][]*models.Media)
+	if len(targetIDs) == 0 {
+		return media, nil
+	}
+
+	args := make([]interface{}, len(targetIDs))
+	for i, id := range targetIDs {
+		args[i] = id
+	}
+
+	rows, err := db.Query(`
+		SELECT `+column+`, `+mediaColumns+` FROM media
+		WHERE `+column+` IN (`+placeholders(len(targetIDs))+`)
+		ORDER BY `+column+`, position
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var targetID int
+		m := &models.Media{}
+		err := rows.Scan(&targetID, &m.ID, &m.UserID, &m.Path, &m.ContentType, &m.Width, &m.Height,
+			&m.Size, &m.ContentHash, &m.AltText, &m.Position, &m.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		media[targetID] = append(media[targetID], m)
+	}
+	return media, rows.Err()
+}
+
+// DeleteOrphanedMedia deletes up to limit uploads that have been
+// unattached since before a time and returns their paths, so their files
+// can be removed. Uploads used as the image_path of a post or comment
+// aren't orphaned.
+func DeleteOrphanedMedia(db *sql.DB, before time.Time, limit int) ([]string, error) {
+	rows, err := db.Query(`
+		DELETE FROM media
+		WHERE id IN (
+			SELECT m.id FROM media m
+			WHERE m.post_id IS NULL AND m.comment_id IS NULL AND m.created_at < ? AND NOT EXISTS (
+				SELECT 1 FROM uploads u
+				WHERE u.path = `+uploadKey("m.path")+` AND u.owner_type IS NOT NULL
+			)
+			ORDER BY m.created_at
+			LIMIT ?
+		)
+		RETURNING path
+	`, before.UTC().Format(models.TimeLayout), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
@@ -0,0 +1,237 @@
+package db
+
+import (
+	"database/sql"
+	"social-network/services/posts/models"
+	"time"
+)
+
+// ResolveUsernames looks up the users whose username matches one of names,
+// ignoring case, oldest account first
+func ResolveUsernames(db *sql.DB, names []string) ([]models.MentionedUser, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT id, username FROM users
+		WHERE username COLLATE NOCASE IN (` + placeholders(len(names)) + `)
+		ORDER BY id
+	`
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.MentionedUser
+	for rows.Next() {
+		var user models.MentionedUser
+		if err := rows.Scan(&user.ID, &user.Username); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// SetMentions replaces the users mentioned in a target and returns the ones
+// that weren't mentioned before, who are the ones to notify
+func SetMentions(db *sql.DB, targetType string, targetID, postID int, userIDs []int, at time.Time) ([]int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT user_id FROM mentions WHERE target_type = ? AND target_id = ?`, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[int]bool)
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		existing[userID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var added []int
+	keep := make(map[int]bool)
+	for _, userID := range userIDs {
+		keep[userID] = true
+		if existing[userID] {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT OR IGNORE INTO mentions (target_type, target_id, user_id, post_id, created_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, targetType, targetID, userID, postID, at.UTC().Format(models.TimeLayout))
+		if err != nil {
+			return nil, err
+		}
+		added = append(added, userID)
+	}
+
+	for userID := range existing {
+		if keep[userID] {
+			continue
+		}
+		_, err := tx.Exec(`DELETE FROM mentions WHERE target_type = ? AND target_id = ? AND user_id = ?`, targetType, targetID, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return added, tx.Commit()
+}
+
+// GetMentionedUsers loads the users mentioned in many targets of one type,
+// chunked like GetReactionSummaries. Targets without mentions are absent.
+func GetMentionedUsers(db *sql.DB, targetType string, targetIDs []int) (map[int][]models.MentionedUser, error) {
+	mentioned := make(map[int][]models.MentionedUser)
+
+	for start := 0; start < len(targetIDs); start += summaryChunkSize {
+		chunk := targetIDs[start:min(start+summaryChunkSize, len(targetIDs))]
+
+		query := `
+			SELECT m.target_id, u.id, u.username
+			FROM mentions m
+			INNER JOIN users u ON u.id = m.user_id
+			WHERE m.target_type = ? AND m.target_id IN (` + placeholders(len(chunk)) + `)
+			ORDER BY m.target_id, u.id
+		`
+		args := make([]interface{}, 0, len(chunk)+1)
+		args = append(args, targetType)
+		for _, id := range chunk {
+			args = append(args, id)
+		}
+
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var targetID int
+			var user models.MentionedUser
+			if err := rows.Scan(&targetID, &user.ID, &user.Username); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			mentioned[targetID] = append(mentioned[targetID], user)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return mentioned, nil
+}
+
+// GetPostsMentioningUser retrieves one page of the posts that mention a user
+// in the post itself or one of its comments, newest first. The user must
+// still be able to see them, like GetPostsByTag.
+func GetPostsMentioningUser(db *sql.DB, userID int, page models.PostPage) ([]*models.Post, bool, error) {
+	keyset, order, pageArgs := pageClause(page)
+
+	query := `
+		SELECT
+			p.id, p.user_id, p.group_id, p.title, p.content, p.image_path, p.privacy_level, p.created_at, p.edited_at, p.status, p.publish_at, p.shared_post_id, p.share_type,
+			u.username, u.first_name, u.last_name, u.avatar_path
+		FROM posts p
+		INNER JOIN users u ON p.user_id = u.id
+		LEFT JOIN follows f ON p.user_id = f.following_id AND f.follower_id = ? AND f.status = 'accepted'
+		LEFT JOIN post_viewers pv ON p.id = pv.post_id AND pv.user_id = ?
+		WHERE
+			p.id IN (SELECT post_id FROM mentions WHERE user_id = ?) AND p.status = 'published' AND (
+				p.privacy_level = 'public' OR
+				p.user_id = ? OR
+				(p.privacy_level = 'almost_private' AND f.follower_id IS NOT NULL) OR
+				(p.privacy_level = 'private' AND pv.user_id IS NOT NULL)
+			) AND (
+				p.group_id IS NULL OR EXISTS (
+					SELECT 1 FROM group_members gm
+					WHERE gm.group_id = p.group_id AND gm.user_id = ? AND gm.status = 'accepted'
+				)
+			) AND ` + keyset + `
+		` + order + `
+	`
+	args := []interface{}{userID, userID, userID, userID, userID}
+	args = append(append(args, pageArgs...), page.Limit+1)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var posts []*models.Post
+	for rows.Next() {
+		post := &models.Post{}
+		var groupID sql.NullInt64
+		var title, imagePath, username, firstName, lastName, avatar sql.NullString
+
+		err := rows.Scan(
+			&post.ID,
+			&post.UserID,
+			&groupID,
+			&title,
+			&post.Content,
+			&imagePath,
+			&post.PrivacyLevel,
+			&post.CreatedAt,
+			&post.EditedAt,
+			&post.Status,
+			&post.PublishAt,
+			&post.SharedPostID,
+			&post.ShareType,
+			&username,
+			&firstName,
+			&lastName,
+			&avatar,
+		)
+		if err != nil {
+			return nil, false, err
+		}
+
+		// Handle nullable fields
+		if groupID.Valid {
+			gid := int(groupID.Int64)
+			post.GroupID = &gid
+		}
+		if title.Valid {
+			post.Title = &title.String
+		}
+		if imagePath.Valid {
+			post.ImagePath = &imagePath.String
+		}
+
+		// Add author information
+		post.Author = &models.Author{
+			ID:         post.UserID,
+			Username:   username.String,
+			FirstName:  firstName.String,
+			LastName:   lastName.String,
+			AvatarPath: avatar.String,
+		}
+
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	posts, hasMore := finishPage(posts, page)
+	return posts, hasMore, nil
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"social-network/services/posts/models"
+)
+
+// pageClause returns the keyset condition, ORDER BY and LIMIT for a page of
+// posts aliased as p. One extra row is fetched to tell whether there's more.
+// Since pages are read oldest-first so a burst of new posts isn't skipped;
+// finishPage flips them back to newest-first.
+func pageClause(page models.PostPage) (string, string, []interface{}) {
+	switch {
+	case page.Since != nil:
+		return "(p.created_at > ? OR (p.created_at = ? AND p.id > ?))",
+			"ORDER BY p.created_at ASC, p.id ASC LIMIT ?",
+			[]interface{}{page.Since.CreatedAt, page.Since.CreatedAt, page.Since.ID}
+
+	case page.Before != nil:
+		return "(p.created_at < ? OR (p.created_at = ? AND p.id < ?))",
+			"ORDER BY p.created_at DESC, p.id DESC LIMIT ?",
+			[]interface{}{page.Before.CreatedAt, page.Before.CreatedAt, page.Before.ID}
+
+	default:
+		return "1 = 1", "ORDER BY p.created_at DESC, p.id DESC LIMIT ?", nil
+	}
+}
+
+// finishPage trims the extra row fetched by pageClause and returns the posts
+// newest first, and whether more posts exist in the direction of the page
+func finishPage(posts []*models.Post, page models.PostPage) ([]*models.Post, bool) {
+	hasMore := len(posts) > page.Limit
+	if hasMore {
+		posts = posts[:page.Limit]
+	}
+
+	if page.Since != nil {
+		for i, j := 0, len(posts)-1; i < j; i, j = i+1, j-1 {
+			posts[i], posts[j] = posts[j], posts[i]
+		}
+	}
+
+	if posts == nil {
+		posts = []*models.Post{}
+	}
+	return posts, hasMore
+}
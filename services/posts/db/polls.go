@@ -0,0 +1,219 @@
+package db
+
+import (
+	"database/sql"
+	"social-network/services/posts/models"
+	"time"
+)
+
+// CreatePoll stores a poll and its options, filling in their IDs
+func CreatePoll(db *sql.DB, poll *models.Poll) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO polls (post_id, multiple_choice, anonymous, hide_results, closes_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, poll.PostID, poll.MultipleChoice, poll.Anonymous, poll.HideResults,
+		formatTime(poll.ClosesAt), poll.CreatedAt.UTC().Format(models.TimeLayout))
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	poll.ID = int(id)
+
+	for _, option := range poll.Options {
+		result, err := tx.Exec(`INSERT INTO poll_options (poll_id, position, text) VALUES (?, ?, ?)`,
+			poll.ID, option.Position, option.Text)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		option.ID = int(id)
+	}
+
+	return tx.Commit()
+}
+
+// GetPolls loads the polls of a list of posts with their options and vote
+// counts, keyed by post ID. Posts without a poll are absent from the map.
+func GetPolls(db *sql.DB, postIDs []int) (map[int]*models.Poll, error) {
+	polls := make(map[int]*models.Poll)
+	if len(postIDs) == 0 {
+		return polls, nil
+	}
+	args := make([]interface{}, len(postIDs))
+	for i, id := range postIDs {
+		args[i] = id
+	}
+
+	rows, err := db.Query(`
+		SELECT p.id, p.post_id, p.multiple_choice, p.anonymous, p.hide_results, p.closes_at, p.created_at,
+			(SELECT COUNT(DISTINCT v.user_id) FROM poll_votes v WHERE v.poll_id = p.id)
+		FROM polls p
+		WHERE p.post_id IN (`+placeholders(len(postIDs))+`)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := make(map[int]*models.Poll)
+	for rows.Next() {
+		poll := &models.Poll{Options: []*models.PollOption{}, ViewerVotes: []int{}}
+		var voters int
+		err := rows.Scan(&poll.ID, &poll.PostID, &poll.MultipleChoice, &poll.Anonymous, &poll.HideResults,
+			&poll.ClosesAt, &poll.CreatedAt, &voters)
+		if err != nil {
+			return nil, err
+		}
+		poll.VoterCount = &voters
+		polls[poll.PostID] = poll
+		byID[poll.ID] = poll
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(byID) == 0 {
+		return polls, nil
+	}
+
+	// One pass over the options of all the polls, counted from the
+	// (option_id, user_id) primary key
+	rows, err = db.Query(`
+		SELECT o.poll_id, o.id, o.position, o.text,
+			(SELECT COUNT(*) FROM poll_votes v WHERE v.option_id = o.id)
+		FROM poll_options o
+		INNER JOIN polls p ON p.id = o.poll_id
+		WHERE p.post_id IN (`+placeholders(len(postIDs))+`)
+		ORDER BY o.poll_id, o.position
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		option := &models.PollOption{}
+		var pollID, votes int
+		if err := rows.Scan(&pollID, &option.ID, &option.Position, &option.Text, &votes); err != nil {
+			return nil, err
+		}
+		option.VoteCount = &votes
+		if poll := byID[pollID]; poll != nil {
+			poll.Options = append(poll.Options, option)
+		}
+	}
+	return polls, rows.Err()
+}
+
+// GetPollVotes returns the options a user chose in each of a list of
+// polls, keyed by poll ID
+func GetPollVotes(db *sql.DB, userID int, pollIDs []int) (map[int][]int, error) {
+	votes := make(map[int][]int)
+	if len(pollIDs) == 0 {
+		return votes, nil
+	}
+
+	args := make([]interface{}, 0, len(pollIDs)+1)
+	args = append(args, userID)
+	for _, id := range pollIDs {
+		args = append(args, id)
+	}
+
+	rows, err := db.Query(`
+		SELECT poll_id, option_id FROM poll_votes
+		WHERE user_id = ? AND poll_id IN (`+placeholders(len(pollIDs))+`)
+		ORDER BY poll_id, option_id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pollID, optionID int
+		if err := rows.Scan(&pollID, &optionID); err != nil {
+			return nil, err
+		}
+		votes[pollID] = append(votes[pollID], optionID)
+	}
+	return votes, rows.Err()
+}
+
+// SetPollVotes replaces a user's vote on a poll with optionIDs (which must
+// belong to the poll). No options withdraws the vote.
+func SetPollVotes(db *sql.DB, pollID, userID int, optionIDs []int, votedAt time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM poll_votes WHERE poll_id = ? AND user_id = ?`, pollID, userID); err != nil {
+		return err
+	}
+
+	at := votedAt.UTC().Format(models.TimeLayout)
+	for _, optionID := range optionIDs {
+		_, err := tx.Exec(`INSERT INTO poll_votes (poll_id, option_id, user_id, created_at) VALUES (?, ?, ?, ?)`,
+			pollID, optionID, userID, at)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetPollVoters lists who voted for each option of a poll, earliest vote
+// first, keyed by option ID
+func GetPollVoters(db *sql.DB, pollID int) (map[int][]*models.Author, error) {
+	rows, err := db.Query(`
+		SELECT v.option_id, u.id, u.username, u.first_name, u.last_name, u.avatar_path
+		FROM poll_votes v
+		INNER JOIN users u ON u.id = v.user_id
+		WHERE v.poll_id = ?
+		ORDER BY v.created_at, u.id
+	`, pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	voters := make(map[int][]*models.Author)
+	for rows.Next() {
+		var optionID int
+		var username, firstName, lastName, avatar sql.NullString
+		author := &models.Author{}
+		if err := rows.Scan(&optionID, &author.ID, &username, &firstName, &lastName, &avatar); err != nil {
+			return nil, err
+		}
+		author.Username = username.String
+		author.FirstName = firstName.String
+		author.LastName = lastName.String
+		author.AvatarPath = avatar.String
+		voters[optionID] = append(voters[optionID], author)
+	}
+	return voters, rows.Err()
+}
+
+// IsGroupMember reports whether a user is an accepted member of a group
+func IsGroupMember(db *sql.DB, groupID, userID int) (bool, error) {
+	var member bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM group_members WHERE group_id = ? AND user_id = ? AND status = 'accepted'
+		)
+	`, groupID, userID).Scan(&member)
+	return member, err
+}
@@ -3,15 +3,18 @@ package db
 import (
 	"database/sql"
 	"social-network/services/posts/models"
+	"time"
 )
 
 // CreatePost inserts a new post into the database
 func CreatePost(db *sql.DB, post *models.Post) error {
 	query := `
-		INSERT INTO posts (user_id, group_id, title, content, image_path, privacy_level, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO posts (user_id, group_id, title, content, image_path, privacy_level, created_at, status, publish_at, shared_post_id, share_type)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	result, err := db.Exec(query, post.UserID, post.GroupID, post.Title, post.Content, post.ImagePath, post.PrivacyLevel, post.CreatedAt)
+	// Stored as UTC "YYYY-MM-DD HH:MM:SS" like datetime('now'), so feed cursors compare correctly
+	post.CreatedAt = post.CreatedAt.UTC().Truncate(time.Second)
+	result, err := db.Exec(query, post.UserID, post.GroupID, post.Title, post.Content, post.ImagePath, post.PrivacyLevel, post.CreatedAt.Format(models.TimeLayout), post.Status, formatTime(post.PublishAt), post.SharedPostID, post.ShareType)
 	if err != nil {
 		return err
 	}
@@ -28,7 +31,7 @@ func CreatePost(db *sql.DB, post *models.Post) error {
 func GetPostByID(db *sql.DB, postID int) (*models.Post, error) {
 	query := `
 		SELECT 
-			p.id, p.user_id, p.group_id, p.title, p.content, p.image_path, p.privacy_level, p.created_at,
+			p.id, p.user_id, p.group_id, p.title, p.content, p.image_path, p.privacy_level, p.created_at, p.edited_at, p.status, p.publish_at, p.shared_post_id, p.share_type,
 			u.username, u.first_name, u.last_name, u.avatar_path
 		FROM posts p
 		INNER JOIN users u ON p.user_id = u.id
@@ -47,6 +50,11 @@ func GetPostByID(db *sql.DB, postID int) (*models.Post, error) {
 		&imagePath,
 		&post.PrivacyLevel,
 		&post.CreatedAt,
+		&post.EditedAt,
+		&post.Status,
+		&post.PublishAt,
+		&post.SharedPostID,
+		&post.ShareType,
 		&username,
 		&firstName,
 		&lastName,
@@ -81,29 +89,93 @@ func GetPostByID(db *sql.DB, postID int) (*models.Post, error) {
 }
 
 // UpdatePost updates an existing post
-func UpdatePost(db *sql.DB, post *models.Post) error {
+// If the title, content, image or privacy level changed, the previous
+// version is saved as a revision and edited_at is set to editedAt.
+func UpdatePost(db *sql.DB, post *models.Post, editedAt time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	at := editedAt.UTC().Format(models.TimeLayout)
+	result, err := tx.Exec(`
+		INSERT INTO post_revisions (post_id, title, content, image_path, privacy_level, replaced_at)
+		SELECT id, title, content, image_path, privacy_level, ?
+		FROM posts
+		WHERE id = ? AND (title IS NOT ? OR content IS NOT ? OR image_path IS NOT ? OR privacy_level IS NOT ?)
+	`, at, post.ID, post.Title, post.Content, post.ImagePath, post.PrivacyLevel)
+	if err != nil {
+		return err
+	}
+	if changed, err := result.RowsAffected(); err != nil || changed == 0 {
+		return err
+	}
+	revisionID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	// The revision keeps who could see it, the post's viewers may change next
+	_, err = tx.Exec(`
+		INSERT INTO post_revision_viewers (revision_id, user_id)
+		SELECT ?, pv.user_id
+		FROM post_viewers pv
+		JOIN posts p ON p.id = pv.post_id
+		WHERE pv.post_id = ? AND p.privacy_level = 'private'
+	`, revisionID, post.ID)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE posts
-		SET content = ?, image_path = ?, privacy_level = ?
+		SET title = ?, content = ?, image_path = ?, privacy_level = ?, edited_at = ?
 		WHERE id = ?
 	`
-	_, err := db.Exec(query, post.Content, post.ImagePath, post.PrivacyLevel, post.ID)
-	return err
+	if _, err := tx.Exec(query, post.Title, post.Content, post.ImagePath, post.PrivacyLevel, at, post.ID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	post.EditedAt = &editedAt
+	return nil
 }
 
 // DeletePost deletes a post by ID
 func DeletePost(db *sql.DB, postID int) error {
-	query := `DELETE FROM posts WHERE id = ?`
-	_, err := db.Exec(query, postID)
-	return err
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Detach the media of the post and its comments so it is garbage-collected,
+	// without relying on the foreign key actions of the connection
+	if _, err := tx.Exec(`UPDATE media SET post_id = NULL WHERE post_id = ?`, postID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE media SET comment_id = NULL
+		WHERE comment_id IN (SELECT id FROM comments WHERE post_id = ?)
+	`, postID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM posts WHERE id = ?`, postID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // GetPostsByUserID retrieves all posts by a specific user (for user's own profile)
 func GetPostsByUserID(db *sql.DB, userID int) ([]*models.Post, error) {
 	query := `
-		SELECT id, user_id, group_id, title, content, image_path, privacy_level, created_at
+		SELECT id, user_id, group_id, title, content, image_path, privacy_level, created_at, edited_at, status, publish_at, shared_post_id, share_type
 		FROM posts
-		WHERE user_id = ? AND group_id IS NULL
+		WHERE user_id = ? AND group_id IS NULL AND status = 'published'
 		ORDER BY created_at DESC
 	`
 	rows, err := db.Query(query, userID)
@@ -127,6 +199,11 @@ func GetPostsByUserID(db *sql.DB, userID int) ([]*models.Post, error) {
 			&imagePath,
 			&privacyLevel,
 			&post.CreatedAt,
+			&post.EditedAt,
+			&post.Status,
+			&post.PublishAt,
+			&post.SharedPostID,
+			&post.ShareType,
 		)
 		if err != nil {
 			return nil, err
@@ -150,28 +227,40 @@ func GetPostsByUserID(db *sql.DB, userID int) ([]*models.Post, error) {
 	return posts, nil
 }
 
-// GetFeedPosts retrieves posts for a user's feed (public + following + own posts)
-func GetFeedPosts(db *sql.DB, userID int) ([]*models.Post, error) {
+// GetFeedPosts retrieves one page of a user's feed (public + following + own posts).
+// Returns the posts newest first and whether more exist (see pageClause).
+func GetFeedPosts(db *sql.DB, userID int, page models.PostPage) ([]*models.Post, bool, error) {
+	keyset, order, pageArgs := pageClause(page)
+
+	// The joins match at most one row per post (both are unique), so no DISTINCT
+	// is needed and the (group_id, created_at, id) index provides the order
 	query := `
-		SELECT DISTINCT 
-			p.id, p.user_id, p.title, p.content, p.image_path, p.privacy_level, p.created_at,
+		SELECT
+			p.id, p.user_id, p.title, p.content, p.image_path, p.privacy_level, p.created_at, p.edited_at, p.status, p.publish_at, p.shared_post_id, p.share_type,
 			u.username, u.first_name, u.last_name, u.avatar_path
 		FROM posts p
 		INNER JOIN users u ON p.user_id = u.id
 		LEFT JOIN follows f ON p.user_id = f.following_id AND f.follower_id = ? AND f.status = 'accepted'
 		LEFT JOIN post_viewers pv ON p.id = pv.post_id AND pv.user_id = ?
 		WHERE 
-			p.group_id IS NULL AND (
+			p.group_id IS NULL AND p.status = 'published' AND (
 				p.privacy_level = 'public' OR
 				p.user_id = ? OR
 				(p.privacy_level = 'almost_private' AND f.follower_id IS NOT NULL) OR
 				(p.privacy_level = 'private' AND pv.user_id IS NOT NULL)
-			)
-		ORDER BY p.created_at DESC
+			) AND (? = 0 OR p.user_id = ?) AND ` + repostVisible + `
+			AND (? != 0 OR ` + repostNewest + `) AND ` + keyset + `
+		` + order + `
 	`
-	rows, err := db.Query(query, userID, userID, userID)
+	// Reposts of the same post are collapsed, except on a profile
+	args := []interface{}{userID, userID, userID, page.AuthorID, page.AuthorID}
+	args = append(args, visibleArgs(userID)...)
+	args = append(args, page.AuthorID)
+	args = append(append(args, pageArgs...), page.Limit+1)
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer rows.Close()
 
@@ -188,13 +277,18 @@ func GetFeedPosts(db *sql.DB, userID int) ([]*models.Post, error) {
 			&imagePath,
 			&post.PrivacyLevel,
 			&post.CreatedAt,
+			&post.EditedAt,
+			&post.Status,
+			&post.PublishAt,
+			&post.SharedPostID,
+			&post.ShareType,
 			&username,
 			&firstName,
 			&lastName,
 			&avatar,
 		)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 
 		// Handle nullable fields
@@ -216,23 +310,33 @@ func GetFeedPosts(db *sql.DB, userID int) ([]*models.Post, error) {
 
 		posts = append(posts, post)
 	}
-	return posts, nil
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	posts, hasMore := finishPage(posts, page)
+	return posts, hasMore, nil
 }
 
-// GetPostsByGroupID retrieves all posts for a specific group
-func GetPostsByGroupID(db *sql.DB, groupID int) ([]*models.Post, error) {
+// GetPostsByGroupID retrieves one page of a group's posts, newest first
+func GetPostsByGroupID(db *sql.DB, groupID int, page models.PostPage) ([]*models.Post, bool, error) {
+	keyset, order, pageArgs := pageClause(page)
+
 	query := `
 		SELECT 
-			p.id, p.user_id, p.group_id, p.title, p.content, p.image_path, p.privacy_level, p.created_at,
+			p.id, p.user_id, p.group_id, p.title, p.content, p.image_path, p.privacy_level, p.created_at, p.edited_at, p.status, p.publish_at, p.shared_post_id, p.share_type,
 			u.username, u.first_name, u.last_name, u.avatar_path
 		FROM posts p
 		INNER JOIN users u ON p.user_id = u.id
-		WHERE p.group_id = ?
-		ORDER BY p.created_at DESC
+		WHERE p.group_id = ? AND p.status = 'published' AND ` + keyset + `
+		` + order + `
 	`
-	rows, err := db.Query(query, groupID)
+	args := append([]interface{}{groupID}, pageArgs...)
+	args = append(args, page.Limit+1)
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer rows.Close()
 
@@ -251,13 +355,18 @@ func GetPostsByGroupID(db *sql.DB, groupID int) ([]*models.Post, error) {
 			&imagePath,
 			&post.PrivacyLevel,
 			&post.CreatedAt,
+			&post.EditedAt,
+			&post.Status,
+			&post.PublishAt,
+			&post.SharedPostID,
+			&post.ShareType,
 			&username,
 			&firstName,
 			&lastName,
 			&avatar,
 		)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 
 		// Handle nullable fields
@@ -283,104 +392,41 @@ func GetPostsByGroupID(db *sql.DB, groupID int) ([]*models.Post, error) {
 
 		posts = append(posts, post)
 	}
-	return posts, nil
-}
-
-// SearchPosts searches for posts based on query string (searches content and title)
-func SearchPosts(db *sql.DB, userID int, searchQuery string) ([]*models.Post, error) {
-	query := `
-		SELECT DISTINCT 
-			p.id, p.user_id, p.title, p.content, p.image_path, p.privacy_level, p.created_at,
-			u.username, u.first_name, u.last_name, u.avatar_path
-		FROM posts p
-		INNER JOIN users u ON p.user_id = u.id
-		LEFT JOIN follows f ON p.user_id = f.following_id AND f.follower_id = ? AND f.status = 'accepted'
-		LEFT JOIN post_viewers pv ON p.id = pv.post_id AND pv.user_id = ?
-		WHERE 
-			p.group_id IS NULL AND (
-				p.privacy_level = 'public' OR
-				p.user_id = ? OR
-				(p.privacy_level = 'almost_private' AND f.follower_id IS NOT NULL) OR
-				(p.privacy_level = 'private' AND pv.user_id IS NOT NULL)
-			) AND (
-				p.content LIKE ? OR
-				p.title LIKE ? OR
-				u.first_name LIKE ? OR
-				u.last_name LIKE ?
-			)
-		ORDER BY p.created_at DESC
-	`
-	searchPattern := "%" + searchQuery + "%"
-	rows, err := db.Query(query, userID, userID, userID, searchPattern, searchPattern, searchPattern, searchPattern)
-	if err != nil {
-		return nil, err
+	if err := rows.Err(); err != nil {
+		return nil, false, err
 	}
-	defer rows.Close()
 
-	var posts []*models.Post
-	for rows.Next() {
-		post := &models.Post{}
-		var title, imagePath, username, firstName, lastName, avatar sql.NullString
+	posts, hasMore := finishPage(posts, page)
+	return posts, hasMore, nil
+}
 
-		err := rows.Scan(
-			&post.ID,
-			&post.UserID,
-			&title,
-			&post.Content,
-			&imagePath,
-			&post.PrivacyLevel,
-			&post.CreatedAt,
-			&username,
-			&firstName,
-			&lastName,
-			&avatar,
+// postAccess is 1 when the viewer can access the post aliased as p: always
+// their own posts, otherwise published posts allowed by its privacy level.
+// Reposts follow their original (see repostVisible). Parameters: see
+// postAccessArgs.
+const postAccess = `(p.user_id = ? OR (
+		p.status = 'published' AND ` + repostVisible + ` AND (
+			p.privacy_level = 'public' OR
+			(p.privacy_level = 'almost_private' AND EXISTS (
+				SELECT 1 FROM follows WHERE follower_id = ? AND following_id = p.user_id AND status = 'accepted'
+			)) OR
+			(p.privacy_level = 'private' AND EXISTS (
+				SELECT 1 FROM post_viewers WHERE post_id = p.id AND user_id = ?
+			))
 		)
-		if err != nil {
-			return nil, err
-		}
-
-		// Handle nullable fields
-		if title.Valid {
-			post.Title = &title.String
-		}
-		if imagePath.Valid {
-			post.ImagePath = &imagePath.String
-		}
-
-		// Add author information
-		post.Author = &models.Author{
-			ID:         post.UserID,
-			Username:   username.String,
-			FirstName:  firstName.String,
-			LastName:   lastName.String,
-			AvatarPath: avatar.String,
-		}
+	))`
 
-		posts = append(posts, post)
-	}
-	return posts, nil
+// postAccessArgs returns the parameters of postAccess
+func postAccessArgs(viewerID int) []interface{} {
+	return append(append([]interface{}{viewerID}, visibleArgs(viewerID)...), viewerID, viewerID)
 }
 
 // CheckPostAccess checks if a user can view a specific post
 func CheckPostAccess(db *sql.DB, postID, userID int) (bool, error) {
-	query := `
-		SELECT 
-			CASE 
-				WHEN p.user_id = ? THEN 1
-				WHEN p.privacy_level = 'public' THEN 1
-				WHEN p.privacy_level = 'almost_private' AND EXISTS (
-					SELECT 1 FROM follows WHERE follower_id = ? AND following_id = p.user_id AND status = 'accepted'
-				) THEN 1
-				WHEN p.privacy_level = 'private' AND EXISTS (
-					SELECT 1 FROM post_viewers WHERE post_id = ? AND user_id = ?
-				) THEN 1
-				ELSE 0
-			END as has_access
-		FROM posts p
-		WHERE p.id = ?
-	`
+	query := `SELECT ` + postAccess + ` FROM posts p WHERE p.id = ?`
 	var hasAccess int
-	err := db.QueryRow(query, userID, userID, postID, userID, postID).Scan(&hasAccess)
+	args := append(postAccessArgs(userID), postID)
+	err := db.QueryRow(query, args...).Scan(&hasAccess)
 	if err != nil {
 		return false, err
 	}
@@ -418,10 +464,10 @@ func AddPostViewers(db *sql.DB, postID int, userIDs []int) error {
 // CreateComment inserts a new comment into the database
 func CreateComment(db *sql.DB, comment *models.Comment) error {
 	query := `
-		INSERT INTO comments (post_id, user_id, content, image_path, created_at)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO comments (post_id, parent_comment_id, depth, user_id, content, image_path, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
-	result, err := db.Exec(query, comment.PostID, comment.UserID, comment.Content, comment.ImagePath, comment.CreatedAt)
+	result, err := db.Exec(query, comment.PostID, comment.ParentCommentID, comment.Depth, comment.UserID, comment.Content, comment.ImagePath, comment.CreatedAt.UTC().Format(models.TimeLayout))
 	if err != nil {
 		return err
 	}
@@ -434,123 +480,106 @@ func CreateComment(db *sql.DB, comment *models.Comment) error {
 	return nil
 }
 
-// GetCommentsByPostID retrieves all comments for a specific post
+// GetCommentsByPostID retrieves all comments for a specific post, replies
+// included, oldest first
 func GetCommentsByPostID(db *sql.DB, postID int) ([]*models.Comment, error) {
 	query := `
-		SELECT 
-			c.id, c.post_id, c.user_id, c.content, c.image_path, c.created_at,
-			u.username, u.first_name, u.last_name, u.avatar_path
+		SELECT ` + commentColumns + `
 		FROM comments c
 		INNER JOIN users u ON c.user_id = u.id
 		WHERE c.post_id = ?
-		ORDER BY c.created_at ASC
+		ORDER BY c.created_at ASC, c.id ASC
 	`
-	rows, err := db.Query(query, postID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var comments []*models.Comment
-	for rows.Next() {
-		comment := &models.Comment{}
-		var imagePath, username, firstName, lastName, avatar sql.NullString
-
-		err := rows.Scan(
-			&comment.ID,
-			&comment.PostID,
-			&comment.UserID,
-			&comment.Content,
-			&imagePath,
-			&comment.CreatedAt,
-			&username,
-			&firstName,
-			&lastName,
-			&avatar,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		// Handle nullable fields
-		if imagePath.Valid {
-			comment.ImagePath = &imagePath.String
-		}
-
-		// Add author information
-		comment.Author = &models.Author{
-			ID:         comment.UserID,
-			Username:   username.String,
-			FirstName:  firstName.String,
-			LastName:   lastName.String,
-			AvatarPath: avatar.String,
-		}
-
-		comments = append(comments, comment)
-	}
-	return comments, nil
+	return queryComments(db, query, postID)
 }
 
-// GetCommentByID retrieves a comment by its ID
+// GetCommentByID retrieves a comment by its ID. Tombstones are returned
+// with Deleted set.
 func GetCommentByID(db *sql.DB, commentID int) (*models.Comment, error) {
 	query := `
-		SELECT 
-			c.id, c.post_id, c.user_id, c.content, c.image_path, c.created_at,
-			u.username, u.first_name, u.last_name, u.avatar_path
+		SELECT ` + commentColumns + `
 		FROM comments c
 		INNER JOIN users u ON c.user_id = u.id
 		WHERE c.id = ?
 	`
-	comment := &models.Comment{}
-	var imagePath, username, firstName, lastName, avatar sql.NullString
-
-	err := db.QueryRow(query, commentID).Scan(
-		&comment.ID,
-		&comment.PostID,
-		&comment.UserID,
-		&comment.Content,
-		&imagePath,
-		&comment.CreatedAt,
-		&username,
-		&firstName,
-		&lastName,
-		&avatar,
-	)
+	return scanComment(db.QueryRow(query, commentID))
+}
+
+// UpdateComment updates an existing comment
+// Like UpdatePost, the previous version is saved as a revision if the
+// content or image changed.
+func UpdateComment(db *sql.DB, comment *models.Comment, editedAt time.Time) error {
+	tx, err := db.Begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	// Handle nullable fields
-	if imagePath.Valid {
-		comment.ImagePath = &imagePath.String
+	defer tx.Rollback()
+
+	at := editedAt.UTC().Format(models.TimeLayout)
+	result, err := tx.Exec(`
+		INSERT INTO comment_revisions (comment_id, content, image_path, replaced_at)
+		SELECT id, content, image_path, ?
+		FROM comments
+		WHERE id = ? AND (content IS NOT ? OR image_path IS NOT ?)
+	`, at, comment.ID, comment.Content, comment.ImagePath)
+	if err != nil {
+		return err
 	}
-
-	// Add author information
-	comment.Author = &models.Author{
-		ID:         comment.UserID,
-		Username:   username.String,
-		FirstName:  firstName.String,
-		LastName:   lastName.String,
-		AvatarPath: avatar.String,
+	if changed, err := result.RowsAffected(); err != nil || changed == 0 {
+		return err
 	}
 
-	return comment, nil
-}
-
-// UpdateComment updates an existing comment
-func UpdateComment(db *sql.DB, comment *models.Comment) error {
 	query := `
 		UPDATE comments
-		SET content = ?, image_path = ?
+		SET content = ?, image_path = ?, edited_at = ?
 		WHERE id = ?
 	`
-	_, err := db.Exec(query, comment.Content, comment.ImagePath, comment.ID)
-	return err
+	if _, err := tx.Exec(query, comment.Content, comment.ImagePath, at, comment.ID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	comment.EditedAt = &editedAt
+	return nil
 }
 
-// DeleteComment deletes a comment by ID
+// DeleteComment deletes a comment by ID. A comment that still has replies
+// is turned into a tombstone instead, and tombstones left without replies
+// by the delete are removed up the thread.
 func DeleteComment(db *sql.DB, commentID int) error {
-	query := `DELETE FROM comments WHERE id = ?`
-	_, err := db.Exec(query, commentID)
-	return err
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var parentID sql.NullInt64
+	var replies int
+	err = tx.QueryRow(`
+		SELECT parent_comment_id, (SELECT COUNT(*) FROM comments r WHERE r.parent_comment_id = c.id)
+		FROM comments c WHERE c.id = ?
+	`, commentID).Scan(&parentID, &replies)
+	if err != nil {
+		return err
+	}
+
+	if replies > 0 {
+		if err := tombstoneComment(tx, commentID, time.Now()); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	if _, err := tx.Exec(`UPDATE media SET comment_id = NULL WHERE comment_id = ?`, commentID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM comments WHERE id = ?`, commentID); err != nil {
+		return err
+	}
+	if err := pruneTombstones(tx, parentID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
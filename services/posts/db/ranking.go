@@ -0,0 +1,163 @@
+package db
+
+import (
+	"database/sql"
+	"social-network/services/posts/models"
+	"time"
+)
+
+// GetRankCandidates loads the posts the ranked feed chooses from: feed posts
+// and posts in the viewer's groups created between from and at (both
+// TimeLayout), newest first, with the inputs to their score. Visibility
+// follows CheckPostAccess. Activity after at is ignored so repeated calls
+// with the same at return the same values.
+func GetRankCandidates(db *sql.DB, userID int, from, at string, limit int) ([]*models.RankCandidate, error) {
+	// The union lets each half use idx_posts_group_created
+	query := `
+		WITH candidates AS (
+			SELECT id FROM posts
+			WHERE group_id IS NULL AND status = 'published' AND created_at >= ? AND created_at <= ?
+			UNION ALL
+			SELECT p.id FROM posts p
+			INNER JOIN group_members gm ON gm.group_id = p.group_id AND gm.user_id = ? AND gm.status = 'accepted'
+			WHERE p.status = 'published' AND p.created_at >= ? AND p.created_at <= ?
+		)
+		SELECT
+			p.id, p.user_id, p.group_id, p.title, p.content, p.image_path, p.privacy_level, p.created_at, p.edited_at, p.status, p.publish_at, p.shared_post_id, p.share_type,
+			u.username, u.first_name, u.last_name, u.avatar_path,
+			f.follower_id IS NOT NULL,
+			EXISTS (
+				SELECT 1 FROM follows fb
+				WHERE fb.follower_id = p.user_id AND fb.following_id = ? AND fb.status = 'accepted'
+			),
+			(
+				SELECT COUNT(*) FROM messages m
+				WHERE ((m.sender_id = ? AND m.recipient_id = p.user_id) OR (m.sender_id = p.user_id AND m.recipient_id = ?))
+					AND datetime(m.created_at) <= ?
+			),
+			(
+				SELECT COUNT(*) FROM reactions r
+				WHERE r.target_type = 'post' AND r.target_id = p.id AND datetime(r.created_at) <= ?
+			),
+			(
+				SELECT COUNT(*) FROM comments c
+				WHERE c.post_id = p.id AND datetime(c.created_at) <= ?
+			),
+			p.group_id IS NOT NULL,
+			EXISTS (
+				SELECT 1 FROM post_views v
+				WHERE v.user_id = ? AND v.post_id = p.id AND v.seen_at <= ?
+			)
+		FROM candidates cand
+		INNER JOIN posts p ON p.id = cand.id
+		INNER JOIN users u ON p.user_id = u.id
+		LEFT JOIN follows f ON p.user_id = f.following_id AND f.follower_id = ? AND f.status = 'accepted'
+		LEFT JOIN post_viewers pv ON p.id = pv.post_id AND pv.user_id = ?
+		WHERE (
+			p.user_id = ? OR
+			p.privacy_level = 'public' OR
+			(p.privacy_level = 'almost_private' AND f.follower_id IS NOT NULL) OR
+			(p.privacy_level = 'private' AND pv.user_id IS NOT NULL)
+		) AND ` + repostVisible + ` AND ` + repostNewest + `
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT ?
+	`
+	rows, err := db.Query(query,
+		from, at, userID, from, at,
+		userID,
+		userID, userID, at,
+		at,
+		at,
+		userID, at,
+		userID, userID, userID,
+		userID, userID, userID, userID,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := []*models.RankCandidate{}
+	for rows.Next() {
+		post := &models.Post{}
+		candidate := &models.RankCandidate{Post: post}
+		var groupID sql.NullInt64
+		var title, imagePath, username, firstName, lastName, avatar sql.NullString
+
+		err := rows.Scan(
+			&post.ID,
+			&post.UserID,
+			&groupID,
+			&title,
+			&post.Content,
+			&imagePath,
+			&post.PrivacyLevel,
+			&post.CreatedAt,
+			&post.EditedAt,
+			&post.Status,
+			&post.PublishAt,
+			&post.SharedPostID,
+			&post.ShareType,
+			&username,
+			&firstName,
+			&lastName,
+			&avatar,
+			&candidate.ViewerFollows,
+			&candidate.FollowsViewer,
+			&candidate.ChatMessages,
+			&candidate.Reactions,
+			&candidate.Comments,
+			&candidate.InGroup,
+			&candidate.Seen,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Handle nullable fields
+		if groupID.Valid {
+			gid := int(groupID.Int64)
+			post.GroupID = &gid
+		}
+		if title.Valid {
+			post.Title = &title.String
+		}
+		if imagePath.Valid {
+			post.ImagePath = &imagePath.String
+		}
+
+		// Add author information
+		post.Author = &models.Author{
+			ID:         post.UserID,
+			Username:   username.String,
+			FirstName:  firstName.String,
+			LastName:   lastName.String,
+			AvatarPath: avatar.String,
+		}
+
+		candidates = append(candidates, candidate)
+	}
+	return candidates, rows.Err()
+}
+
+// MarkPostsSeen records that a user has seen posts. Posts already seen keep
+// their first seen_at, and IDs of posts that don't exist are skipped.
+func MarkPostsSeen(db *sql.DB, userID int, postIDs []int, seenAt time.Time) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT OR IGNORE INTO post_views (user_id, post_id, seen_at)
+		SELECT ?, id, ? FROM posts WHERE id IN (` + placeholders(len(postIDs)) + `)
+	`
+	args := make([]interface{}, 0, len(postIDs)+2)
+	args = append(args, userID, seenAt.UTC().Format(models.TimeLayout))
+	for _, id := range postIDs {
+		args = append(args, id)
+	}
+
+	_, err := db.Exec(query, args...)
+	return err
+}
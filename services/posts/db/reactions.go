@@ -0,0 +1,173 @@
+package db
+
+import (
+	"database/sql"
+	"social-network/services/posts/models"
+	"strings"
+)
+
+// AddReaction adds a user's emoji to a target.
+// Returns false if the user had already reacted with that emoji.
+func AddReaction(db *sql.DB, reaction *models.Reaction) (bool, error) {
+	query := `
+		INSERT OR IGNORE INTO reactions (target_type, target_id, user_id, emoji, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	result, err := db.Exec(query, reaction.TargetType, reaction.TargetID, reaction.UserID, reaction.Emoji, reaction.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// RemoveReaction removes a user's emoji from a target.
+// Returns false if there was nothing to remove.
+func RemoveReaction(db *sql.DB, targetType string, targetID, userID int, emoji string) (bool, error) {
+	query := `
+		DELETE FROM reactions
+		WHERE target_type = ? AND target_id = ? AND user_id = ? AND emoji = ?
+	`
+	result, err := db.Exec(query, targetType, targetID, userID, emoji)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// CountUserReactions counts the different emoji a user added to a target
+func CountUserReactions(db *sql.DB, targetType string, targetID, userID int) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM reactions
+		WHERE target_type = ? AND target_id = ? AND user_id = ?
+	`
+	var count int
+	err := db.QueryRow(query, targetType, targetID, userID).Scan(&count)
+	return count, err
+}
+
+// GetReactions lists who reacted to a target, newest first.
+// If emoji is non-empty only that emoji is listed.
+func GetReactions(db *sql.DB, targetType string, targetID int, emoji string, limit int) ([]*models.Reaction, error) {
+	query := `
+		SELECT
+			r.target_type, r.target_id, r.user_id, r.emoji, r.created_at,
+			u.username, u.first_name, u.last_name, u.avatar_path
+		FROM reactions r
+		INNER JOIN users u ON r.user_id = u.id
+		WHERE r.target_type = ? AND r.target_id = ? AND (? = '' OR r.emoji = ?)
+		ORDER BY r.created_at DESC
+		LIMIT ?
+	`
+	rows, err := db.Query(query, targetType, targetID, emoji, emoji, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactions := []*models.Reaction{}
+	for rows.Next() {
+		reaction := &models.Reaction{}
+		var username, firstName, lastName, avatar sql.NullString
+
+		err := rows.Scan(
+			&reaction.TargetType,
+			&reaction.TargetID,
+			&reaction.UserID,
+			&reaction.Emoji,
+			&reaction.CreatedAt,
+			&username,
+			&firstName,
+			&lastName,
+			&avatar,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		reaction.Author = &models.Author{
+			ID:         reaction.UserID,
+			Username:   username.String,
+			FirstName:  firstName.String,
+			LastName:   lastName.String,
+			AvatarPath: avatar.String,
+		}
+
+		reactions = append(reactions, reaction)
+	}
+	return reactions, rows.Err()
+}
+
+// summaryChunkSize keeps GetReactionSummaries under SQLite's bound-parameter limit
+const summaryChunkSize = 500
+
+// GetReactionSummaries aggregates the reactions on many targets of one type
+// with one query per 500 targets: per-emoji counts (most used first) and the
+// emoji viewerID added. Targets without reactions are absent from the map.
+func GetReactionSummaries(db *sql.DB, targetType string, targetIDs []int, viewerID int) (map[int]*models.ReactionSummary, error) {
+	summaries := make(map[int]*models.ReactionSummary)
+
+	for start := 0; start < len(targetIDs); start += summaryChunkSize {
+		end := min(start+summaryChunkSize, len(targetIDs))
+		if err := loadReactionSummaries(db, summaries, targetType, targetIDs[start:end], viewerID); err != nil {
+			return nil, err
+		}
+	}
+	return summaries, nil
+}
+
+// loadReactionSummaries adds the summaries of targetIDs to summaries
+func loadReactionSummaries(db *sql.DB, summaries map[int]*models.ReactionSummary, targetType string, targetIDs []int, viewerID int) error {
+	query := `
+		SELECT target_id, emoji, COUNT(*), MAX(user_id = ?)
+		FROM reactions
+		WHERE target_type = ? AND target_id IN (` + placeholders(len(targetIDs)) + `)
+		GROUP BY target_id, emoji
+		ORDER BY target_id, COUNT(*) DESC, MIN(created_at)
+	`
+	args := make([]interface{}, 0, len(targetIDs)+2)
+	args = append(args, viewerID, targetType)
+	for _, id := range targetIDs {
+		args = append(args, id)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var targetID, count int
+		var emoji string
+		var mine bool
+		if err := rows.Scan(&targetID, &emoji, &count, &mine); err != nil {
+			return err
+		}
+
+		summary, ok := summaries[targetID]
+		if !ok {
+			summary = &models.ReactionSummary{}
+			summaries[targetID] = summary
+		}
+		summary.Counts = append(summary.Counts, models.ReactionCount{Emoji: emoji, Count: count})
+		if mine {
+			summary.ViewerReactions = append(summary.ViewerReactions, emoji)
+		}
+	}
+	return rows.Err()
+}
+
+// placeholders returns "?,?,...,?" with n placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
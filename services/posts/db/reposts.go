@@ -0,0 +1,189 @@
+package db
+
+import (
+	"database/sql"
+	"social-network/services/posts/models"
+)
+
+// originalVisible is true when the viewer can see the post aliased as o,
+// applying the feed's privacy rules plus group membership.
+// Parameters: viewer x4.
+const originalVisible = `o.status = 'published' AND (
+		o.user_id = ? OR
+		o.privacy_level = 'public' OR
+		(o.privacy_level = 'almost_private' AND EXISTS (
+			SELECT 1 FROM follows vf
+			WHERE vf.follower_id = ? AND vf.following_id = o.user_id AND vf.status = 'accepted'
+		)) OR
+		(o.privacy_level = 'private' AND EXISTS (
+			SELECT 1 FROM post_viewers vv WHERE vv.post_id = o.id AND vv.user_id = ?
+		))
+	) AND (
+		o.group_id IS NULL OR EXISTS (
+			SELECT 1 FROM group_members vg
+			WHERE vg.group_id = o.group_id AND vg.user_id = ? AND vg.status = 'accepted'
+		)
+	)`
+
+// repostVisible lists a repost (post aliased as p) only to viewers who can
+// see its original, so reposting never widens the original's audience.
+// Parameters: viewer x4.
+const repostVisible = `(p.share_type != 'repost' OR EXISTS (
+		SELECT 1 FROM posts o WHERE o.id = p.shared_post_id AND ` + originalVisible + `
+	))`
+
+// repostNewest collapses the reposts of an original into the newest one.
+// Reposts are public, so whoever can see one repost can see them all.
+const repostNewest = `(p.share_type != 'repost' OR NOT EXISTS (
+		SELECT 1 FROM posts r
+		WHERE r.shared_post_id = p.shared_post_id AND r.share_type = 'repost' AND r.status = 'published'
+			AND (r.created_at > p.created_at OR (r.created_at = p.created_at AND r.id > p.id))
+	))`
+
+// visibleArgs returns the parameters of originalVisible and repostVisible
+func visibleArgs(viewerID int) []interface{} {
+	return []interface{}{viewerID, viewerID, viewerID, viewerID}
+}
+
+// postColumns is the select list read by scanPost, for posts aliased as o
+// joined with their author as u
+const postColumns = `
+	o.id, o.user_id, o.group_id, o.title, o.content, o.image_path, o.privacy_level,
+	o.created_at, o.edited_at, o.status, o.publish_at, o.shared_post_id, o.share_type,
+	u.username, u.first_name, u.last_name, u.avatar_path`
+
+// scanPost reads one row selected with postColumns
+func scanPost(row rowScanner) (*models.Post, error) {
+	post := &models.Post{}
+	var groupID sql.NullInt64
+	var title, imagePath, username, firstName, lastName, avatar sql.NullString
+
+	err := row.Scan(
+		&post.ID,
+		&post.UserID,
+		&groupID,
+		&title,
+		&post.Content,
+		&imagePath,
+		&post.PrivacyLevel,
+		&post.CreatedAt,
+		&post.EditedAt,
+		&post.Status,
+		&post.PublishAt,
+		&post.SharedPostID,
+		&post.ShareType,
+		&username,
+		&firstName,
+		&lastName,
+		&avatar,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle nullable fields
+	if groupID.Valid {
+		gid := int(groupID.Int64)
+		post.GroupID = &gid
+	}
+	if title.Valid {
+		post.Title = &title.String
+	}
+	if imagePath.Valid {
+		post.ImagePath = &imagePath.String
+	}
+
+	// Add author information
+	post.Author = &models.Author{
+		ID:         post.UserID,
+		Username:   username.String,
+		FirstName:  firstName.String,
+		LastName:   lastName.String,
+		AvatarPath: avatar.String,
+	}
+
+	return post, nil
+}
+
+// GetVisiblePosts loads the posts among postIDs that the viewer can see,
+// keyed by ID. Used to embed shared posts.
+func GetVisiblePosts(db *sql.DB, viewerID int, postIDs []int) (map[int]*models.Post, error) {
+	posts := make(map[int]*models.Post)
+	if len(postIDs) == 0 {
+		return posts, nil
+	}
+
+	query := `
+		SELECT ` + postColumns + `
+		FROM posts o
+		INNER JOIN users u ON o.user_id = u.id
+		WHERE o.id IN (` + placeholders(len(postIDs)) + `) AND ` + originalVisible
+	args := make([]interface{}, 0, len(postIDs)+4)
+	for _, id := range postIDs {
+		args = append(args, id)
+	}
+	args = append(args, visibleArgs(viewerID)...)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts[post.ID] = post
+	}
+	return posts, rows.Err()
+}
+
+// GetRepostCounts counts the published reposts of each post. Posts without
+// reposts are absent from the map.
+func GetRepostCounts(db *sql.DB, postIDs []int) (map[int]int, error) {
+	counts := make(map[int]int)
+	if len(postIDs) == 0 {
+		return counts, nil
+	}
+
+	query := `
+		SELECT shared_post_id, COUNT(*)
+		FROM posts
+		WHERE shared_post_id IN (` + placeholders(len(postIDs)) + `) AND share_type = 'repost' AND status = 'published'
+		GROUP BY shared_post_id
+	`
+	args := make([]interface{}, len(postIDs))
+	for i, id := range postIDs {
+		args[i] = id
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID, count int
+		if err := rows.Scan(&postID, &count); err != nil {
+			return nil, err
+		}
+		counts[postID] = count
+	}
+	return counts, rows.Err()
+}
+
+// GetRepostID returns the ID of the user's repost of a post, or 0
+func GetRepostID(db *sql.DB, userID, postID int) (int, error) {
+	var id int
+	err := db.QueryRow(`
+		SELECT id FROM posts
+		WHERE user_id = ? AND shared_post_id = ? AND share_type = 'repost'
+	`, userID, postID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
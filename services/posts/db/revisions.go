@@ -0,0 +1,144 @@
+package db
+
+import (
+	"database/sql"
+	"social-network/services/posts/models"
+)
+
+// revisionAccess is 1 when the viewer could have seen the revision aliased
+// as r, of the post aliased as p, by its own privacy level and viewers: the
+// author always can. Parameters: the viewer ID, 3 times.
+const revisionAccess = `(p.user_id = ? OR
+		r.privacy_level = 'public' OR
+		(r.privacy_level = 'almost_private' AND EXISTS (
+			SELECT 1 FROM follows WHERE follower_id = ? AND following_id = p.user_id AND status = 'accepted'
+		)) OR
+		(r.privacy_level = 'private' AND EXISTS (
+			SELECT 1 FROM post_revision_viewers WHERE revision_id = r.id AND user_id = ?
+		))
+	)`
+
+// GetPostRevisions lists the previous versions of a post the viewer could
+// have seen, newest first
+func GetPostRevisions(db *sql.DB, postID, viewerID int) ([]*models.PostRevision, error) {
+	query := `
+		SELECT r.id, r.post_id, r.title, r.content, r.image_path, r.privacy_level, r.replaced_at
+		FROM post_revisions r
+		JOIN posts p ON p.id = r.post_id
+		WHERE r.post_id = ? AND ` + revisionAccess + `
+		ORDER BY r.id DESC
+	`
+	rows, err := db.Query(query, postID, viewerID, viewerID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*models.PostRevision{}
+	for rows.Next() {
+		revision := &models.PostRevision{}
+		var title, imagePath sql.NullString
+
+		err := rows.Scan(
+			&revision.ID,
+			&revision.PostID,
+			&title,
+			&revision.Content,
+			&imagePath,
+			&revision.PrivacyLevel,
+			&revision.ReplacedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Handle nullable fields
+		if title.Valid {
+			revision.Title = &title.String
+		}
+		if imagePath.Valid {
+			revision.ImagePath = &imagePath.String
+		}
+
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+// GetCommentRevisions lists the previous versions of a comment, newest first
+func GetCommentRevisions(db *sql.DB, commentID int) ([]*models.CommentRevision, error) {
+	query := `
+		SELECT id, comment_id, content, image_path, replaced_at
+		FROM comment_revisions
+		WHERE comment_id = ?
+		ORDER BY id DESC
+	`
+	rows, err := db.Query(query, commentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*models.CommentRevision{}
+	for rows.Next() {
+		revision := &models.CommentRevision{}
+		var imagePath sql.NullString
+
+		err := rows.Scan(
+			&revision.ID,
+			&revision.CommentID,
+			&revision.Content,
+			&imagePath,
+			&revision.ReplacedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if imagePath.Valid {
+			revision.ImagePath = &imagePath.String
+		}
+
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+// RevisionImageHidden reports whether a file of a post, by storage key, is
+// only the image of previous versions the viewer couldn't have seen
+func RevisionImageHidden(db *sql.DB, postID int, key string, viewerID int) (bool, error) {
+	var hidden bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM post_revisions r WHERE r.post_id = ? AND `+uploadKey("r.image_path")+` = ?
+		) AND NOT EXISTS (
+			SELECT 1 FROM posts p WHERE p.id = ? AND `+uploadKey("p.image_path")+` = ?
+		) AND NOT EXISTS (
+			SELECT 1 FROM post_revisions r
+			JOIN posts p ON p.id = r.post_id
+			WHERE r.post_id = ? AND `+uploadKey("r.image_path")+` = ? AND `+revisionAccess+`
+		)
+	`, postID, key, postID, key, postID, key, viewerID, viewerID, viewerID).Scan(&hidden)
+	return hidden, err
+}
+
+// DeletePostRevisions purges a post's edit history. The post keeps its
+// edited_at. Returns the number of revisions removed.
+func DeletePostRevisions(db *sql.DB, postID int) (int, error) {
+	result, err := db.Exec(`DELETE FROM post_revisions WHERE post_id = ?`, postID)
+	if err != nil {
+		return 0, err
+	}
+	removed, err := result.RowsAffected()
+	return int(removed), err
+}
+
+// DeleteCommentRevisions is DeletePostRevisions for comments
+func DeleteCommentRevisions(db *sql.DB, commentID int) (int, error) {
+	result, err := db.Exec(`DELETE FROM comment_revisions WHERE comment_id = ?`, commentID)
+	if err != nil {
+		return 0, err
+	}
+	removed, err := result.RowsAffected()
+	return int(removed), err
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"database/sql"
+	"social-network/services/posts/models"
+)
+
+// searchColumns selects a post, its author and the raw highlights of a
+// posts_fts match. Matches are wrapped in the models.Highlight markers.
+const searchColumns = `
+	p.id, p.user_id, p.group_id, p.title, p.content, p.image_path, p.privacy_level, p.created_at, p.edited_at, p.status, p.publish_at, p.shared_post_id, p.share_type,
+	u.username, u.first_name, u.last_name, u.avatar_path,
+	highlight(posts_fts, 0, ?, ?),
+	snippet(posts_fts, 1, ?, ?, '…', 16)
+`
+
+// searchFrom joins the FTS matches to their posts and applies the feed's
+// privacy rules. Unlike the feed, posts in groups the viewer belongs to are
+// included. Parameters: viewer x4.
+const searchFrom = `
+	FROM posts_fts
+	INNER JOIN posts p ON p.id = posts_fts.rowid
+	INNER JOIN users u ON p.user_id = u.id
+	LEFT JOIN follows f ON p.user_id = f.following_id AND f.follower_id = ? AND f.status = 'accepted'
+	LEFT JOIN post_viewers pv ON p.id = pv.post_id AND pv.user_id = ?
+	WHERE
+		posts_fts MATCH ? AND p.status = 'published' AND p.share_type != 'repost' AND (
+			p.privacy_level = 'public' OR
+			p.user_id = ? OR
+			(p.privacy_level = 'almost_private' AND f.follower_id IS NOT NULL) OR
+			(p.privacy_level = 'private' AND pv.user_id IS NOT NULL)
+		) AND (
+			p.group_id IS NULL OR EXISTS (
+				SELECT 1 FROM group_members gm
+				WHERE gm.group_id = p.group_id AND gm.user_id = ? AND gm.status = 'accepted'
+			)
+		)
+`
+
+// searchArgs returns the parameters of searchColumns and searchFrom
+func searchArgs(userID int, match string) []interface{} {
+	return []interface{}{
+		models.HighlightStart, models.HighlightEnd,
+		models.HighlightStart, models.HighlightEnd,
+		userID, userID, match, userID, userID,
+	}
+}
+
+// SearchPosts finds posts matching an FTS5 query (see utils.BuildSearchQuery)
+// in title, content or author name, best match first. Title matches weigh
+// most, then author names, then content. Returns the page and whether more
+// results exist.
+func SearchPosts(db *sql.DB, userID int, match string, offset, limit int) ([]*models.Post, bool, error) {
+	query := `SELECT ` + searchColumns + searchFrom + `
+		ORDER BY bm25(posts_fts, 4.0, 1.0, 2.0), p.id DESC
+		LIMIT ? OFFSET ?
+	`
+	args := append(searchArgs(userID, match), limit+1, offset)
+
+	posts, err := querySearch(db, query, args)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(posts) > limit
+	if hasMore {
+		posts = posts[:limit]
+	}
+	return posts, hasMore, nil
+}
+
+// SearchPostsLatest is SearchPosts ordered newest first and paginated like
+// the feed
+func SearchPostsLatest(db *sql.DB, userID int, match string, page models.PostPage) ([]*models.Post, bool, error) {
+	keyset, order, pageArgs := pageClause(page)
+
+	query := `SELECT ` + searchColumns + searchFrom + ` AND ` + keyset + `
+		` + order + `
+	`
+	args := append(searchArgs(userID, match), pageArgs...)
+	args = append(args, page.Limit+1)
+
+	posts, err := querySearch(db, query, args)
+	if err != nil {
+		return nil, false, err
+	}
+
+	posts, hasMore := finishPage(posts, page)
+	return posts, hasMore, nil
+}
+
+// querySearch runs a search query and scans its rows
+func querySearch(db *sql.DB, query string, args []interface{}) ([]*models.Post, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []*models.Post{}
+	for rows.Next() {
+		post := &models.Post{Highlight: &models.Highlight{}}
+		var groupID sql.NullInt64
+		var title, imagePath, username, firstName, lastName, avatar sql.NullString
+
+		err := rows.Scan(
+			&post.ID,
+			&post.UserID,
+			&groupID,
+			&title,
+			&post.Content,
+			&imagePath,
+			&post.PrivacyLevel,
+			&post.CreatedAt,
+			&post.EditedAt,
+			&post.Status,
+			&post.PublishAt,
+			&post.SharedPostID,
+			&post.ShareType,
+			&username,
+			&firstName,
+			&lastName,
+			&avatar,
+			&post.Highlight.Title,
+			&post.Highlight.Snippet,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Handle nullable fields
+		if groupID.Valid {
+			gid := int(groupID.Int64)
+			post.GroupID = &gid
+		}
+		if title.Valid {
+			post.Title = &title.String
+		}
+		if imagePath.Valid {
+			post.ImagePath = &imagePath.String
+		}
+
+		// Add author information
+		post.Author = &models.Author{
+			ID:         post.UserID,
+			Username:   username.String,
+			FirstName:  firstName.String,
+			LastName:   lastName.String,
+			AvatarPath: avatar.String,
+		}
+
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"database/sql"
+	"social-network/services/posts/models"
+	"time"
+)
+
+// SetPostTags replaces a post's hashtags with tags. Tags the post already
+// had keep their original created_at, so editing a post doesn't make its
+// tags trend again.
+func SetPostTags(db *sql.DB, postID int, tags []string, taggedAt time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Drop the tags that are no longer in the post
+	args := make([]interface{}, 0, len(tags)+1)
+	args = append(args, postID)
+	for _, tag := range tags {
+		args = append(args, tag)
+	}
+	query := `DELETE FROM post_tags WHERE post_id = ?`
+	if len(tags) > 0 {
+		query += ` AND tag_id NOT IN (SELECT id FROM tags WHERE name IN (` + placeholders(len(tags)) + `))`
+	}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+
+	at := taggedAt.UTC().Format(models.TimeLayout)
+	for _, tag := range tags {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO tags (name, created_at) VALUES (?, ?)`, tag, at); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`
+			INSERT OR IGNORE INTO post_tags (post_id, tag_id, created_at)
+			SELECT ?, id, ? FROM tags WHERE name = ?
+		`, postID, at, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetPostsByTag retrieves one page of the posts with a hashtag that the
+// user can see, newest first. Like search, posts in the user's groups are
+// included.
+func GetPostsByTag(db *sql.DB, userID int, tag string, page models.PostPage) ([]*models.Post, bool, error) {
+	keyset, order, pageArgs := pageClause(page)
+
+	query := `
+		SELECT
+			p.id, p.user_id, p.group_id, p.title, p.content, p.image_path, p.privacy_level, p.created_at, p.edited_at, p.status, p.publish_at, p.shared_post_id, p.share_type,
+			u.username, u.first_name, u.last_name, u.avatar_path
+		FROM tags t
+		INNER JOIN post_tags pt ON pt.tag_id = t.id
+		INNER JOIN posts p ON p.id = pt.post_id
+		INNER JOIN users u ON p.user_id = u.id
+		LEFT JOIN follows f ON p.user_id = f.following_id AND f.follower_id = ? AND f.status = 'accepted'
+		LEFT JOIN post_viewers pv ON p.id = pv.post_id AND pv.user_id = ?
+		WHERE
+			t.name = ? AND p.status = 'published' AND (
+				p.privacy_level = 'public' OR
+				p.user_id = ? OR
+				(p.privacy_level = 'almost_private' AND f.follower_id IS NOT NULL) OR
+				(p.privacy_level = 'private' AND pv.user_id IS NOT NULL)
+			) AND (
+				p.group_id IS NULL OR EXISTS (
+					SELECT 1 FROM group_members gm
+					WHERE gm.group_id = p.group_id AND gm.user_id = ? AND gm.status = 'accepted'
+				)
+			) AND ` + keyset + `
+		` + order + `
+	`
+	args := []interface{}{userID, userID, tag, userID, userID}
+	args = append(append(args, pageArgs...), page.Limit+1)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var posts []*models.Post
+	for rows.Next() {
+		post := &models.Post{}
+		var groupID sql.NullInt64
+		var title, imagePath, username, firstName, lastName, avatar sql.NullString
+
+		err := rows.Scan(
+			&post.ID,
+			&post.UserID,
+			&groupID,
+			&title,
+			&post.Content,
+			&imagePath,
+			&post.PrivacyLevel,
+			&post.CreatedAt,
+			&post.EditedAt,
+			&post.Status,
+			&post.PublishAt,
+			&post.SharedPostID,
+			&post.ShareType,
+			&username,
+			&firstName,
+			&lastName,
+			&avatar,
+		)
+		if err != nil {
+			return nil, false, err
+		}
+
+		// Handle nullable fields
+		if groupID.Valid {
+			gid := int(groupID.Int64)
+			post.GroupID = &gid
+		}
+		if title.Valid {
+			post.Title = &title.String
+		}
+		if imagePath.Valid {
+			post.ImagePath = &imagePath.String
+		}
+
+		// Add author information
+		post.Author = &models.Author{
+			ID:         post.UserID,
+			Username:   username.String,
+			FirstName:  firstName.String,
+			LastName:   lastName.String,
+			AvatarPath: avatar.String,
+		}
+
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	posts, hasMore := finishPage(posts, page)
+	return posts, hasMore, nil
+}
+
+// GetTagUses lists the hashtags added to public, non-group posts since a
+// time (TimeLayout). Only those count towards trending, so tags on posts
+// with limited visibility never show up there.
+func GetTagUses(db *sql.DB, since string) ([]*models.TagUse, error) {
+	query := `
+		SELECT t.name, p.user_id, pt.created_at
+		FROM post_tags pt
+		INNER JOIN tags t ON t.id = pt.tag_id
+		INNER JOIN posts p ON p.id = pt.post_id
+		WHERE pt.created_at >= ? AND p.privacy_level = 'public' AND p.group_id IS NULL
+	`
+	rows, err := db.Query(query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uses []*models.TagUse
+	for rows.Next() {
+		use := &models.TagUse{}
+		if err := rows.Scan(&use.Tag, &use.AuthorID, &use.TaggedAt); err != nil {
+			return nil, err
+		}
+		uses = append(uses, use)
+	}
+	return uses, rows.Err()
+}
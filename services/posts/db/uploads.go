@@ -0,0 +1,62 @@
+package db
+
+import (
+	"database/sql"
+	"social-network/services/common/storage"
+	"social-network/services/posts/models"
+)
+
+// uploadKey converts a stored path column to its storage key, like
+// storage.Key
+func uploadKey(column string) string {
+	return `CASE WHEN ` + column + ` LIKE '/uploads/%' THEN substr(` + column + `, 10) ELSE substr(` + column + `, 9) END`
+}
+
+// ClaimUpload makes a post or comment the owner of an uploaded file. Only
+// the uploader can claim a file nobody owns yet; claiming a file the same
+// owner already has succeeds. Returns false if the file can't be claimed.
+func ClaimUpload(db *sql.DB, path string, userID int, ownerType string, ownerID int) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE uploads SET owner_type = ?, owner_id = ?
+		WHERE path = ? AND (
+			(owner_type IS NULL AND user_id = ?) OR
+			(owner_type = ? AND owner_id = ?)
+		)
+	`, ownerType, ownerID, storage.Key(path), userID, ownerType, ownerID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// GetUpload loads the ownership of a file by storage key
+func GetUpload(db *sql.DB, key string) (*models.Upload, error) {
+	upload := &models.Upload{}
+	var ownerType sql.NullString
+	var ownerID sql.NullInt64
+	err := db.QueryRow(`SELECT path, user_id, owner_type, owner_id FROM uploads WHERE path = ?`, key).
+		Scan(&upload.Path, &upload.UserID, &ownerType, &ownerID)
+	if err != nil {
+		return nil, err
+	}
+	upload.OwnerType = ownerType.String
+	upload.OwnerID = int(ownerID.Int64)
+	return upload, nil
+}
+
+// DeleteUnusedMedia deletes the media of a file the user uploaded and
+// nothing uses yet. Returns false if there is no such file.
+func DeleteUnusedMedia(db *sql.DB, key string, userID int) (bool, error) {
+	result, err := db.Exec(`
+		DELETE FROM media
+		WHERE `+uploadKey("path")+` = ? AND user_id = ? AND post_id IS NULL AND comment_id IS NULL AND NOT EXISTS (
+			SELECT 1 FROM uploads u WHERE u.path = ? AND u.owner_type IS NOT NULL
+		)
+	`, key, userID, key)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
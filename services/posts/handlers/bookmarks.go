@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"social-network/services/posts/middleware"
+	"social-network/services/posts/models"
+	"social-network/services/posts/utils"
+)
+
+// Bookmark handles POST /posts/:id/bookmark requests, with an optional
+// {"collection_id": ...} body. Bookmarking a post again moves it to the
+// given collection (or out of its collection).
+func (h *PostHandlers) Bookmark(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := bookmarkPostID(r)
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.BookmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.ErrorResponse(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	bookmark, err := h.postService.Bookmark(postID, userID, req.CollectionID)
+	if err != nil {
+		bookmarkError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"bookmark": bookmark,
+	})
+}
+
+// Unbookmark handles DELETE /posts/:id/bookmark requests
+func (h *PostHandlers) Unbookmark(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := bookmarkPostID(r)
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.postService.Unbookmark(postID, userID); err != nil {
+		bookmarkError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"message": "Bookmark removed successfully",
+	})
+}
+
+// GetBookmarks handles GET /posts/bookmarks[?collection_id=&limit=&before=]
+// requests, listing the user's bookmarks newest first
+func (h *PostHandlers) GetBookmarks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, err := parseBookmarkPage(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bookmarks, hasMore, err := h.postService.GetBookmarks(userID, page)
+	if err != nil {
+		log.Printf("GetBookmarks error for user %d: %v", userID, err)
+		bookmarkError(w, err)
+		return
+	}
+
+	var nextCursor *string
+	if hasMore && len(bookmarks) > 0 {
+		cursor := models.BookmarkCursorFor(bookmarks[len(bookmarks)-1]).Encode()
+		nextCursor = &cursor
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"bookmarks":   bookmarks,
+		"has_more":    hasMore,
+		"next_cursor": nextCursor,
+	})
+}
+
+// BookmarkCollections handles GET /posts/bookmarks/collections requests,
+// listing the user's collections, and POST requests creating one
+func (h *PostHandlers) BookmarkCollections(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		collections, err := h.postService.GetBookmarkCollections(userID)
+		if err != nil {
+			log.Printf("GetBookmarkCollections error for user %d: %v", userID, err)
+			utils.ErrorResponse(w, "Failed to retrieve collections", http.StatusInternalServerError)
+			return
+		}
+		utils.SuccessResponse(w, map[string]interface{}{
+			"collections": collections,
+		})
+
+	case "POST":
+		var req models.BookmarkCollectionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.ErrorResponse(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+
+		collection, err := h.postService.CreateBookmarkCollection(userID, req.Name)
+		if err != nil {
+			bookmarkError(w, err)
+			return
+		}
+		utils.SuccessResponse(w, map[string]interface{}{
+			"collection": collection,
+		})
+
+	default:
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// BookmarkCollection handles PUT /posts/bookmarks/collections/:id requests,
+// renaming a collection, and DELETE requests deleting it (its bookmarks
+// are kept)
+func (h *PostHandlers) BookmarkCollection(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	collectionID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/posts/bookmarks/collections/"))
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid collection ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "PUT":
+		var req models.BookmarkCollectionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.ErrorResponse(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+
+		collection, err := h.postService.RenameBookmarkCollection(collectionID, userID, req.Name)
+		if err != nil {
+			bookmarkError(w, err)
+			return
+		}
+		utils.SuccessResponse(w, map[string]interface{}{
+			"collection": collection,
+		})
+
+	case "DELETE":
+		if err := h.postService.DeleteBookmarkCollection(collectionID, userID); err != nil {
+			bookmarkError(w, err)
+			return
+		}
+		utils.SuccessResponse(w, map[string]interface{}{
+			"message": "Collection deleted successfully",
+		})
+
+	default:
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// parseBookmarkPage reads ?collection_id=&limit=&before= from the request;
+// before takes the next_cursor of a previous page
+func parseBookmarkPage(r *http.Request) (models.BookmarkPage, error) {
+	page := models.BookmarkPage{}
+
+	limit, err := parsePostsLimit(r)
+	if err != nil {
+		return page, err
+	}
+	page.Limit = limit
+
+	if value := r.URL.Query().Get("collection_id"); value != "" {
+		collectionID, err := strconv.Atoi(value)
+		if err != nil {
+			return page, errors.New("invalid collection_id")
+		}
+		page.CollectionID = &collectionID
+	}
+
+	if before := r.URL.Query().Get("before"); before != "" {
+		cursor, err := models.DecodePostCursor(before)
+		if err != nil {
+			return page, err
+		}
+		page.Before = cursor
+	}
+
+	return page, nil
+}
+
+// bookmarkPostID extracts the post ID from a /posts/:id/bookmark path
+func bookmarkPostID(r *http.Request) (int, error) {
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/posts/"), "/bookmark")
+	return strconv.Atoi(path)
+}
+
+// bookmarkError writes the response for a bookmark service error
+func bookmarkError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "access denied") {
+		utils.ErrorResponse(w, err.Error(), http.StatusForbidden)
+	} else if strings.Contains(err.Error(), "not found") {
+		utils.ErrorResponse(w, err.Error(), http.StatusNotFound)
+	} else if strings.Contains(err.Error(), "already exists") {
+		utils.ErrorResponse(w, err.Error(), http.StatusConflict)
+	} else {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+	}
+}
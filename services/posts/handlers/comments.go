@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"social-network/services/posts/middleware"
+	"social-network/services/posts/models"
+	"social-network/services/posts/utils"
+)
+
+// GetPostComments handles GET /posts/:id/comments[?limit=&after=] requests,
+// listing a post's top-level comments oldest first
+func (h *PostHandlers) GetPostComments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Extract post ID from URL path
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/posts/"), "/comments")
+	postID, err := strconv.Atoi(path)
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parseCommentPage(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	comments, hasMore, err := h.postService.GetPostComments(postID, userID, page)
+	if err != nil {
+		commentListError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, commentPageResponse(comments, hasMore))
+}
+
+// GetReplies handles GET /comments/:id/replies[?limit=&after=] requests,
+// listing the direct replies to a comment oldest first
+func (h *PostHandlers) GetReplies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Extract comment ID from URL path
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/comments/"), "/replies")
+	commentID, err := strconv.Atoi(path)
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parseCommentPage(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	replies, hasMore, err := h.postService.GetReplies(commentID, userID, page)
+	if err != nil {
+		commentListError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, commentPageResponse(replies, hasMore))
+}
+
+// commentListError writes the error response for a failed comment listing
+func commentListError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "access denied") {
+		utils.ErrorResponse(w, err.Error(), http.StatusForbidden)
+	} else if strings.Contains(err.Error(), "not found") {
+		utils.ErrorResponse(w, err.Error(), http.StatusNotFound)
+	} else {
+		utils.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// parseCommentPage reads ?limit=&after= from the request; after takes the
+// next_cursor of a previous page
+func parseCommentPage(r *http.Request) (models.CommentPage, error) {
+	page := models.CommentPage{}
+
+	limit, err := parsePostsLimit(r)
+	if err != nil {
+		return page, err
+	}
+	page.Limit = limit
+
+	if after := r.URL.Query().Get("after"); after != "" {
+		cursor, err := models.DecodePostCursor(after)
+		if err != nil {
+			return page, err
+		}
+		page.After = cursor
+	}
+
+	return page, nil
+}
+
+// commentPageResponse builds the response for a page of comments.
+// next_cursor is set when more comments follow this page.
+func commentPageResponse(comments []*models.Comment, hasMore bool) map[string]interface{} {
+	var nextCursor *string
+	if hasMore && len(comments) > 0 {
+		cursor := models.CommentCursorFor(comments[len(comments)-1]).Encode()
+		nextCursor = &cursor
+	}
+
+	return map[string]interface{}{
+		"comments":    comments,
+		"has_more":    hasMore,
+		"next_cursor": nextCursor,
+	}
+}
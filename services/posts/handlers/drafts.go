@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"social-network/services/posts/middleware"
+	"social-network/services/posts/models"
+	"social-network/services/posts/utils"
+)
+
+// GetDrafts handles GET /posts/drafts requests, listing the authenticated
+// user's scheduled posts and drafts
+func (h *PostHandlers) GetDrafts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	posts, err := h.postService.GetDrafts(userID)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"posts": posts,
+	})
+}
+
+// UpdateDraft handles PUT /posts/drafts/:id requests
+func (h *PostHandlers) UpdateDraft(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PUT" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := draftID(r, "")
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdatePostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.postService.UpdateDraft(postID, userID, &req)
+	if err != nil {
+		draftError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"post": post,
+	})
+}
+
+// ScheduleDraft handles POST /posts/drafts/:id/schedule requests.
+// {"publish_at": null} turns a scheduled post back into a draft.
+func (h *PostHandlers) ScheduleDraft(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := draftID(r, "/schedule")
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.postService.ScheduleDraft(postID, userID, req.PublishAt)
+	if err != nil {
+		draftError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"post": post,
+	})
+}
+
+// PublishDraft handles POST /posts/drafts/:id/publish requests
+func (h *PostHandlers) PublishDraft(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := draftID(r, "/publish")
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.postService.PublishDraft(postID, userID)
+	if err != nil {
+		draftError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"post": post,
+	})
+}
+
+// CancelDraft handles DELETE /posts/drafts/:id requests
+func (h *PostHandlers) CancelDraft(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := draftID(r, "")
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.postService.CancelDraft(postID, userID); err != nil {
+		draftError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"message": "Draft deleted successfully",
+	})
+}
+
+// draftID extracts the post ID from /posts/drafts/:id{suffix}
+func draftID(r *http.Request, suffix string) (int, error) {
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/posts/drafts/"), suffix)
+	return strconv.Atoi(path)
+}
+
+// draftError writes the error response for a failed draft operation
+func draftError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "unauthorized") {
+		utils.ErrorResponse(w, err.Error(), http.StatusForbidden)
+	} else if strings.Contains(err.Error(), "not found") {
+		utils.ErrorResponse(w, err.Error(), http.StatusNotFound)
+	} else if strings.Contains(err.Error(), "already published") {
+		utils.ErrorResponse(w, err.Error(), http.StatusConflict)
+	} else {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+	}
+}
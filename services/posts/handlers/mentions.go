@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"social-network/services/posts/middleware"
+	"social-network/services/posts/utils"
+)
+
+// GetMentions handles GET /posts/mentions[?limit=&before=&since=] requests,
+// listing the posts that mention the authenticated user
+func (h *PostHandlers) GetMentions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, err := parsePostPage(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	posts, hasMore, err := h.postService.GetMentions(userID, page)
+	if err != nil {
+		log.Printf("GetMentions error for user %d: %v", userID, err)
+		utils.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.SuccessResponse(w, postPageResponse(posts, hasMore, page))
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"social-network/services/posts/models"
+)
+
+// Page size limits for the feed, group posts and search
+const (
+	defaultPostsLimit = 20
+	maxPostsLimit     = 100
+)
+
+// parsePostPage reads ?limit=&before=&since= from the request.
+// before and since take a cursor returned by a previous page; since also
+// accepts an RFC 3339 time for clients that only know when they last looked.
+func parsePostPage(r *http.Request) (models.PostPage, error) {
+	query := r.URL.Query()
+	page := models.PostPage{Limit: defaultPostsLimit}
+
+	limit, err := parsePostsLimit(r)
+	if err != nil {
+		return page, err
+	}
+	page.Limit = limit
+
+	before, since := query.Get("before"), query.Get("since")
+	if before != "" && since != "" {
+		return page, errors.New("before and since can't be combined")
+	}
+
+	if before != "" {
+		cursor, err := models.DecodePostCursor(before)
+		if err != nil {
+			return page, err
+		}
+		page.Before = cursor
+	}
+
+	if since != "" {
+		cursor, err := models.DecodePostCursor(since)
+		if err != nil {
+			t, terr := time.Parse(time.RFC3339, since)
+			if terr != nil {
+				return page, err
+			}
+			cursor = &models.PostCursor{CreatedAt: t.UTC().Format(models.TimeLayout)}
+		}
+		page.Since = cursor
+	}
+
+	return page, nil
+}
+
+// parsePostsLimit reads ?limit=, capped at maxPostsLimit
+func parsePostsLimit(r *http.Request) (int, error) {
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		return defaultPostsLimit, nil
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		return 0, errors.New("invalid limit")
+	}
+	return min(limit, maxPostsLimit), nil
+}
+
+// postPageResponse builds the response for a page of posts.
+// next_cursor is set when older posts remain; newest_cursor is what to pass
+// as since to poll for posts newer than this page.
+func postPageResponse(posts []*models.Post, hasMore bool, page models.PostPage) map[string]interface{} {
+	var nextCursor, newestCursor *string
+
+	if hasMore && page.Since == nil && len(posts) > 0 {
+		cursor := models.CursorFor(posts[len(posts)-1]).Encode()
+		nextCursor = &cursor
+	}
+	if len(posts) > 0 {
+		cursor := models.CursorFor(posts[0]).Encode()
+		newestCursor = &cursor
+	} else if page.Since != nil {
+		// Nothing new yet: keep polling from the same position
+		cursor := page.Since.Encode()
+		newestCursor = &cursor
+	}
+
+	return map[string]interface{}{
+		"posts":         posts,
+		"has_more":      hasMore,
+		"next_cursor":   nextCursor,
+		"newest_cursor": newestCursor,
+	}
+}
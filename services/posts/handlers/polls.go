@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"social-network/services/posts/middleware"
+	"social-network/services/posts/models"
+	"social-network/services/posts/utils"
+)
+
+// GetPoll handles GET /posts/:id/poll requests, returning the poll with
+// its results (and voters, unless anonymous) if the user may see them
+func (h *PostHandlers) GetPoll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := pollPostID(r, "/poll")
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	poll, err := h.postService.GetPoll(postID, userID)
+	if err != nil {
+		pollError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"poll": poll,
+	})
+}
+
+// Vote handles POST /posts/:id/poll/vote requests. Voting again replaces
+// the earlier vote.
+func (h *PostHandlers) Vote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := pollPostID(r, "/poll/vote")
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.VoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	poll, err := h.postService.Vote(postID, userID, req.OptionIDs)
+	if err != nil {
+		pollError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"poll": poll,
+	})
+}
+
+// RetractVote handles DELETE /posts/:id/poll/vote requests
+func (h *PostHandlers) RetractVote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := pollPostID(r, "/poll/vote")
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	poll, err := h.postService.RetractVote(postID, userID)
+	if err != nil {
+		pollError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"poll": poll,
+	})
+}
+
+// pollPostID extracts the post ID from a /posts/:id/poll... path
+func pollPostID(r *http.Request, suffix string) (int, error) {
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/posts/"), suffix)
+	return strconv.Atoi(path)
+}
+
+// pollError writes the response for a poll service error
+func pollError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "access denied") {
+		utils.ErrorResponse(w, err.Error(), http.StatusForbidden)
+	} else if strings.Contains(err.Error(), "not found") {
+		utils.ErrorResponse(w, err.Error(), http.StatusNotFound)
+	} else if strings.Contains(err.Error(), "closed") {
+		utils.ErrorResponse(w, err.Error(), http.StatusConflict)
+	} else {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+	}
+}
@@ -39,13 +39,19 @@ func (h *PostHandlers) CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	username, ok := middleware.GetUsernameFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req models.CreatePostRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ErrorResponse(w, "Invalid JSON payload", http.StatusBadRequest)
 		return
 	}
 
-	post, err := h.postService.CreatePost(&req, userID)
+	post, err := h.postService.CreatePost(&req, userID, username)
 	if err != nil {
 		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
@@ -56,7 +62,7 @@ func (h *PostHandlers) CreatePost(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GetFeed handles GET /posts requests
+// GetFeed handles GET /posts/feed[?mode=latest|ranked&limit=&before=&since=&user_id=] requests
 func (h *PostHandlers) GetFeed(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -70,19 +76,43 @@ func (h *PostHandlers) GetFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	posts, err := h.postService.GetFeed(userID)
+	switch r.URL.Query().Get("mode") {
+	case "", models.FeedModeLatest:
+	case models.FeedModeRanked:
+		h.getRankedFeed(w, r, userID)
+		return
+	default:
+		utils.ErrorResponse(w, "Invalid feed mode", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePostPage(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Optional author filter, used by profile pages
+	if authorStr := r.URL.Query().Get("user_id"); authorStr != "" {
+		page.AuthorID, err = strconv.Atoi(authorStr)
+		if err != nil || page.AuthorID <= 0 {
+			utils.ErrorResponse(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	posts, hasMore, err := h.postService.GetFeed(userID, page)
 	if err != nil {
 		log.Printf("GetFeed error for user %d: %v", userID, err)
 		utils.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	utils.SuccessResponse(w, map[string]interface{}{
-		"posts": posts,
-	})
+	utils.SuccessResponse(w, postPageResponse(posts, hasMore, page))
 }
 
-// SearchPosts handles GET /posts/search?q=query requests
+// SearchPosts handles GET /posts/search?q=query[&sort=relevance|latest&limit=&before=&since=] requests.
+// Relevance results page with before only; latest results page like the feed.
 func (h *PostHandlers) SearchPosts(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -100,21 +130,21 @@ func (h *PostHandlers) SearchPosts(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 	if query == "" {
 		utils.SuccessResponse(w, map[string]interface{}{
-			"posts": []*models.Post{},
+			"posts":       []*models.Post{},
+			"has_more":    false,
+			"next_cursor": nil,
 		})
 		return
 	}
 
-	posts, err := h.postService.SearchPosts(userID, query)
-	if err != nil {
-		log.Printf("SearchPosts error for user %d: %v", userID, err)
-		utils.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
-		return
+	switch r.URL.Query().Get("sort") {
+	case "", models.SearchSortRelevance:
+		h.searchByRelevance(w, r, userID, query)
+	case models.SearchSortLatest:
+		h.searchLatest(w, r, userID, query)
+	default:
+		utils.ErrorResponse(w, "Invalid sort", http.StatusBadRequest)
 	}
-
-	utils.SuccessResponse(w, map[string]interface{}{
-		"posts": posts,
-	})
 }
 
 // GetPost handles GET /posts/:id requests
@@ -168,6 +198,12 @@ func (h *PostHandlers) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	username, ok := middleware.GetUsernameFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	// Extract post ID from URL path
 	path := strings.TrimPrefix(r.URL.Path, "/posts/")
 	postID, err := strconv.Atoi(path)
@@ -182,7 +218,7 @@ func (h *PostHandlers) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post, err := h.postService.UpdatePost(postID, userID, &req)
+	post, err := h.postService.UpdatePost(postID, userID, username, &req)
 	if err != nil {
 		if strings.Contains(err.Error(), "unauthorized") {
 			utils.ErrorResponse(w, err.Error(), http.StatusForbidden)
@@ -264,6 +300,8 @@ func (h *PostHandlers) CreateComment(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		if err.Error() == "access denied: cannot comment on this post" {
 			utils.ErrorResponse(w, err.Error(), http.StatusForbidden)
+		} else if strings.Contains(err.Error(), "not found") {
+			utils.ErrorResponse(w, err.Error(), http.StatusNotFound)
 		} else {
 			utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
 		}
@@ -331,6 +369,12 @@ func (h *PostHandlers) UpdateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	username, ok := middleware.GetUsernameFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	// Extract comment ID from URL path
 	path := strings.TrimPrefix(r.URL.Path, "/comments/")
 	commentID, err := strconv.Atoi(path)
@@ -347,7 +391,7 @@ func (h *PostHandlers) UpdateComment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update comment
-	comment, err := h.postService.UpdateComment(commentID, userID, req.Content, req.ImagePath)
+	comment, err := h.postService.UpdateComment(commentID, userID, username, req.Content, req.ImagePath)
 	if err != nil {
 		if strings.Contains(err.Error(), "unauthorized") {
 			utils.ErrorResponse(w, err.Error(), http.StatusForbidden)
@@ -404,15 +448,15 @@ func (h *PostHandlers) DeleteComment(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GetGroupPosts handles GET /posts/group/:id requests
+// GetGroupPosts handles GET /posts/group/:id[?limit=&before=&since=] requests
 func (h *PostHandlers) GetGroupPosts(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Get authenticated user ID from context (for future access control)
-	_, ok := middleware.GetUserIDFromContext(r)
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
 	if !ok {
 		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -426,16 +470,20 @@ func (h *PostHandlers) GetGroupPosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	posts, err := h.postService.GetGroupPosts(groupID)
+	page, err := parsePostPage(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	posts, hasMore, err := h.postService.GetGroupPosts(groupID, userID, page)
 	if err != nil {
 		log.Printf("GetGroupPosts error for group %d: %v", groupID, err)
 		utils.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	utils.SuccessResponse(w, map[string]interface{}{
-		"posts": posts,
-	})
+	utils.SuccessResponse(w, postPageResponse(posts, hasMore, page))
 }
 
 // HealthHandler handles GET /health requests
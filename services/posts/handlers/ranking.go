@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"social-network/services/posts/middleware"
+	"social-network/services/posts/models"
+	"social-network/services/posts/utils"
+)
+
+// getRankedFeed serves GET /posts/feed?mode=ranked[&limit=&before=].
+// before takes the next_cursor of the previous page; since and user_id only
+// apply to the chronological feed.
+func (h *PostHandlers) getRankedFeed(w http.ResponseWriter, r *http.Request, userID int) {
+	query := r.URL.Query()
+	if query.Get("since") != "" || query.Get("user_id") != "" {
+		utils.ErrorResponse(w, "since and user_id aren't supported by the ranked feed", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := parsePostsLimit(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var cursor *models.RankCursor
+	if before := query.Get("before"); before != "" {
+		cursor, err = models.DecodeRankCursor(before)
+		if err != nil {
+			utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	posts, next, err := h.postService.GetRankedFeed(userID, cursor, limit)
+	if err != nil {
+		log.Printf("GetRankedFeed error for user %d: %v", userID, err)
+		utils.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var nextCursor *string
+	if next != nil {
+		encoded := next.Encode()
+		nextCursor = &encoded
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"posts":       posts,
+		"has_more":    next != nil,
+		"next_cursor": nextCursor,
+	})
+}
+
+// MarkPostsSeen handles POST /posts/seen requests with {"post_ids": [...]},
+// sent by clients for posts that were on screen in the feed
+func (h *PostHandlers) MarkPostsSeen(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.SeenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.postService.MarkPostsSeen(userID, req.PostIDs); err != nil {
+		log.Printf("MarkPostsSeen error for user %d: %v", userID, err)
+		utils.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"message": "Posts marked as seen",
+	})
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"social-network/services/posts/middleware"
+	"social-network/services/posts/models"
+	"social-network/services/posts/utils"
+)
+
+// Page size limits for GET /reactions
+const (
+	defaultReactionsLimit = 50
+	maxReactionsLimit     = 200
+)
+
+// AddReaction handles POST /reactions requests
+func (h *PostHandlers) AddReaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	username, ok := middleware.GetUsernameFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.ReactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.postService.AddReaction(&req, userID, username)
+	if err != nil {
+		reactionError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, reactionSummaryResponse(&req, summary))
+}
+
+// RemoveReaction handles DELETE /reactions requests
+// The reaction is given in the JSON body, like POST.
+func (h *PostHandlers) RemoveReaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.ReactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.postService.RemoveReaction(&req, userID)
+	if err != nil {
+		reactionError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, reactionSummaryResponse(&req, summary))
+}
+
+// GetReactions handles GET /reactions?target_type=post&target_id=:id[&emoji=&limit=] requests
+func (h *PostHandlers) GetReactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	targetType := query.Get("target_type")
+	targetID, err := strconv.Atoi(query.Get("target_id"))
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid target_id", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultReactionsLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			utils.ErrorResponse(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		if limit > maxReactionsLimit {
+			limit = maxReactionsLimit
+		}
+	}
+
+	reactions, summary, err := h.postService.GetReactions(targetType, targetID, userID, query.Get("emoji"), limit)
+	if err != nil {
+		reactionError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"reactions":        reactions,
+		"counts":           summary.Counts,
+		"viewer_reactions": summary.ViewerReactions,
+	})
+}
+
+// reactionSummaryResponse builds the response to adding or removing a reaction
+func reactionSummaryResponse(req *models.ReactionRequest, summary *models.ReactionSummary) map[string]interface{} {
+	return map[string]interface{}{
+		"target_type":      req.TargetType,
+		"target_id":        req.TargetID,
+		"reactions":        summary.Counts,
+		"viewer_reactions": summary.ViewerReactions,
+	}
+}
+
+// reactionError maps a reaction service error to an HTTP status
+func reactionError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "access denied"):
+		utils.ErrorResponse(w, err.Error(), http.StatusForbidden)
+	case strings.Contains(err.Error(), "not found"):
+		utils.ErrorResponse(w, err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "emoji"), strings.Contains(err.Error(), "Emoji"),
+		strings.Contains(err.Error(), "invalid target type"), strings.Contains(err.Error(), "limit reached"):
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Reaction error: %v", err)
+		utils.ErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
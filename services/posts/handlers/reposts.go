@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"social-network/services/posts/middleware"
+	"social-network/services/posts/utils"
+)
+
+// Repost handles POST /posts/:id/repost requests. Reposting a post twice
+// returns the existing repost.
+func (h *PostHandlers) Repost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	username, ok := middleware.GetUsernameFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := repostPostID(r)
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.postService.Repost(postID, userID, username)
+	if err != nil {
+		repostError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"post": post,
+	})
+}
+
+// Unrepost handles DELETE /posts/:id/repost requests, where :id is the
+// original or the repost
+func (h *PostHandlers) Unrepost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := repostPostID(r)
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.postService.Unrepost(postID, userID); err != nil {
+		repostError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"message": "Repost removed successfully",
+	})
+}
+
+// repostPostID extracts the post ID from a /posts/:id/repost path
+func repostPostID(r *http.Request) (int, error) {
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/posts/"), "/repost")
+	return strconv.Atoi(path)
+}
+
+// repostError writes the response for a repost service error
+func repostError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "access denied") || strings.Contains(err.Error(), "unauthorized") {
+		utils.ErrorResponse(w, err.Error(), http.StatusForbidden)
+	} else if strings.Contains(err.Error(), "not found") {
+		utils.ErrorResponse(w, err.Error(), http.StatusNotFound)
+	} else {
+		utils.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+	}
+}
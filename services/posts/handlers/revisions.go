@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"social-network/services/posts/middleware"
+	"social-network/services/posts/utils"
+)
+
+// GetPostRevisions handles GET /posts/:id/revisions requests
+func (h *PostHandlers) GetPostRevisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Extract post ID from URL path
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/posts/"), "/revisions")
+	postID, err := strconv.Atoi(path)
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := h.postService.GetPostRevisions(postID, userID)
+	if err != nil {
+		revisionError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"revisions": revisions,
+	})
+}
+
+// PurgePostRevisions handles DELETE /posts/:id/revisions requests
+func (h *PostHandlers) PurgePostRevisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Extract post ID from URL path
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/posts/"), "/revisions")
+	postID, err := strconv.Atoi(path)
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	removed, err := h.postService.PurgePostRevisions(postID, userID)
+	if err != nil {
+		revisionError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"message": "Edit history deleted successfully",
+		"removed": removed,
+	})
+}
+
+// GetCommentRevisions handles GET /comments/:id/revisions requests
+func (h *PostHandlers) GetCommentRevisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Extract comment ID from URL path
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/comments/"), "/revisions")
+	commentID, err := strconv.Atoi(path)
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := h.postService.GetCommentRevisions(commentID, userID)
+	if err != nil {
+		revisionError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"revisions": revisions,
+	})
+}
+
+// PurgeCommentRevisions handles DELETE /comments/:id/revisions requests
+func (h *PostHandlers) PurgeCommentRevisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Extract comment ID from URL path
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/comments/"), "/revisions")
+	commentID, err := strconv.Atoi(path)
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	removed, err := h.postService.PurgeCommentRevisions(commentID, userID)
+	if err != nil {
+		revisionError(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"message": "Edit history deleted successfully",
+		"removed": removed,
+	})
+}
+
+// revisionError writes the error response for a failed history request
+func revisionError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "access denied") || strings.Contains(err.Error(), "unauthorized") {
+		utils.ErrorResponse(w, err.Error(), http.StatusForbidden)
+	} else if strings.Contains(err.Error(), "not found") {
+		utils.ErrorResponse(w, err.Error(), http.StatusNotFound)
+	} else {
+		utils.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+	}
+}
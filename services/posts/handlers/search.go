@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"social-network/services/posts/models"
+	"social-network/services/posts/utils"
+)
+
+// searchByRelevance serves GET /posts/search with sort=relevance (the default)
+func (h *PostHandlers) searchByRelevance(w http.ResponseWriter, r *http.Request, userID int, query string) {
+	if r.URL.Query().Get("since") != "" {
+		utils.ErrorResponse(w, "since is only supported with sort=latest", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := parsePostsLimit(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var cursor *models.SearchCursor
+	if before := r.URL.Query().Get("before"); before != "" {
+		cursor, err = models.DecodeSearchCursor(before)
+		if err != nil {
+			utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	posts, next, err := h.postService.SearchPosts(userID, query, cursor, limit)
+	if err != nil {
+		searchError(w, userID, err)
+		return
+	}
+
+	var nextCursor *string
+	if next != nil {
+		encoded := next.Encode()
+		nextCursor = &encoded
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"posts":       posts,
+		"has_more":    next != nil,
+		"next_cursor": nextCursor,
+	})
+}
+
+// searchLatest serves GET /posts/search with sort=latest
+func (h *PostHandlers) searchLatest(w http.ResponseWriter, r *http.Request, userID int, query string) {
+	page, err := parsePostPage(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	posts, hasMore, err := h.postService.SearchPostsLatest(userID, query, page)
+	if err != nil {
+		searchError(w, userID, err)
+		return
+	}
+
+	utils.SuccessResponse(w, postPageResponse(posts, hasMore, page))
+}
+
+// searchError maps search errors to HTTP status codes
+func searchError(w http.ResponseWriter, userID int, err error) {
+	if strings.Contains(err.Error(), "search query") {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("SearchPosts error for user %d: %v", userID, err)
+	utils.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"social-network/services/posts/middleware"
+	"social-network/services/posts/services"
+	"social-network/services/posts/utils"
+)
+
+// defaultTrendingLimit is the trending list size when no limit is given
+const defaultTrendingLimit = 10
+
+// GetPostsByTag handles GET /posts/tags/:tag[?limit=&before=&since=] requests
+func (h *PostHandlers) GetPostsByTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authenticated user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Extract tag from URL path, with or without the #
+	tag := strings.TrimPrefix(r.URL.Path, "/posts/tags/")
+	normalized, err := utils.NormalizeTag(tag)
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid tag", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePostPage(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	posts, hasMore, err := h.postService.GetPostsByTag(userID, normalized, page)
+	if err != nil {
+		log.Printf("GetPostsByTag error for tag %q: %v", normalized, err)
+		utils.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := postPageResponse(posts, hasMore, page)
+	response["tag"] = normalized
+	utils.SuccessResponse(w, response)
+}
+
+// TrendingTags handles GET /posts/tags/trending[?limit=] requests
+func (h *PostHandlers) TrendingTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := defaultTrendingLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n <= 0 {
+			utils.ErrorResponse(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, services.MaxTrendingTags)
+	}
+
+	tags, err := h.postService.TrendingTags(limit)
+	if err != nil {
+		log.Printf("TrendingTags error: %v", err)
+		utils.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"tags": tags,
+	})
+}
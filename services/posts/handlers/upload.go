@@ -1,34 +1,52 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"io"
+	"log"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
 	"strings"
-	"time"
 
+	"social-network/services/common/imaging"
+	"social-network/services/common/storage"
 	"social-network/services/posts/middleware"
+	"social-network/services/posts/models"
+	"social-network/services/posts/services"
 	"social-network/services/posts/utils"
 )
 
 const (
 	maxUploadSize = 5 << 20 // 5MB
-	uploadDir     = "./uploads/posts"
+	uploadDir     = "posts" // Storage key prefix
 )
 
+// uploadLimits are the image limits of post and comment uploads
+var uploadLimits = imaging.Limits{
+	MaxBytes:     maxUploadSize,
+	MaxDimension: imaging.DefaultLimits.MaxDimension,
+	MaxPixels:    imaging.DefaultLimits.MaxPixels,
+	MaxGIFPixels: imaging.DefaultLimits.MaxGIFPixels,
+}
+
 // UploadHandlers handles file upload requests
-type UploadHandlers struct{}
+type UploadHandlers struct {
+	postService *services.PostService
+	store       storage.Storage
+}
 
 // NewUploadHandlers creates a new upload handlers instance
-func NewUploadHandlers() *UploadHandlers {
-	// Ensure upload directory exists
-	os.MkdirAll(uploadDir, os.ModePerm)
-	return &UploadHandlers{}
+func NewUploadHandlers(postService *services.PostService, store storage.Storage) *UploadHandlers {
+	return &UploadHandlers{
+		postService: postService,
+		store:       store,
+	}
 }
 
-// UploadImage handles POST /upload/image requests
+// UploadImage handles POST /upload/image requests. The upload is recorded
+// as media: the returned media_id is what posts and comments attach, with
+// an optional alt_text form field.
 func (h *UploadHandlers) UploadImage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -54,47 +72,72 @@ func (h *UploadHandlers) UploadImage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get the file from form
-	file, fileHeader, err := r.FormFile("image")
+	file, _, err := r.FormFile("image")
 	if err != nil {
 		utils.ErrorResponse(w, "No file provided", http.StatusBadRequest)
 		return
 	}
 	defer file.Close()
 
-	// Validate file type
-	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
-	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".gif" {
-		utils.ErrorResponse(w, "Invalid file type. Only JPG, PNG, and GIF allowed", http.StatusBadRequest)
+	// Identify, check and re-encode the image (stripping its metadata)
+	img, err := imaging.Process(file, uploadLimits)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Generate unique filename
-	timestamp := time.Now().Unix()
-	filename := fmt.Sprintf("%d_%d%s", userID, timestamp, ext)
-	filePath := filepath.Join(uploadDir, filename)
-
-	// Create the file on disk
-	dst, err := os.Create(filePath)
+	// Save it with its thumbnails under a unique name
+	name, err := storage.RandomName()
 	if err != nil {
 		utils.ErrorResponse(w, "Failed to save file", http.StatusInternalServerError)
 		return
 	}
-	defer dst.Close()
-
-	// Copy file content
-	if _, err := io.Copy(dst, file); err != nil {
+	key, err := img.Save(r.Context(), h.store, uploadDir, name)
+	if err != nil {
+		log.Printf("Error saving upload: %v", err)
 		utils.ErrorResponse(w, "Failed to save file", http.StatusInternalServerError)
 		return
 	}
 
 	// Return the file path (relative, without leading slash)
-	relativePath := fmt.Sprintf("uploads/posts/%s", filename)
-	utils.SuccessResponse(w, map[string]string{
+	relativePath := strings.TrimPrefix(storage.Path(key), "/")
+	hash := sha256.Sum256(img.Data)
+	media := &models.Media{
+		UserID:      userID,
+		Path:        relativePath,
+		ContentType: img.ContentType,
+		Width:       img.Width,
+		Height:      img.Height,
+		Size:        int64(len(img.Data)),
+		ContentHash: hex.EncodeToString(hash[:]),
+		AltText:     r.FormValue("alt_text"),
+	}
+	if err := h.postService.RecordUpload(media); err != nil {
+		imaging.Remove(r.Context(), h.store, key)
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
 		"image_path": relativePath,
-		"filename":   filename,
+		"image_url":  media.URL,
+		"filename":   path.Base(key),
+		"media_id":   media.ID,
+		"media":      media,
 	})
 }
 
+// CanViewUpload is the storage.Authorizer of post and comment images: they
+// are served to whoever can see the post using them, or to their uploader
+// until then
+func (h *UploadHandlers) CanViewUpload(r *http.Request, key string) (bool, error) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		return false, nil
+	}
+	return h.postService.CanViewUpload(key, userID)
+}
+
 // DeleteImage handles DELETE /upload/image requests
 func (h *UploadHandlers) DeleteImage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "DELETE" {
@@ -103,7 +146,7 @@ func (h *UploadHandlers) DeleteImage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get authenticated user ID from context
-	_, ok := middleware.GetUserIDFromContext(r)
+	userID, ok := middleware.GetUserIDFromContext(r)
 	if !ok {
 		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -117,17 +160,20 @@ func (h *UploadHandlers) DeleteImage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract filename from path
-	filename := filepath.Base(imagePath)
-	fullPath := filepath.Join(uploadDir, filename)
+	key := uploadDir + "/" + path.Base(imagePath)
 
-	// Check if file exists
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-		utils.ErrorResponse(w, "File not found", http.StatusNotFound)
+	// Only the uploader can delete a file, and only while nothing uses it
+	if err := h.postService.DeleteUpload(key, userID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			utils.ErrorResponse(w, "File not found", http.StatusNotFound)
+		} else {
+			utils.ErrorResponse(w, "Failed to delete file", http.StatusInternalServerError)
+		}
 		return
 	}
 
-	// Delete the file
-	if err := os.Remove(fullPath); err != nil {
+	// Delete the file and its thumbnails
+	if err := imaging.Remove(r.Context(), h.store, key); err != nil {
 		utils.ErrorResponse(w, "Failed to delete file", http.StatusInternalServerError)
 		return
 	}
@@ -5,15 +5,29 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 
 	"social-network/services/common/authcache"
+	"social-network/services/common/storage"
 	"social-network/services/posts/handlers"
 	"social-network/services/posts/middleware"
+	"social-network/services/posts/ranking"
 	"social-network/services/posts/services"
 )
 
+// schedulerInterval is how often due scheduled posts are published
+const schedulerInterval = 30 * time.Second
+
+// Unattached uploads are removed once they're mediaGracePeriod old,
+// checked every mediaCollectInterval
+const (
+	mediaGracePeriod     = 24 * time.Hour
+	mediaCollectInterval = time.Hour
+)
+
 func main() {
 	// Get database path from environment variable or use default
 	dbPath := os.Getenv("DATABASE_PATH")
@@ -34,12 +48,25 @@ func main() {
 		authServiceURL = "http://auth-service:8081"
 	}
 
+	rankConfig, err := ranking.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid ranking configuration: %v", err)
+	}
+
+	// Uploaded images live in the shared storage
+	store, err := storage.FromEnv()
+	if err != nil {
+		log.Fatalf("Failed to set up upload storage: %v", err)
+	}
+
 	// Initialize services
-	postService := services.NewPostService(database)
+	postService := services.NewPostService(database, rankConfig, store)
+	postService.StartScheduler(schedulerInterval)
+	postService.StartMediaCollector(mediaCollectInterval, mediaGracePeriod)
 
 	// Initialize handlers
 	postHandlers := handlers.NewPostHandlers(postService)
-	uploadHandlers := handlers.NewUploadHandlers()
+	uploadHandlers := handlers.NewUploadHandlers(postService, store)
 
 	// Initialize middleware
 	rateLimiter := middleware.NewRateLimiter()
@@ -53,9 +80,8 @@ func main() {
 	// Health check (no auth, no rate limiting)
 	mux.HandleFunc("/health", handlers.HealthHandler)
 
-	// Static file server for uploaded images
-	fs := http.FileServer(http.Dir("./uploads"))
-	mux.Handle("/uploads/", http.StripPrefix("/uploads/", fs))
+	// Uploaded images, to whoever can see the post using them
+	mux.Handle(storage.PathPrefix, storage.AuthorizedHandler(store, authMiddleware, uploadHandlers.CanViewUpload))
 
 	// Post endpoints
 	mux.Handle("/posts", authMiddleware(rateLimiter.RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -70,13 +96,108 @@ func main() {
 	// Feed endpoint
 	mux.Handle("/posts/feed", authMiddleware(http.HandlerFunc(postHandlers.GetFeed)))
 
+	// Drafts and scheduled posts
+	mux.Handle("/posts/drafts", authMiddleware(http.HandlerFunc(postHandlers.GetDrafts)))
+	mux.Handle("/posts/drafts/", authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/schedule"):
+			postHandlers.ScheduleDraft(w, r)
+		case strings.HasSuffix(r.URL.Path, "/publish"):
+			postHandlers.PublishDraft(w, r)
+		case r.Method == "PUT":
+			postHandlers.UpdateDraft(w, r)
+		case r.Method == "DELETE":
+			postHandlers.CancelDraft(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Posts mentioning the authenticated user
+	mux.Handle("/posts/mentions", authMiddleware(http.HandlerFunc(postHandlers.GetMentions)))
+
+	// Bookmarks of the authenticated user and their collections
+	mux.Handle("/posts/bookmarks", authMiddleware(http.HandlerFunc(postHandlers.GetBookmarks)))
+	mux.Handle("/posts/bookmarks/collections", authMiddleware(rateLimiter.RateLimit(http.HandlerFunc(postHandlers.BookmarkCollections))))
+	mux.Handle("/posts/bookmarks/collections/", authMiddleware(rateLimiter.RateLimit(http.HandlerFunc(postHandlers.BookmarkCollection))))
+
+	// Feed impressions for the ranked feed
+	mux.Handle("/posts/seen", authMiddleware(rateLimiter.RateLimit(http.HandlerFunc(postHandlers.MarkPostsSeen))))
+
 	// Search endpoint
 	mux.Handle("/posts/search", authMiddleware(http.HandlerFunc(postHandlers.SearchPosts)))
 
 	// Group posts endpoint
 	mux.Handle("/posts/group/", authMiddleware(http.HandlerFunc(postHandlers.GetGroupPosts)))
 
+	// Hashtag endpoints. The exact trending path wins over the tag pages;
+	// the #trending tag page is reached as /posts/tags/%23trending.
+	mux.Handle("/posts/tags/trending", authMiddleware(http.HandlerFunc(postHandlers.TrendingTags)))
+	mux.Handle("/posts/tags/", authMiddleware(http.HandlerFunc(postHandlers.GetPostsByTag)))
+
 	mux.Handle("/posts/", authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Top-level comments of a post
+		if strings.HasSuffix(r.URL.Path, "/comments") {
+			postHandlers.GetPostComments(w, r)
+			return
+		}
+
+		// Voting in the poll of a post, and its results
+		if strings.HasSuffix(r.URL.Path, "/poll/vote") {
+			switch r.Method {
+			case "POST":
+				postHandlers.Vote(w, r)
+			case "DELETE":
+				postHandlers.RetractVote(w, r)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/poll") {
+			postHandlers.GetPoll(w, r)
+			return
+		}
+
+		// Reposting a post, and undoing it
+		if strings.HasSuffix(r.URL.Path, "/repost") {
+			switch r.Method {
+			case "POST":
+				postHandlers.Repost(w, r)
+			case "DELETE":
+				postHandlers.Unrepost(w, r)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		// Bookmarking a post, and removing the bookmark
+		if strings.HasSuffix(r.URL.Path, "/bookmark") {
+			switch r.Method {
+			case "POST":
+				postHandlers.Bookmark(w, r)
+			case "DELETE":
+				postHandlers.Unbookmark(w, r)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		// Edit history of a post
+		if strings.HasSuffix(r.URL.Path, "/revisions") {
+			switch r.Method {
+			case "GET":
+				postHandlers.GetPostRevisions(w, r)
+			case "DELETE":
+				postHandlers.PurgePostRevisions(w, r)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
 		switch r.Method {
 		case "GET":
 			postHandlers.GetPost(w, r)
@@ -101,8 +222,25 @@ func main() {
 		}
 	}))))
 
-	// Comment by ID endpoints (update, delete)
+	// Comment by ID endpoints (update, delete, replies, edit history)
 	mux.Handle("/comments/", authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/replies") {
+			postHandlers.GetReplies(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/revisions") {
+			switch r.Method {
+			case "GET":
+				postHandlers.GetCommentRevisions(w, r)
+			case "DELETE":
+				postHandlers.PurgeCommentRevisions(w, r)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
 		switch r.Method {
 		case "PUT":
 			postHandlers.UpdateComment(w, r)
@@ -113,6 +251,20 @@ func main() {
 		}
 	})))
 
+	// Reaction endpoints (add, remove, list)
+	mux.Handle("/reactions", authMiddleware(rateLimiter.RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			postHandlers.AddReaction(w, r)
+		case "DELETE":
+			postHandlers.RemoveReaction(w, r)
+		case "GET":
+			postHandlers.GetReactions(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+
 	// Upload endpoints
 	mux.Handle("/upload/image", authMiddleware(rateLimiter.RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Bookmark limits
+const (
+	MaxBookmarkCollections       = 100 // Per user
+	MaxBookmarkCollectionNameLen = 50
+)
+
+// Bookmark is a post the user saved for later. Only its owner sees it.
+type Bookmark struct {
+	ID           int       `json:"id"`
+	UserID       int       `json:"user_id"`
+	PostID       int       `json:"post_id"`
+	CollectionID *int      `json:"collection_id"` // nil if not filed in a collection
+	CreatedAt    time.Time `json:"created_at"`
+	Post         *Post     `json:"post,omitempty"` // Listings only
+}
+
+// BookmarkCollection is a named group of a user's bookmarks
+type BookmarkCollection struct {
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	CreatedAt     time.Time `json:"created_at"`
+	BookmarkCount int       `json:"bookmark_count"` // Bookmarks of posts the user can still see
+}
+
+// BookmarkRequest is the body of POST /posts/:id/bookmark
+type BookmarkRequest struct {
+	CollectionID *int `json:"collection_id,omitempty"` // File it in this collection
+}
+
+// BookmarkCollectionRequest creates or renames a collection
+type BookmarkCollectionRequest struct {
+	Name string `json:"name"`
+}
+
+// BookmarkPage selects one page of a user's bookmarks, newest first
+type BookmarkPage struct {
+	CollectionID *int        // Only this collection's bookmarks (nil = all)
+	Before       *PostCursor // Older than this position (next page)
+	Limit        int
+}
+
+// BookmarkCursorFor returns the position of a bookmark in the
+// (created_at DESC, id DESC) ordering of a listing
+func BookmarkCursorFor(bookmark *Bookmark) PostCursor {
+	return PostCursor{CreatedAt: bookmark.CreatedAt.UTC().Format(TimeLayout), ID: bookmark.ID}
+}
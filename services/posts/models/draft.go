@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Post statuses. Only published posts are visible to anyone but the author.
+const (
+	PostStatusPublished = "published"
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled" // Draft with a publish_at
+)
+
+// ScheduleRequest sets when a draft is published; a null publish_at turns
+// a scheduled post back into a draft
+type ScheduleRequest struct {
+	PublishAt *time.Time `json:"publish_at"`
+}
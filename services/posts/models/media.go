@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Media limits
+const (
+	MaxPostMedia     = 10
+	MaxCommentMedia  = 4
+	MaxAltTextLength = 1000
+)
+
+// Media is an uploaded image. It belongs to its uploader until it is
+// attached to a post or comment.
+type Media struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	Path        string    `json:"path"` // Relative, like image_path
+	ContentType string    `json:"content_type"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Size        int64     `json:"size"`         // Bytes
+	ContentHash string    `json:"content_hash"` // Hex SHA-256
+	AltText     string    `json:"alt_text"`
+	Position    int       `json:"position"` // Order within the post or comment
+	CreatedAt   time.Time `json:"created_at"`
+
+	URL        string         `json:"url"`        // Signed, expires
+	Thumbnails map[int]string `json:"thumbnails"` // Signed URLs keyed by longest side
+}
+
+// MediaAttachment references an upload in a CreatePostRequest or
+// CreateCommentRequest. The order of the list is the display order.
+type MediaAttachment struct {
+	ID      int     `json:"id"`
+	AltText *string `json:"alt_text,omitempty"` // Replaces the alt text given at upload
+}
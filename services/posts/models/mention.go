@@ -0,0 +1,30 @@
+package models
+
+// Mention target types
+const (
+	MentionTargetPost    = "post"
+	MentionTargetComment = "comment"
+)
+
+// Fields of a post a mention can appear in
+const (
+	MentionFieldTitle   = "title"
+	MentionFieldContent = "content"
+)
+
+// MentionedUser is a user resolved from an @username
+type MentionedUser struct {
+	ID       int
+	Username string
+}
+
+// Mention is an @username in a post or comment, linking to the user.
+// Start and End delimit "@username" in the field as returned by the API,
+// in UTF-16 code units (JavaScript string indices).
+type Mention struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Field    string `json:"field"` // "title" or "content"
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Poll limits
+const (
+	MinPollOptions      = 2
+	MaxPollOptions      = 10
+	MaxPollOptionLength = 100
+)
+
+// Poll is a question attached to a post. Vote counts are nil when the
+// viewer isn't allowed to see the results yet.
+type Poll struct {
+	ID             int           `json:"id"`
+	PostID         int           `json:"post_id"`
+	MultipleChoice bool          `json:"multiple_choice"`
+	Anonymous      bool          `json:"anonymous"`    // Nobody sees who voted for what
+	HideResults    bool          `json:"hide_results"` // Results hidden until the viewer votes or the poll closes
+	ClosesAt       *time.Time    `json:"closes_at"`    // nil if the poll never closes
+	CreatedAt      time.Time     `json:"created_at"`
+	Options        []*PollOption `json:"options"`
+
+	Closed         bool  `json:"closed"`
+	ResultsVisible bool  `json:"results_visible"`
+	VoterCount     *int  `json:"voter_count,omitempty"` // People who voted
+	ViewerVotes    []int `json:"viewer_votes"`          // Option IDs the requesting user chose
+}
+
+// PollOption is one answer of a poll
+type PollOption struct {
+	ID        int       `json:"id"`
+	Position  int       `json:"position"`
+	Text      string    `json:"text"`
+	VoteCount *int      `json:"vote_count,omitempty"`
+	Voters    []*Author `json:"voters,omitempty"` // Poll results endpoint only, never for anonymous polls
+}
+
+// PollRequest is the poll part of a CreatePostRequest
+type PollRequest struct {
+	Options        []string   `json:"options"`
+	MultipleChoice bool       `json:"multiple_choice"`
+	Anonymous      bool       `json:"anonymous"`
+	HideResults    bool       `json:"hide_results"`
+	ClosesAt       *time.Time `json:"closes_at,omitempty"`
+}
+
+// VoteRequest replaces the user's vote on a poll
+type VoteRequest struct {
+	OptionIDs []int `json:"option_ids"`
+}
@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
 
 // Author represents post author information
 type Author struct {
@@ -13,26 +18,59 @@ type Author struct {
 
 // Post represents a user post
 type Post struct {
-	ID           int       `json:"id"`
-	UserID       int       `json:"user_id"`
-	GroupID      *int      `json:"group_id,omitempty"`
-	Title        *string   `json:"title,omitempty"`
-	Content      string    `json:"content"`
-	ImagePath    *string   `json:"image_path,omitempty"`
-	PrivacyLevel string    `json:"privacy_level"` // "public", "private", "almost_private"
-	CreatedAt    time.Time `json:"created_at"`
-	Author       *Author   `json:"author,omitempty"` // Author information for feed
+	ID           int        `json:"id"`
+	UserID       int        `json:"user_id"`
+	GroupID      *int       `json:"group_id,omitempty"`
+	Title        *string    `json:"title,omitempty"`
+	Content      string     `json:"content"`
+	ImagePath    *string    `json:"image_path,omitempty"`
+	ImageURL     string     `json:"image_url,omitempty"` // Signed URL of image_path, expires
+	PrivacyLevel string     `json:"privacy_level"`       // "public", "private", "almost_private"
+	CreatedAt    time.Time  `json:"created_at"`
+	EditedAt     *time.Time `json:"edited_at"`            // Latest edit, nil if never edited
+	Status       string     `json:"status"`               // "published", "draft", "scheduled"
+	PublishAt    *time.Time `json:"publish_at,omitempty"` // Scheduled posts only
+	Author       *Author    `json:"author,omitempty"`     // Author information for feed
+
+	ShareType             string `json:"share_type,omitempty"`              // "repost" or "quote" for shares
+	SharedPostID          *int   `json:"shared_post_id,omitempty"`          // The shared post, nil once deleted
+	SharedPost            *Post  `json:"shared_post,omitempty"`             // Only if the viewer can see it
+	SharedPostUnavailable bool   `json:"shared_post_unavailable,omitempty"` // Share of a post the viewer can't see
+	RepostCount           int    `json:"repost_count"`
+
+	Poll  *Poll    `json:"poll,omitempty"`
+	Media []*Media `json:"media"` // Ordered attachments
+
+	Reactions       []ReactionCount `json:"reactions"`        // Per-emoji counts, most used first
+	ViewerReactions []string        `json:"viewer_reactions"` // Emoji the requesting user added
+	Mentions        []Mention       `json:"mentions"`         // @username spans in title and content
+	Bookmarked      bool            `json:"bookmarked"`       // The requesting user bookmarked it
+
+	Highlight *Highlight `json:"highlight,omitempty"` // Search results only
 }
 
 // Comment represents a comment on a post
 type Comment struct {
-	ID        int       `json:"id"`
-	PostID    int       `json:"post_id"`
-	UserID    int       `json:"user_id"`
-	Content   string    `json:"content"`
-	ImagePath *string   `json:"image_path,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	Author    *Author   `json:"author,omitempty"` // Author information
+	ID        int        `json:"id"`
+	PostID    int        `json:"post_id"`
+	UserID    int        `json:"user_id"`
+	Content   string     `json:"content"`
+	ImagePath *string    `json:"image_path,omitempty"`
+	ImageURL  string     `json:"image_url,omitempty"` // Signed URL of image_path, expires
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at"`        // Latest edit, nil if never edited
+	Author    *Author    `json:"author,omitempty"` // Author information
+
+	ParentCommentID *int `json:"parent_comment_id"` // nil for top-level comments
+	Depth           int  `json:"depth"`             // 0 for top-level comments
+	ReplyCount      int  `json:"reply_count"`       // Direct replies, tombstones included
+	Deleted         bool `json:"deleted"`           // Tombstone kept for its replies; no content or author
+
+	Reactions       []ReactionCount `json:"reactions"`        // Per-emoji counts, most used first
+	ViewerReactions []string        `json:"viewer_reactions"` // Emoji the requesting user added
+	Mentions        []Mention       `json:"mentions"`         // @username spans in content
+
+	Media []*Media `json:"media"` // Ordered attachments
 }
 
 // PostViewer represents a user who can view an "almost_private" post
@@ -51,6 +89,14 @@ type CreatePostRequest struct {
 	ImagePath    *string `json:"image_path,omitempty"`
 	PrivacyLevel string  `json:"privacy_level"`     // "public", "private", "almost_private"
 	Viewers      []int   `json:"viewers,omitempty"` // User IDs for "almost_private" posts
+
+	Draft     bool       `json:"draft,omitempty"`      // Save without publishing
+	PublishAt *time.Time `json:"publish_at,omitempty"` // Publish later (scheduled post)
+
+	QuotedPostID *int `json:"quoted_post_id,omitempty"` // Quote this post
+
+	Poll  *PollRequest      `json:"poll,omitempty"`
+	Media []MediaAttachment `json:"media,omitempty"` // Uploads to attach, in order
 }
 
 // UpdatePostRequest represents the request to update a post
@@ -64,9 +110,11 @@ type UpdatePostRequest struct {
 
 // CreateCommentRequest represents the request to create a comment
 type CreateCommentRequest struct {
-	PostID    int     `json:"post_id"`
-	Content   string  `json:"content"`
-	ImagePath *string `json:"image_path,omitempty"`
+	PostID          int               `json:"post_id"`
+	ParentCommentID *int              `json:"parent_comment_id,omitempty"` // Set to reply to a comment
+	Content         string            `json:"content"`
+	ImagePath       *string           `json:"image_path,omitempty"`
+	Media           []MediaAttachment `json:"media,omitempty"` // Uploads to attach, in order
 }
 
 // UpdateCommentRequest represents the request to update a comment
@@ -74,3 +122,54 @@ type UpdateCommentRequest struct {
 	Content   string  `json:"content"`
 	ImagePath *string `json:"image_path,omitempty"`
 }
+
+// TimeLayout is how post timestamps are stored (UTC, second precision)
+const TimeLayout = "2006-01-02 15:04:05"
+
+// PostPage selects one page of a post list, newest first.
+// Before and Since are mutually exclusive.
+type PostPage struct {
+	Before   *PostCursor // Older than this position (next page)
+	Since    *PostCursor // Newer than this position (polling for new posts)
+	AuthorID int         // Only this author's posts (feed only, 0 = everyone)
+	Limit    int
+}
+
+// PostCursor is a position in the (created_at DESC, id DESC) ordering.
+// Clients only ever see it encoded, as an opaque string.
+type PostCursor struct {
+	CreatedAt string `json:"t"` // As stored, "YYYY-MM-DD HH:MM:SS"
+	ID        int    `json:"id"`
+}
+
+// ErrInvalidCursor is returned when a cursor string can't be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorFor returns the position of a post
+func CursorFor(post *Post) PostCursor {
+	return PostCursor{CreatedAt: post.CreatedAt.UTC().Format(TimeLayout), ID: post.ID}
+}
+
+// Encode returns the opaque string form of the cursor
+func (c PostCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodePostCursor parses a cursor produced by Encode
+func DecodePostCursor(s string) (*PostCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c PostCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID < 0 {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := time.Parse(TimeLayout, c.CreatedAt); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
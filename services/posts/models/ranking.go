@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+// Feed modes accepted by GET /posts/feed?mode=
+const (
+	FeedModeLatest = "latest" // Chronological (default)
+	FeedModeRanked = "ranked" // Scored by the ranking package
+)
+
+// RankCandidate is a post considered for the ranked feed, with the
+// viewer-specific inputs to its score. Counts only include activity up to
+// the snapshot time so every page of one ranking sees the same values.
+type RankCandidate struct {
+	Post          *Post
+	ViewerFollows bool // The viewer follows the author
+	FollowsViewer bool // The author follows the viewer
+	ChatMessages  int  // Direct messages exchanged between viewer and author
+	Reactions     int
+	Comments      int
+	InGroup       bool // Posted in a group the viewer belongs to
+	Seen          bool // The viewer has already seen the post
+	Score         float64
+}
+
+// RankCursor is a position in a ranked feed: the snapshot the ranking was
+// computed at and how many posts were already returned
+type RankCursor struct {
+	At     string `json:"at"` // Snapshot time, TimeLayout in UTC
+	Offset int    `json:"o"`
+}
+
+// Encode returns the opaque string form of the cursor
+func (c RankCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Time returns the snapshot time
+func (c RankCursor) Time() time.Time {
+	t, _ := time.Parse(TimeLayout, c.At)
+	return t
+}
+
+// DecodeRankCursor parses a cursor produced by RankCursor.Encode
+func DecodeRankCursor(s string) (*RankCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c RankCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Offset < 0 {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := time.Parse(TimeLayout, c.At); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// SeenRequest represents the request body for marking posts as seen
+type SeenRequest struct {
+	PostIDs []int `json:"post_ids"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Reaction target types
+const (
+	ReactionTargetPost    = "post"
+	ReactionTargetComment = "comment"
+)
+
+// Reaction represents one user's emoji on a post or comment
+type Reaction struct {
+	TargetType string    `json:"target_type"`
+	TargetID   int       `json:"target_id"`
+	UserID     int       `json:"user_id"`
+	Emoji      string    `json:"emoji"`
+	CreatedAt  time.Time `json:"created_at"`
+	Author     *Author   `json:"author,omitempty"` // The reacting user
+}
+
+// ReactionCount is the number of reactions with one emoji
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+}
+
+// ReactionSummary aggregates the reactions on one target
+type ReactionSummary struct {
+	Counts          []ReactionCount
+	ViewerReactions []string
+}
+
+// ReactionRequest represents the request to add or remove a reaction
+type ReactionRequest struct {
+	TargetType string `json:"target_type"` // "post" or "comment"
+	TargetID   int    `json:"target_id"`
+	Emoji      string `json:"emoji"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// PostRevision is a post as it was before one of its edits
+type PostRevision struct {
+	ID           int       `json:"id"`
+	PostID       int       `json:"post_id"`
+	Title        *string   `json:"title,omitempty"`
+	Content      string    `json:"content"`
+	ImagePath    *string   `json:"image_path,omitempty"`
+	PrivacyLevel string    `json:"privacy_level"`
+	ReplacedAt   time.Time `json:"replaced_at"` // When the edit replaced this version
+}
+
+// CommentRevision is a comment as it was before one of its edits
+type CommentRevision struct {
+	ID         int       `json:"id"`
+	CommentID  int       `json:"comment_id"`
+	Content    string    `json:"content"`
+	ImagePath  *string   `json:"image_path,omitempty"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
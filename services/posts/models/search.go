@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// Search result orders accepted by GET /posts/search?sort=
+const (
+	SearchSortRelevance = "relevance" // bm25, best match first (default)
+	SearchSortLatest    = "latest"    // Newest first, keyset paginated like the feed
+)
+
+// Highlight holds the matching parts of a search result as HTML-escaped
+// text with matches wrapped in <mark></mark>
+type Highlight struct {
+	Title   string `json:"title,omitempty"`
+	Snippet string `json:"snippet"` // Excerpt of the content around the matches
+}
+
+// SearchCursor is a position in relevance-ordered search results
+type SearchCursor struct {
+	Offset int `json:"o"`
+}
+
+// Encode returns the opaque string form of the cursor
+func (c SearchCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeSearchCursor parses a cursor produced by SearchCursor.Encode
+func DecodeSearchCursor(s string) (*SearchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c SearchCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Offset < 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// Markers db puts around matches in raw highlights. Control characters can't
+// occur in sanitized post text, so they survive escaping unambiguously.
+const (
+	HighlightStart = "\x02"
+	HighlightEnd   = "\x03"
+)
@@ -0,0 +1,7 @@
+package models
+
+// Share types. A repost shares a post as it is; a quote adds commentary.
+const (
+	ShareTypeRepost = "repost"
+	ShareTypeQuote  = "quote"
+)
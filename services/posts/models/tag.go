@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// TagUse is one hashtag on a public post, the input to trending
+type TagUse struct {
+	Tag      string
+	AuthorID int
+	TaggedAt time.Time
+}
+
+// TrendingTag is a hashtag and how fast it is being used
+type TrendingTag struct {
+	Tag   string  `json:"tag"`
+	Score float64 `json:"score"` // Time-decayed uses, see services.TrendingTags
+	Posts int     `json:"posts"` // Public posts with the tag within the window
+}
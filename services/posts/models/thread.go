@@ -0,0 +1,17 @@
+package models
+
+// MaxCommentDepth is the deepest a reply can be nested; top-level comments
+// are depth 0, so threads are at most MaxCommentDepth+1 levels
+const MaxCommentDepth = 3
+
+// CommentPage selects one page of a comment thread, oldest first
+type CommentPage struct {
+	After *PostCursor // Newer than this position (next page)
+	Limit int
+}
+
+// CommentCursorFor returns the position of a comment in the
+// (created_at ASC, id ASC) ordering of its thread
+func CommentCursorFor(comment *Comment) PostCursor {
+	return PostCursor{CreatedAt: comment.CreatedAt.UTC().Format(TimeLayout), ID: comment.ID}
+}
@@ -0,0 +1,17 @@
+package models
+
+// Owners of uploaded files (see the uploads table)
+const (
+	UploadOwnerPost    = "post"
+	UploadOwnerComment = "comment"
+)
+
+// Upload is the ownership of an uploaded file. OwnerType is empty until
+// the file is used by a post or comment; until then only its uploader can
+// see it.
+type Upload struct {
+	Path      string // Storage key
+	UserID    int    // Uploader
+	OwnerType string
+	OwnerID   int
+}
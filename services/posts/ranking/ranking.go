@@ -0,0 +1,230 @@
+package ranking
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"social-network/services/posts/models"
+)
+
+// Config holds the ranked feed settings
+type Config struct {
+	HalfLife      time.Duration // Age at which the recency signal has halved
+	Window        time.Duration // How far back candidates are taken from
+	MaxCandidates int           // Most recent candidates scored per ranking
+
+	// Signal weights. A weight of 0 turns the signal off.
+	RecencyWeight      float64
+	RelationshipWeight float64
+	EngagementWeight   float64
+	GroupWeight        float64
+	UnseenWeight       float64
+}
+
+// DefaultConfig returns the settings used when nothing is configured
+func DefaultConfig() Config {
+	return Config{
+		HalfLife:           12 * time.Hour,
+		Window:             72 * time.Hour,
+		MaxCandidates:      500,
+		RecencyWeight:      3,
+		RelationshipWeight: 2,
+		EngagementWeight:   1.5,
+		GroupWeight:        0.5,
+		UnseenWeight:       1,
+	}
+}
+
+// LoadConfig reads the ranking settings from the environment:
+//
+//	RANK_HALF_LIFE               recency half-life (default 12h)
+//	RANK_WINDOW                  candidate window (default 72h)
+//	RANK_MAX_CANDIDATES          candidates scored per ranking (default 500)
+//	RANK_WEIGHT_RECENCY          (default 3)
+//	RANK_WEIGHT_RELATIONSHIP     (default 2)
+//	RANK_WEIGHT_ENGAGEMENT       (default 1.5)
+//	RANK_WEIGHT_GROUP            (default 0.5)
+//	RANK_WEIGHT_UNSEEN           (default 1)
+func LoadConfig() (Config, error) {
+	cfg := DefaultConfig()
+
+	if v := os.Getenv("RANK_HALF_LIFE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("invalid RANK_HALF_LIFE %q", v)
+		}
+		cfg.HalfLife = d
+	}
+	if v := os.Getenv("RANK_WINDOW"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("invalid RANK_WINDOW %q", v)
+		}
+		cfg.Window = d
+	}
+	if v := os.Getenv("RANK_MAX_CANDIDATES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("invalid RANK_MAX_CANDIDATES %q", v)
+		}
+		cfg.MaxCandidates = n
+	}
+
+	weights := []struct {
+		env string
+		dst *float64
+	}{
+		{"RANK_WEIGHT_RECENCY", &cfg.RecencyWeight},
+		{"RANK_WEIGHT_RELATIONSHIP", &cfg.RelationshipWeight},
+		{"RANK_WEIGHT_ENGAGEMENT", &cfg.EngagementWeight},
+		{"RANK_WEIGHT_GROUP", &cfg.GroupWeight},
+		{"RANK_WEIGHT_UNSEEN", &cfg.UnseenWeight},
+	}
+	for _, w := range weights {
+		if v := os.Getenv(w.env); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+				return cfg, fmt.Errorf("invalid %s %q", w.env, v)
+			}
+			*w.dst = f
+		}
+	}
+
+	return cfg, nil
+}
+
+// ============================================
+// SIGNALS
+// ============================================
+
+// Signal is one input to a post's score. Score returns a value in [0, 1]
+// for the candidate as of the ranking time; it is multiplied by Weight.
+type Signal struct {
+	Name   string
+	Weight float64
+	Score  func(c *models.RankCandidate, at time.Time) float64
+}
+
+// Saturation points: engagement and chat history count fully from here on
+const (
+	engagementSaturation = 100 // Reactions + 2 x comments
+	chatSaturation       = 50  // Messages exchanged
+)
+
+// DefaultSignals returns the built-in signals weighted by cfg
+func DefaultSignals(cfg Config) []Signal {
+	return []Signal{
+		{Name: "recency", Weight: cfg.RecencyWeight, Score: Recency(cfg.HalfLife)},
+		{Name: "relationship", Weight: cfg.RelationshipWeight, Score: Relationship},
+		{Name: "engagement", Weight: cfg.EngagementWeight, Score: Engagement},
+		{Name: "group", Weight: cfg.GroupWeight, Score: GroupMember},
+		{Name: "unseen", Weight: cfg.UnseenWeight, Score: Unseen},
+	}
+}
+
+// Recency decays exponentially with the post's age: 1 when just posted,
+// 0.5 after halfLife, 0.25 after twice that
+func Recency(halfLife time.Duration) func(*models.RankCandidate, time.Time) float64 {
+	return func(c *models.RankCandidate, at time.Time) float64 {
+		age := at.Sub(c.Post.CreatedAt)
+		if age < 0 {
+			age = 0
+		}
+		return math.Exp2(-float64(age) / float64(halfLife))
+	}
+}
+
+// Relationship scores how close the viewer is to the author: following
+// counts most, mutual follows more, and direct messages add the rest
+func Relationship(c *models.RankCandidate, _ time.Time) float64 {
+	var follow float64
+	switch {
+	case c.ViewerFollows && c.FollowsViewer:
+		follow = 1
+	case c.ViewerFollows:
+		follow = 0.6
+	case c.FollowsViewer:
+		follow = 0.3
+	}
+	return 0.7*follow + 0.3*saturate(c.ChatMessages, chatSaturation)
+}
+
+// Engagement grows logarithmically with reactions and comments, comments
+// counting double
+func Engagement(c *models.RankCandidate, _ time.Time) float64 {
+	return saturate(c.Reactions+2*c.Comments, engagementSaturation)
+}
+
+// GroupMember is 1 for posts in a group the viewer belongs to
+func GroupMember(c *models.RankCandidate, _ time.Time) float64 {
+	if c.InGroup {
+		return 1
+	}
+	return 0
+}
+
+// Unseen is 1 for posts the viewer hasn't seen yet
+func Unseen(c *models.RankCandidate, _ time.Time) float64 {
+	if c.Seen {
+		return 0
+	}
+	return 1
+}
+
+// saturate maps n to [0, 1] on a log scale, reaching 1 at max
+func saturate(n, max int) float64 {
+	if n <= 0 {
+		return 0
+	}
+	return math.Min(1, math.Log1p(float64(n))/math.Log1p(float64(max)))
+}
+
+// ============================================
+// RANKER
+// ============================================
+
+// Ranker orders candidates by the weighted sum of its signals
+type Ranker struct {
+	signals []Signal
+}
+
+// NewRanker creates a ranker from signals, e.g. DefaultSignals(cfg)
+func NewRanker(signals []Signal) *Ranker {
+	return &Ranker{signals: signals}
+}
+
+// Score returns the candidate's weighted score as of at
+func (r *Ranker) Score(c *models.RankCandidate, at time.Time) float64 {
+	var score float64
+	for _, signal := range r.signals {
+		if signal.Weight == 0 {
+			continue
+		}
+		score += signal.Weight * signal.Score(c, at)
+	}
+	return score
+}
+
+// Rank scores the candidates as of at and sorts them best first. Equal
+// scores fall back to newest first, so the order only depends on the
+// candidates and at.
+func (r *Ranker) Rank(candidates []*models.RankCandidate, at time.Time) {
+	for _, c := range candidates {
+		c.Score = r.Score(c, at)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.Post.CreatedAt.Equal(b.Post.CreatedAt) {
+			return a.Post.CreatedAt.After(b.Post.CreatedAt)
+		}
+		return a.Post.ID > b.Post.ID
+	})
+}
@@ -0,0 +1,219 @@
+package ranking
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"social-network/services/posts/models"
+)
+
+// at is the ranking time used throughout
+var at = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func candidate(id int, age time.Duration) *models.RankCandidate {
+	return &models.RankCandidate{Post: &models.Post{ID: id, CreatedAt: at.Add(-age)}}
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestRecency(t *testing.T) {
+	halfLife := 12 * time.Hour
+	recency := Recency(halfLife)
+
+	tests := []struct {
+		name string
+		age  time.Duration
+		want float64
+	}{
+		{"just posted", 0, 1},
+		{"one half-life", halfLife, 0.5},
+		{"two half-lives", 2 * halfLife, 0.25},
+		{"in the future", -time.Hour, 1},
+	}
+	for _, tt := range tests {
+		if got := recency(candidate(1, tt.age), at); !approx(got, tt.want) {
+			t.Errorf("%s: Recency = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSaturate(t *testing.T) {
+	tests := []struct {
+		n, max int
+		want   float64
+	}{
+		{-5, 100, 0},
+		{0, 100, 0},
+		{100, 100, 1},
+		{1000, 100, 1},
+	}
+	for _, tt := range tests {
+		if got := saturate(tt.n, tt.max); got != tt.want {
+			t.Errorf("saturate(%d, %d) = %v, want %v", tt.n, tt.max, got, tt.want)
+		}
+	}
+
+	// In between it stays inside (0, 1) and grows with n
+	prev := 0.0
+	for n := 1; n < 100; n++ {
+		got := saturate(n, 100)
+		if got <= prev || got >= 1 {
+			t.Fatalf("saturate(%d, 100) = %v, want in (%v, 1)", n, got, prev)
+		}
+		prev = got
+	}
+}
+
+func TestRelationship(t *testing.T) {
+	tests := []struct {
+		name          string
+		viewerFollows bool
+		followsViewer bool
+		want          float64
+	}{
+		{"mutual", true, true, 0.7},
+		{"viewer follows author", true, false, 0.42},
+		{"author follows viewer", false, true, 0.21},
+		{"no follow", false, false, 0},
+	}
+	for _, tt := range tests {
+		c := candidate(1, 0)
+		c.ViewerFollows, c.FollowsViewer = tt.viewerFollows, tt.followsViewer
+		if got := Relationship(c, at); !approx(got, tt.want) {
+			t.Errorf("%s: Relationship = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Chat history adds up to the remaining 0.3
+	c := candidate(1, 0)
+	c.ViewerFollows, c.FollowsViewer, c.ChatMessages = true, true, chatSaturation
+	if got := Relationship(c, at); !approx(got, 1) {
+		t.Errorf("mutual with chat: Relationship = %v, want 1", got)
+	}
+}
+
+func TestScoreZeroWeightDisablesSignal(t *testing.T) {
+	c := candidate(1, 0)
+	c.Reactions = 1000
+
+	cfg := DefaultConfig()
+	with := NewRanker(DefaultSignals(cfg)).Score(c, at)
+
+	cfg.EngagementWeight = 0
+	without := NewRanker(DefaultSignals(cfg)).Score(c, at)
+
+	if !approx(with-without, DefaultConfig().EngagementWeight) {
+		t.Errorf("engagement contributed %v, want %v", with-without, DefaultConfig().EngagementWeight)
+	}
+
+	// A zero-weight signal isn't even evaluated
+	called := false
+	ranker := NewRanker([]Signal{{Name: "off", Weight: 0, Score: func(*models.RankCandidate, time.Time) float64 {
+		called = true
+		return 1
+	}}})
+	if got := ranker.Score(c, at); got != 0 || called {
+		t.Errorf("zero weight: Score = %v, evaluated = %v", got, called)
+	}
+}
+
+func TestRankTieBreak(t *testing.T) {
+	// A constant signal makes every score equal
+	ranker := NewRanker([]Signal{{Name: "flat", Weight: 1, Score: func(*models.RankCandidate, time.Time) float64 {
+		return 1
+	}}})
+
+	candidates := []*models.RankCandidate{
+		candidate(1, 2*time.Hour),
+		candidate(2, time.Hour),
+		candidate(4, time.Hour),
+		candidate(3, 0),
+	}
+	ranker.Rank(candidates, at)
+
+	want := []int{3, 4, 2, 1} // Newest first, then higher ID first
+	for i, c := range candidates {
+		if c.Post.ID != want[i] {
+			t.Fatalf("order = %v, want %v", ids(candidates), want)
+		}
+	}
+
+	// Any input order gives the same result
+	reversed := []*models.RankCandidate{candidates[3], candidates[2], candidates[1], candidates[0]}
+	ranker.Rank(reversed, at)
+	for i, c := range reversed {
+		if c.Post.ID != want[i] {
+			t.Fatalf("order from reversed input = %v, want %v", ids(reversed), want)
+		}
+	}
+}
+
+func TestRankByScore(t *testing.T) {
+	ranker := NewRanker(DefaultSignals(DefaultConfig()))
+
+	old := candidate(1, 48*time.Hour)
+	fresh := candidate(2, 0)
+	ranked := []*models.RankCandidate{old, fresh}
+	ranker.Rank(ranked, at)
+
+	if ranked[0] != fresh || fresh.Score <= old.Score {
+		t.Errorf("fresh post should rank first: scores %v, %v", fresh.Score, old.Score)
+	}
+}
+
+func ids(candidates []*models.RankCandidate) []int {
+	out := make([]int, len(candidates))
+	for i, c := range candidates {
+		out[i] = c.Post.ID
+	}
+	return out
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg != DefaultConfig() {
+			t.Errorf("LoadConfig() = %+v, want defaults", cfg)
+		}
+	})
+
+	t.Run("weights", func(t *testing.T) {
+		t.Setenv("RANK_WEIGHT_RECENCY", "0")
+		t.Setenv("RANK_WEIGHT_ENGAGEMENT", "2.5")
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.RecencyWeight != 0 || cfg.EngagementWeight != 2.5 {
+			t.Errorf("weights = %v, %v, want 0, 2.5", cfg.RecencyWeight, cfg.EngagementWeight)
+		}
+	})
+
+	for _, value := range []string{"-1", "NaN", "Inf", "-Inf", "+Inf", "heavy"} {
+		t.Run("rejects "+value, func(t *testing.T) {
+			t.Setenv("RANK_WEIGHT_RELATIONSHIP", value)
+			if _, err := LoadConfig(); err == nil {
+				t.Errorf("RANK_WEIGHT_RELATIONSHIP=%s accepted", value)
+			}
+		})
+	}
+
+	for env, value := range map[string]string{
+		"RANK_HALF_LIFE":      "0s",
+		"RANK_WINDOW":         "-1h",
+		"RANK_MAX_CANDIDATES": "0",
+	} {
+		t.Run("rejects "+env, func(t *testing.T) {
+			t.Setenv(env, value)
+			if _, err := LoadConfig(); err == nil {
+				t.Errorf("%s=%s accepted", env, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"social-network/services/posts/db"
+	"social-network/services/posts/models"
+	"social-network/services/posts/utils"
+)
+
+// Bookmark saves a post for the user, filed in one of their collections if
+// collectionID is set. Bookmarking a post again moves it to that
+// collection. A repost is bookmarked as its original.
+func (s *PostService) Bookmark(postID, userID int, collectionID *int) (*models.Bookmark, error) {
+	post, err := s.bookmarkable(postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if collectionID != nil {
+		if _, err := db.GetBookmarkCollection(s.database, userID, *collectionID); err == sql.ErrNoRows {
+			return nil, errors.New("collection not found")
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	bookmark := &models.Bookmark{
+		UserID:       userID,
+		PostID:       post.ID,
+		CollectionID: collectionID,
+		CreatedAt:    time.Now(),
+	}
+	if err := db.SaveBookmark(s.database, bookmark); err != nil {
+		return nil, err
+	}
+	return bookmark, nil
+}
+
+// Unbookmark removes the user's bookmark of a post (or of the original of
+// a repost)
+func (s *PostService) Unbookmark(postID, userID int) error {
+	removed, err := db.DeleteBookmark(s.database, userID, postID)
+	if err != nil {
+		return err
+	}
+	if removed {
+		return nil
+	}
+
+	// The post may be a repost of the bookmarked original
+	post, err := db.GetPostByID(s.database, postID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil && post.ShareType == models.ShareTypeRepost && post.SharedPostID != nil {
+		removed, err = db.DeleteBookmark(s.database, userID, *post.SharedPostID)
+		if err != nil {
+			return err
+		}
+	}
+	if !removed {
+		return errors.New("bookmark not found")
+	}
+	return nil
+}
+
+// GetBookmarks retrieves one page of the user's bookmarks, newest first,
+// with their posts. Posts the user can no longer access drop out.
+func (s *PostService) GetBookmarks(userID int, page models.BookmarkPage) ([]*models.Bookmark, bool, error) {
+	if page.CollectionID != nil {
+		if _, err := db.GetBookmarkCollection(s.database, userID, *page.CollectionID); err == sql.ErrNoRows {
+			return nil, false, errors.New("collection not found")
+		} else if err != nil {
+			return nil, false, err
+		}
+	}
+
+	bookmarks, hasMore, err := db.GetBookmarks(s.database, userID, page)
+	if err != nil {
+		return nil, false, err
+	}
+
+	posts := make([]*models.Post, len(bookmarks))
+	for i, bookmark := range bookmarks {
+		posts[i] = bookmark.Post
+	}
+	if err := s.attachPostDetails(posts, userID); err != nil {
+		return nil, false, err
+	}
+
+	return bookmarks, hasMore, nil
+}
+
+// GetBookmarkCollections lists the user's collections by name
+func (s *PostService) GetBookmarkCollections(userID int) ([]*models.BookmarkCollection, error) {
+	return db.GetBookmarkCollections(s.database, userID)
+}
+
+// CreateBookmarkCollection creates a named collection for the user's
+// bookmarks
+func (s *PostService) CreateBookmarkCollection(userID int, name string) (*models.BookmarkCollection, error) {
+	name, err := utils.ValidateCollectionName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := db.CountBookmarkCollections(s.database, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= models.MaxBookmarkCollections {
+		return nil, errors.New("too many collections")
+	}
+
+	collection := &models.BookmarkCollection{Name: name, CreatedAt: time.Now()}
+	created, err := db.CreateBookmarkCollection(s.database, userID, collection)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, errors.New("a collection with this name already exists")
+	}
+	return collection, nil
+}
+
+// RenameBookmarkCollection renames one of the user's collections
+func (s *PostService) RenameBookmarkCollection(collectionID, userID int, name string) (*models.BookmarkCollection, error) {
+	name, err := utils.ValidateCollectionName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	collection, err := db.GetBookmarkCollection(s.database, userID, collectionID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("collection not found")
+	} else if err != nil {
+		return nil, err
+	}
+	if collection.Name == name {
+		return collection, nil
+	}
+
+	renamed, err := db.RenameBookmarkCollection(s.database, userID, collectionID, name)
+	if err != nil {
+		return nil, err
+	}
+	if !renamed {
+		return nil, errors.New("a collection with this name already exists")
+	}
+	collection.Name = name
+	return collection, nil
+}
+
+// DeleteBookmarkCollection deletes one of the user's collections. Its
+// bookmarks are kept, unfiled.
+func (s *PostService) DeleteBookmarkCollection(collectionID, userID int) error {
+	deleted, err := db.DeleteBookmarkCollection(s.database, userID, collectionID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("collection not found")
+	}
+	return nil
+}
+
+// bookmarkable loads the post a bookmark of postID would point to: the
+// post itself, or the original of a repost. The user must be able to
+// access it, be a member of its group if it has one, and it must be
+// published.
+func (s *PostService) bookmarkable(postID, userID int) (*models.Post, error) {
+	hasAccess, err := db.CheckPostAccess(s.database, postID, userID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("post not found")
+	} else if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, errors.New("access denied: cannot bookmark this post")
+	}
+
+	post, err := db.GetPostByID(s.database, postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.GroupID != nil {
+		member, err := db.IsGroupMember(s.database, *post.GroupID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !member {
+			return nil, errors.New("access denied: cannot bookmark this post")
+		}
+	}
+	if post.Status != models.PostStatusPublished {
+		return nil, errors.New("cannot bookmark an unpublished post")
+	}
+	if post.ShareType == models.ShareTypeRepost {
+		if post.SharedPostID == nil {
+			return nil, errors.New("post not found")
+		}
+		return s.bookmarkable(*post.SharedPostID, userID)
+	}
+	return post, nil
+}
+
+// attachBookmarks marks the posts the viewer bookmarked
+func (s *PostService) attachBookmarks(posts []*models.Post, viewerID int) error {
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	bookmarked, err := db.GetBookmarkedPostIDs(s.database, viewerID, ids)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		post.Bookmarked = bookmarked[post.ID]
+	}
+	return nil
+}
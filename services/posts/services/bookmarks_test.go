@@ -0,0 +1,119 @@
+package services
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"social-network/services/common/storage"
+	"social-network/services/common/testdb"
+	"social-network/services/posts/models"
+	"social-network/services/posts/ranking"
+)
+
+func newTestService(t *testing.T) (*sql.DB, *PostService) {
+	database := testdb.Open(t)
+	store, err := storage.NewLocal(t.TempDir(), []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return database, NewPostService(database, ranking.DefaultConfig(), store)
+}
+
+// group creates a group with accepted members
+func group(t *testing.T, database *sql.DB, creatorID int, memberIDs ...int) int {
+	t.Helper()
+	result, err := database.Exec(`INSERT INTO groups (name, creator_id) VALUES ('Hikers', ?)`, creatorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groupID, _ := result.LastInsertId()
+	for _, userID := range append([]int{creatorID}, memberIDs...) {
+		_, err := database.Exec(`INSERT INTO group_members (group_id, user_id, status) VALUES (?, ?, 'accepted')`, groupID, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return int(groupID)
+}
+
+func publicPost(t *testing.T, s *PostService, userID int, groupID *int) int {
+	t.Helper()
+	post, err := s.CreatePost(&models.CreatePostRequest{GroupID: groupID, Content: "Trail report", PrivacyLevel: "public"}, userID, "author")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return post.ID
+}
+
+func bookmarkedIDs(t *testing.T, s *PostService, userID int) []int {
+	t.Helper()
+	bookmarks, _, err := s.GetBookmarks(userID, models.BookmarkPage{Limit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int{}
+	for _, bookmark := range bookmarks {
+		ids = append(ids, bookmark.PostID)
+	}
+	return ids
+}
+
+// Leaving a group hides bookmarks of its posts, from listings and
+// collection counts
+func TestBookmarksOfLeftGroup(t *testing.T) {
+	database, s := newTestService(t)
+	author := testdb.User(t, database, "author")
+	reader := testdb.User(t, database, "reader")
+	groupID := group(t, database, author, reader)
+
+	groupPost := publicPost(t, s, author, &groupID)
+	profilePost := publicPost(t, s, author, nil)
+
+	collection, err := s.CreateBookmarkCollection(reader, "Hiking")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, postID := range []int{profilePost, groupPost} {
+		if _, err := s.Bookmark(postID, reader, &collection.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := bookmarkedIDs(t, s, reader); len(got) != 2 {
+		t.Fatalf("bookmarks = %v, want both posts", got)
+	}
+
+	if _, err := database.Exec(`DELETE FROM group_members WHERE group_id = ? AND user_id = ?`, groupID, reader); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := bookmarkedIDs(t, s, reader); len(got) != 1 || got[0] != profilePost {
+		t.Errorf("bookmarks after leaving the group = %v, want [%d]", got, profilePost)
+	}
+	collections, err := s.GetBookmarkCollections(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collections) != 1 {
+		t.Fatalf("%d collections, want 1", len(collections))
+	}
+	if got := collections[0].BookmarkCount; got != 1 {
+		t.Errorf("collection count after leaving the group = %d, want 1", got)
+	}
+}
+
+func TestBookmarkGroupPostRequiresMembership(t *testing.T) {
+	database, s := newTestService(t)
+	author := testdb.User(t, database, "author")
+	outsider := testdb.User(t, database, "outsider")
+	groupID := group(t, database, author)
+	groupPost := publicPost(t, s, author, &groupID)
+
+	_, err := s.Bookmark(groupPost, outsider, nil)
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("bookmarking a group post from outside: err = %v, want access denied", err)
+	}
+	if _, err := s.Bookmark(groupPost, author, nil); err != nil {
+		t.Errorf("bookmarking as a member: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"social-network/services/posts/db"
+	"social-network/services/posts/models"
+)
+
+// GetPostComments retrieves one page of a post's top-level comments, oldest
+// first. Replies are loaded per comment with GetReplies.
+func (s *PostService) GetPostComments(postID, userID int, page models.CommentPage) ([]*models.Comment, bool, error) {
+	hasAccess, err := db.CheckPostAccess(s.database, postID, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !hasAccess {
+		return nil, false, errors.New("access denied: cannot view comments on this post")
+	}
+
+	comments, hasMore, err := db.GetTopLevelComments(s.database, postID, page)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := s.attachCommentDetails(comments, userID); err != nil {
+		return nil, false, err
+	}
+
+	return comments, hasMore, nil
+}
+
+// GetReplies retrieves one page of the direct replies to a comment, oldest
+// first. Replies to a tombstone can still be listed.
+func (s *PostService) GetReplies(commentID, userID int, page models.CommentPage) ([]*models.Comment, bool, error) {
+	parent, err := db.GetCommentByID(s.database, commentID)
+	if err == sql.ErrNoRows {
+		return nil, false, errors.New("comment not found")
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	hasAccess, err := db.CheckPostAccess(s.database, parent.PostID, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !hasAccess {
+		return nil, false, errors.New("access denied: cannot view comments on this post")
+	}
+
+	replies, hasMore, err := db.GetReplies(s.database, commentID, page)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := s.attachCommentDetails(replies, userID); err != nil {
+		return nil, false, err
+	}
+
+	return replies, hasMore, nil
+}
+
+// liveComment loads a comment that hasn't been deleted; tombstones are
+// reported as not found
+func (s *PostService) liveComment(commentID int) (*models.Comment, error) {
+	comment, err := db.GetCommentByID(s.database, commentID)
+	if err == sql.ErrNoRows || (err == nil && comment.Deleted) {
+		return nil, errors.New("comment not found")
+	}
+	return comment, err
+}
+
+// replyParent loads the comment being replied to, checking that it belongs
+// to the post and that the reply stays within MaxCommentDepth
+func (s *PostService) replyParent(postID, parentID int) (*models.Comment, error) {
+	parent, err := db.GetCommentByID(s.database, parentID)
+	if err == sql.ErrNoRows || (err == nil && (parent.Deleted || parent.PostID != postID)) {
+		return nil, errors.New("parent comment not found")
+	} else if err != nil {
+		return nil, err
+	}
+	if parent.Depth >= models.MaxCommentDepth {
+		return nil, fmt.Errorf("replies can't be nested more than %d levels deep", models.MaxCommentDepth)
+	}
+	return parent, nil
+}
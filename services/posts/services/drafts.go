@@ -0,0 +1,290 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"social-network/services/common/notify"
+	"social-network/services/posts/db"
+	"social-network/services/posts/models"
+	"social-network/services/posts/utils"
+)
+
+// errAlreadyPublished is returned when a draft operation loses the race
+// against its publication
+var errAlreadyPublished = errors.New("post is already published")
+
+// GetDrafts lists the user's scheduled posts, soonest first, then drafts
+func (s *PostService) GetDrafts(userID int) ([]*models.Post, error) {
+	posts, err := db.GetUnpublishedPosts(s.database, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachPostDetails(posts, userID); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// UpdateDraft edits a draft or scheduled post. Unlike UpdatePost no
+// revision is kept, as nobody else has seen it yet.
+func (s *PostService) UpdateDraft(postID, userID int, req *models.UpdatePostRequest) (*models.Post, error) {
+	post, err := s.ownDraft(postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate privacy level
+	if req.PrivacyLevel != "public" && req.PrivacyLevel != "private" && req.PrivacyLevel != "almost_private" {
+		return nil, errors.New("invalid privacy level")
+	}
+
+	// Validate and sanitize content
+	sanitizedContent, err := utils.ValidatePostContent(req.Content, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// A title is only changed when one is sent
+	if req.Title != nil {
+		sanitizedTitle, err := utils.ValidateTitle(req.Title)
+		if err != nil {
+			return nil, err
+		}
+		post.Title = sanitizedTitle
+	}
+
+	// Validate image path
+	if err := utils.ValidateImagePath(req.ImagePath); err != nil {
+		return nil, err
+	}
+	if err := s.claimUploads(req.ImagePath, nil, userID, models.UploadOwnerPost, post.ID); err != nil {
+		return nil, err
+	}
+
+	post.Content = sanitizedContent
+	post.ImagePath = req.ImagePath
+	post.PrivacyLevel = req.PrivacyLevel
+
+	updated, err := db.UpdateDraft(s.database, post)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, errAlreadyPublished
+	}
+
+	// Viewers only matter for private posts
+	viewers := []int{}
+	if req.PrivacyLevel == "private" {
+		viewers = req.Viewers
+	}
+	if err := db.AddPostViewers(s.database, post.ID, viewers); err != nil {
+		return nil, err
+	}
+
+	s.signPostImages(post)
+	return post, nil
+}
+
+// ScheduleDraft sets when a draft or scheduled post is published. A nil
+// publishAt turns it back into a draft.
+func (s *PostService) ScheduleDraft(postID, userID int, publishAt *time.Time) (*models.Post, error) {
+	if _, err := s.ownDraft(postID, userID); err != nil {
+		return nil, err
+	}
+
+	if publishAt != nil && !publishAt.After(time.Now()) {
+		return nil, errors.New("publish_at must be in the future")
+	}
+
+	scheduled, err := db.SchedulePost(s.database, postID, publishAt)
+	if err != nil {
+		return nil, err
+	}
+	if !scheduled {
+		return nil, errAlreadyPublished
+	}
+
+	post, err := db.GetPostByID(s.database, postID)
+	if err != nil {
+		return nil, err
+	}
+	s.signPostImages(post)
+	return post, nil
+}
+
+// PublishDraft publishes a draft or scheduled post right away
+func (s *PostService) PublishDraft(postID, userID int) (*models.Post, error) {
+	if _, err := s.ownDraft(postID, userID); err != nil {
+		return nil, err
+	}
+
+	post, published, err := s.publishPost(postID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !published {
+		return nil, errAlreadyPublished
+	}
+
+	s.signPostImages(post)
+	return post, nil
+}
+
+// CancelDraft deletes a draft or scheduled post
+func (s *PostService) CancelDraft(postID, userID int) error {
+	if _, err := s.ownDraft(postID, userID); err != nil {
+		return err
+	}
+
+	deleted, err := db.DeleteUnpublishedPost(s.database, postID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errAlreadyPublished
+	}
+	return nil
+}
+
+// ownDraft loads one of the user's unpublished posts
+func (s *PostService) ownDraft(postID, userID int) (*models.Post, error) {
+	post, err := s.existingPost(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.UserID != userID {
+		return nil, errors.New("unauthorized: you can only manage your own drafts")
+	}
+	if post.Status == models.PostStatusPublished {
+		return nil, errAlreadyPublished
+	}
+
+	return post, nil
+}
+
+// publishPost publishes a draft or scheduled post at the given time and
+// runs the side effects of CreatePost. It returns false, doing nothing, if
+// the post was already published: ClaimPublication lets only one caller
+// through, so a post is never published (or announced) twice.
+func (s *PostService) publishPost(postID int, at time.Time) (*models.Post, bool, error) {
+	claimed, err := db.ClaimPublication(s.database, postID, at)
+	if err != nil || !claimed {
+		return nil, false, err
+	}
+
+	post, err := db.GetPostByID(s.database, postID)
+	if err != nil {
+		return nil, true, err
+	}
+
+	if err := s.afterPublish(post, post.Author.Username); err != nil {
+		return nil, true, err
+	}
+
+	return post, true, nil
+}
+
+// afterPublish runs the side effects of a post becoming visible: its tags
+// and mentions are recorded (notifying the mentioned users) and the people
+// who can see it are told about it, as is the author of a quoted post
+func (s *PostService) afterPublish(post *models.Post, username string) error {
+	if err := s.syncPostTags(post); err != nil {
+		return err
+	}
+	s.syncPostMentions(post, username)
+	if err := s.notifyNewPost(post, username); err != nil {
+		return err
+	}
+	return s.notifyQuote(post, username)
+}
+
+// notifyNewPost notifies group members about a group post, and otherwise
+// the author's followers (only the chosen viewers for a private post).
+// Notifications are sent in the background so large audiences don't hold
+// up the request.
+func (s *PostService) notifyNewPost(post *models.Post, authorName string) error {
+	if post.GroupID != nil {
+		memberIDs, err := db.GetGroupMemberIDs(s.database, *post.GroupID)
+		if err != nil {
+			return err
+		}
+		groupName, err := db.GetGroupName(s.database, *post.GroupID)
+		if err != nil {
+			return err
+		}
+
+		go notify.GroupPost(excludeUser(memberIDs, post.UserID), post.ID, *post.GroupID, post.UserID, authorName, groupName)
+		return nil
+	}
+
+	var recipientIDs []int
+	var err error
+	if post.PrivacyLevel == "private" {
+		recipientIDs, err = db.GetPostViewerIDs(s.database, post.ID)
+	} else {
+		recipientIDs, err = db.GetFollowerIDs(s.database, post.UserID)
+	}
+	if err != nil {
+		return err
+	}
+
+	go notify.NewPost(excludeUser(recipientIDs, post.UserID), post.ID, post.UserID, authorName)
+	return nil
+}
+
+// excludeUser returns ids without userID
+func excludeUser(ids []int, userID int) []int {
+	kept := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if id != userID {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// ============================================
+// SCHEDULER
+// ============================================
+
+// schedulerBatchSize caps the posts published per scheduler run
+const schedulerBatchSize = 100
+
+// StartScheduler publishes scheduled posts as they come due, checking every
+// interval. The first check runs immediately to catch up on posts that came
+// due while the service was down.
+func (s *PostService) StartScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.publishDuePosts()
+			<-ticker.C
+		}
+	}()
+}
+
+// publishDuePosts publishes the scheduled posts whose time has come
+func (s *PostService) publishDuePosts() {
+	ids, err := db.GetDuePostIDs(s.database, time.Now(), schedulerBatchSize)
+	if err != nil {
+		log.Printf("[Scheduler] Failed to list due posts: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		_, published, err := s.publishPost(id, time.Now())
+		if err != nil {
+			log.Printf("[Scheduler] Failed to publish post %d: %v", id, err)
+		} else if published {
+			log.Printf("[Scheduler] Published post %d", id)
+		}
+	}
+}
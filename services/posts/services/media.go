@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"social-network/services/common/imaging"
+	"social-network/services/common/storage"
+	"social-network/services/posts/db"
+	"social-network/services/posts/models"
+	"social-network/services/posts/utils"
+)
+
+// RecordUpload stores an uploaded image as media of its uploader, ready
+// to be attached to a post or comment
+func (s *PostService) RecordUpload(media *models.Media) error {
+	altText, err := utils.ValidateAltText(media.AltText)
+	if err != nil {
+		return err
+	}
+	media.AltText = altText
+	media.CreatedAt = time.Now()
+	if err := db.CreateMedia(s.database, media); err != nil {
+		return err
+	}
+	s.signMedia([]*models.Media{media})
+	return nil
+}
+
+// pendingMedia checks the attachments of a new post or comment: at most
+// limit distinct uploads of the user that aren't attached yet. Returns
+// them in order with their final alt text.
+func (s *PostService) pendingMedia(refs []models.MediaAttachment, userID, limit int) ([]*models.Media, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	if len(refs) > limit {
+		return nil, errors.New("too many media attachments")
+	}
+
+	ids := make([]int, len(refs))
+	seen := make(map[int]bool, len(refs))
+	for i, ref := range refs {
+		if seen[ref.ID] {
+			return nil, errors.New("media attached twice")
+		}
+		seen[ref.ID] = true
+		ids[i] = ref.ID
+	}
+
+	uploads, err := db.GetUnattachedMedia(s.database, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	media := make([]*models.Media, len(refs))
+	for i, ref := range refs {
+		m := uploads[ref.ID]
+		if m == nil {
+			return nil, errors.New("media not found")
+		}
+		if ref.AltText != nil {
+			altText, err := utils.ValidateAltText(*ref.AltText)
+			if err != nil {
+				return nil, err
+			}
+			m.AltText = altText
+		}
+		media[i] = m
+	}
+	return media, nil
+}
+
+// attachPostMedia loads the attachments of a list of posts
+func (s *PostService) attachPostMedia(posts []*models.Post) error {
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	media, err := db.GetPostMedia(s.database, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		post.Media = media[post.ID]
+		if post.Media == nil {
+			post.Media = []*models.Media{}
+		}
+	}
+	s.signPostImages(posts...)
+	return nil
+}
+
+// attachCommentMedia is attachPostMedia for comments
+func (s *PostService) attachCommentMedia(comments []*models.Comment) error {
+	ids := make([]int, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+
+	media, err := db.GetCommentMedia(s.database, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, comment := range comments {
+		comment.Media = media[comment.ID]
+		if comment.Media == nil {
+			comment.Media = []*models.Media{}
+		}
+	}
+	s.signCommentImages(comments...)
+	return nil
+}
+
+// ============================================
+// SIGNED URLS
+// ============================================
+
+// imageURLTTL is how long the signed image URLs of responses work
+const imageURLTTL = 12 * time.Hour
+
+// ImageURL returns the signed URL of a stored image path. Paths that aren't
+// in the storage, such as legacy external URLs, give "".
+func (s *PostService) ImageURL(path string) string {
+	url, err := s.store.SignedURL(storage.Key(path), imageURLTTL)
+	if err != nil {
+		if !errors.Is(err, storage.ErrInvalidKey) {
+			log.Printf("Error signing URL of %s: %v", path, err)
+		}
+		return ""
+	}
+	return url
+}
+
+// signMedia fills in the URLs of attachments and their thumbnails
+func (s *PostService) signMedia(media []*models.Media) {
+	for _, m := range media {
+		m.URL = s.ImageURL(m.Path)
+		m.Thumbnails = make(map[int]string, len(imaging.ThumbnailSizes))
+		for size, path := range imaging.ThumbnailPaths(m.Path) {
+			m.Thumbnails[size] = s.ImageURL(path)
+		}
+	}
+}
+
+// signPostImages fills in the image URLs of posts
+func (s *PostService) signPostImages(posts ...*models.Post) {
+	for _, post := range posts {
+		if post.ImagePath != nil {
+			post.ImageURL = s.ImageURL(*post.ImagePath)
+		}
+		s.signMedia(post.Media)
+	}
+}
+
+// signCommentImages fills in the image URLs of comments
+func (s *PostService) signCommentImages(comments ...*models.Comment) {
+	for _, comment := range comments {
+		if comment.ImagePath != nil {
+			comment.ImageURL = s.ImageURL(*comment.ImagePath)
+		}
+		s.signMedia(comment.Media)
+	}
+}
+
+// ============================================
+// MEDIA COLLECTOR
+// ============================================
+
+// mediaCollectBatchSize caps the uploads removed per collector run
+const mediaCollectBatchSize = 100
+
+// StartMediaCollector removes uploads left unattached for longer than
+// grace, checking every interval: uploads never used in a post or comment,
+// and the media of deleted posts and comments.
+func (s *PostService) StartMediaCollector(interval, grace time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.collectOrphanedMedia(grace)
+			<-ticker.C
+		}
+	}()
+}
+
+// collectOrphanedMedia removes the uploads unattached for longer than
+// grace. Rows go first, so a file is never removed while it can still be
+// attached.
+func (s *PostService) collectOrphanedMedia(grace time.Duration) {
+	for {
+		paths, err := db.DeleteOrphanedMedia(s.database, time.Now().Add(-grace), mediaCollectBatchSize)
+		if err != nil {
+			log.Printf("[MediaCollector] Failed to delete orphaned media: %v", err)
+			return
+		}
+
+		for _, path := range paths {
+			if err := imaging.Remove(context.Background(), s.store, storage.Key(path)); err != nil {
+				log.Printf("[MediaCollector] Failed to remove %s: %v", path, err)
+			}
+		}
+		if len(paths) > 0 {
+			log.Printf("[MediaCollector] Removed %d orphaned upload(s)", len(paths))
+		}
+		if len(paths) < mediaCollectBatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"social-network/services/common/notify"
+	"social-network/services/posts/db"
+	"social-network/services/posts/models"
+	"social-network/services/posts/utils"
+)
+
+// GetMentions retrieves one page of the posts that mention the user, in the
+// post or in a comment
+func (s *PostService) GetMentions(userID int, page models.PostPage) ([]*models.Post, bool, error) {
+	posts, hasMore, err := db.GetPostsMentioningUser(s.database, userID, page)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := s.attachPostDetails(posts, userID); err != nil {
+		return nil, false, err
+	}
+
+	return posts, hasMore, nil
+}
+
+// syncMentions resolves the @usernames in a post or comment, stores them and
+// notifies users mentioned for the first time, provided they can see the post
+func (s *PostService) syncMentions(targetType string, targetID, postID, authorID int, authorName, preview string, texts ...string) error {
+	users, err := db.ResolveUsernames(s.database, utils.MentionCandidates(texts...))
+	if err != nil {
+		return err
+	}
+	index := utils.MentionIndex(users)
+
+	// Only users an actual mention resolves to, e.g. not "@bob" for "@bob." if
+	// both exist
+	var userIDs []int
+	seen := make(map[int]bool)
+	for _, text := range texts {
+		for _, mention := range utils.FindMentions(text, "", index) {
+			if !seen[mention.UserID] && len(userIDs) < utils.MaxMentionsPerText {
+				seen[mention.UserID] = true
+				userIDs = append(userIDs, mention.UserID)
+			}
+		}
+	}
+
+	added, err := db.SetMentions(s.database, targetType, targetID, postID, userIDs, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var recipientIDs []int
+	for _, userID := range added {
+		if userID == authorID {
+			continue
+		}
+		hasAccess, err := db.CheckPostAccess(s.database, postID, userID)
+		if err != nil {
+			return err
+		}
+		if hasAccess {
+			recipientIDs = append(recipientIDs, userID)
+		}
+	}
+
+	for _, userID := range recipientIDs {
+		go notify.Mention(userID, postID, targetType, targetID, authorID, authorName, preview)
+	}
+	return nil
+}
+
+// attachPostMentions fills in the mention spans of a list of posts
+func (s *PostService) attachPostMentions(posts []*models.Post) error {
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	mentioned, err := db.GetMentionedUsers(s.database, models.MentionTargetPost, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		index := utils.MentionIndex(mentioned[post.ID])
+		post.Mentions = []models.Mention{}
+		if post.Title != nil {
+			post.Mentions = append(post.Mentions, utils.FindMentions(*post.Title, models.MentionFieldTitle, index)...)
+		}
+		post.Mentions = append(post.Mentions, utils.FindMentions(post.Content, models.MentionFieldContent, index)...)
+	}
+	return nil
+}
+
+// attachCommentMentions is attachPostMentions for comments
+func (s *PostService) attachCommentMentions(comments []*models.Comment) error {
+	ids := make([]int, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+
+	mentioned, err := db.GetMentionedUsers(s.database, models.MentionTargetComment, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, comment := range comments {
+		index := utils.MentionIndex(mentioned[comment.ID])
+		comment.Mentions = append([]models.Mention{}, utils.FindMentions(comment.Content, models.MentionFieldContent, index)...)
+	}
+	return nil
+}
+
+// syncPostMentions runs syncMentions for a post's title and content and
+// fills in its mention spans. The post is already saved, so failures are
+// logged rather than failing the request.
+func (s *PostService) syncPostMentions(post *models.Post, username string) {
+	texts := []string{post.Content}
+	if post.Title != nil {
+		texts = append(texts, *post.Title)
+	}
+
+	err := s.syncMentions(models.MentionTargetPost, post.ID, post.ID, post.UserID, username, post.Content, texts...)
+	if err != nil {
+		log.Printf("Failed to sync mentions of post %d: %v", post.ID, err)
+	}
+	if err := s.attachPostMentions([]*models.Post{post}); err != nil {
+		log.Printf("Failed to load mentions of post %d: %v", post.ID, err)
+	}
+}
+
+// syncCommentMentions is syncPostMentions for comments
+func (s *PostService) syncCommentMentions(comment *models.Comment, username string) {
+	err := s.syncMentions(models.MentionTargetComment, comment.ID, comment.PostID, comment.UserID, username, comment.Content, comment.Content)
+	if err != nil {
+		log.Printf("Failed to sync mentions of comment %d: %v", comment.ID, err)
+	}
+	if err := s.attachCommentMentions([]*models.Comment{comment}); err != nil {
+		log.Printf("Failed to load mentions of comment %d: %v", comment.ID, err)
+	}
+}
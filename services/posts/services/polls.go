@@ -0,0 +1,228 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"social-network/services/posts/db"
+	"social-network/services/posts/models"
+	"social-network/services/posts/utils"
+)
+
+// newPoll validates the poll of a new post. publishAt is when the post
+// goes out, nil for now.
+func newPoll(req *models.PollRequest, publishAt *time.Time, now time.Time) (*models.Poll, error) {
+	if len(req.Options) < models.MinPollOptions || len(req.Options) > models.MaxPollOptions {
+		return nil, errors.New("a poll needs 2 to 10 options")
+	}
+
+	poll := &models.Poll{
+		MultipleChoice: req.MultipleChoice,
+		Anonymous:      req.Anonymous,
+		HideResults:    req.HideResults,
+		ClosesAt:       req.ClosesAt,
+		CreatedAt:      now,
+	}
+
+	seen := make(map[string]bool)
+	for i, text := range req.Options {
+		sanitized, err := utils.ValidatePollOption(text)
+		if err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(sanitized)
+		if seen[key] {
+			return nil, errors.New("poll options must be different")
+		}
+		seen[key] = true
+		poll.Options = append(poll.Options, &models.PollOption{Position: i, Text: sanitized})
+	}
+
+	if req.ClosesAt != nil {
+		opensAt := now
+		if publishAt != nil {
+			opensAt = *publishAt
+		}
+		if !req.ClosesAt.After(opensAt) {
+			return nil, errors.New("closes_at must be after the post is published")
+		}
+	}
+
+	return poll, nil
+}
+
+// GetPoll retrieves the poll of a post. Unless the poll is anonymous, the
+// voters of each option are listed once the viewer may see the results.
+func (s *PostService) GetPoll(postID, userID int) (*models.Poll, error) {
+	_, poll, err := s.accessiblePoll(postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !poll.Anonymous && poll.ResultsVisible {
+		voters, err := db.GetPollVoters(s.database, poll.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, option := range poll.Options {
+			option.Voters = voters[option.ID]
+		}
+	}
+
+	return poll, nil
+}
+
+// Vote records the user's choice in the poll of a post, replacing any
+// earlier vote
+func (s *PostService) Vote(postID, userID int, optionIDs []int) (*models.Poll, error) {
+	post, poll, err := s.openPoll(postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(optionIDs) == 0 {
+		return nil, errors.New("choose at least one option")
+	}
+	if len(optionIDs) > 1 && !poll.MultipleChoice {
+		return nil, errors.New("this poll allows only one choice")
+	}
+
+	valid := make(map[int]bool, len(poll.Options))
+	for _, option := range poll.Options {
+		valid[option.ID] = true
+	}
+	chosen := make([]int, 0, len(optionIDs))
+	seen := make(map[int]bool, len(optionIDs))
+	for _, id := range optionIDs {
+		if !valid[id] {
+			return nil, errors.New("invalid poll option")
+		}
+		if !seen[id] {
+			seen[id] = true
+			chosen = append(chosen, id)
+		}
+	}
+
+	if err := db.SetPollVotes(s.database, poll.ID, userID, chosen, time.Now()); err != nil {
+		return nil, err
+	}
+
+	_, poll, err = s.accessiblePoll(post.ID, userID)
+	return poll, err
+}
+
+// RetractVote withdraws the user's vote from the poll of a post
+func (s *PostService) RetractVote(postID, userID int) (*models.Poll, error) {
+	post, poll, err := s.openPoll(postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.SetPollVotes(s.database, poll.ID, userID, nil, time.Now()); err != nil {
+		return nil, err
+	}
+
+	_, poll, err = s.accessiblePoll(post.ID, userID)
+	return poll, err
+}
+
+// openPoll is accessiblePoll for voting: the post must be published and
+// the poll still open
+func (s *PostService) openPoll(postID, userID int) (*models.Post, *models.Poll, error) {
+	post, poll, err := s.accessiblePoll(postID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if post.Status != models.PostStatusPublished {
+		return nil, nil, errors.New("access denied: cannot vote on this poll")
+	}
+	if poll.Closed {
+		return nil, nil, errors.New("poll is closed")
+	}
+	return post, poll, nil
+}
+
+// accessiblePoll loads the poll of a post the user can see, as that user
+// sees it. Group posts are only accessible to the group's members.
+func (s *PostService) accessiblePoll(postID, userID int) (*models.Post, *models.Poll, error) {
+	post, err := s.existingPost(postID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hasAccess, err := db.CheckPostAccess(s.database, postID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if hasAccess && post.GroupID != nil {
+		hasAccess, err = db.IsGroupMember(s.database, *post.GroupID, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if !hasAccess {
+		return nil, nil, errors.New("access denied: cannot view this poll")
+	}
+
+	if err := s.attachPolls([]*models.Post{post}, userID); err != nil {
+		return nil, nil, err
+	}
+	if post.Poll == nil {
+		return nil, nil, errors.New("poll not found")
+	}
+	return post, post.Poll, nil
+}
+
+// attachPolls loads the polls of a list of posts as the viewer sees them:
+// with the viewer's votes, and without counts if the results are hidden
+// from them. Two queries cover the whole list.
+func (s *PostService) attachPolls(posts []*models.Post, viewerID int) error {
+	postIDs := make([]int, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID
+	}
+
+	polls, err := db.GetPolls(s.database, postIDs)
+	if err != nil || len(polls) == 0 {
+		return err
+	}
+
+	pollIDs := make([]int, 0, len(polls))
+	for _, poll := range polls {
+		pollIDs = append(pollIDs, poll.ID)
+	}
+	votes, err := db.GetPollVotes(s.database, viewerID, pollIDs)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, post := range posts {
+		poll := polls[post.ID]
+		if poll == nil {
+			continue
+		}
+		if viewerVotes := votes[poll.ID]; viewerVotes != nil {
+			poll.ViewerVotes = viewerVotes
+		}
+		finishPoll(poll, post.UserID == viewerID, now)
+		post.Poll = poll
+	}
+	return nil
+}
+
+// finishPoll works out whether a poll is closed and whether the viewer may
+// see its results, removing the counts if not. The author always may.
+func finishPoll(poll *models.Poll, isAuthor bool, now time.Time) {
+	poll.Closed = poll.ClosesAt != nil && !poll.ClosesAt.After(now)
+	poll.ResultsVisible = !poll.HideResults || poll.Closed || len(poll.ViewerVotes) > 0 || isAuthor
+	if poll.ResultsVisible {
+		return
+	}
+
+	poll.VoterCount = nil
+	for _, option := range poll.Options {
+		option.VoteCount = nil
+	}
+}
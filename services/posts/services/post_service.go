@@ -6,25 +6,38 @@ import (
 	"time"
 
 	"social-network/services/common/notify"
+	"social-network/services/common/storage"
 	"social-network/services/posts/db"
 	"social-network/services/posts/models"
+	"social-network/services/posts/ranking"
 	"social-network/services/posts/utils"
 )
 
 // PostService handles business logic for posts
 type PostService struct {
-	database *sql.DB
+	database   *sql.DB
+	reactions  *reactionNotifier
+	rankConfig ranking.Config
+	ranker     *ranking.Ranker
+	trending   *trendingCache
+	store      storage.Storage
 }
 
-// NewPostService creates a new post service instance
-func NewPostService(database *sql.DB) *PostService {
+// NewPostService creates a new post service instance. Uploaded images are
+// kept in store.
+func NewPostService(database *sql.DB, rankConfig ranking.Config, store storage.Storage) *PostService {
 	return &PostService{
-		database: database,
+		database:   database,
+		store:      store,
+		reactions:  newReactionNotifier(reactionNotifyWindow),
+		rankConfig: rankConfig,
+		ranker:     ranking.NewRanker(ranking.DefaultSignals(rankConfig)),
+		trending:   &trendingCache{},
 	}
 }
 
 // CreatePost creates a new post
-func (s *PostService) CreatePost(req *models.CreatePostRequest, userID int) (*models.Post, error) {
+func (s *PostService) CreatePost(req *models.CreatePostRequest, userID int, username string) (*models.Post, error) {
 	// Validate privacy level
 	if req.PrivacyLevel != "public" && req.PrivacyLevel != "private" && req.PrivacyLevel != "almost_private" {
 		return nil, errors.New("invalid privacy level")
@@ -47,15 +60,61 @@ func (s *PostService) CreatePost(req *models.CreatePostRequest, userID int) (*mo
 		return nil, err
 	}
 
+	// A quote points at the original, never at a repost of it
+	var sharedPostID *int
+	shareType := ""
+	if req.QuotedPostID != nil {
+		original, err := s.shareable(*req.QuotedPostID, userID)
+		if err != nil {
+			return nil, err
+		}
+		sharedPostID, shareType = &original.ID, models.ShareTypeQuote
+	}
+
+	// Posts saved as drafts or for later are published by publishPost
+	status := models.PostStatusPublished
+	if req.PublishAt != nil {
+		if !req.PublishAt.After(time.Now()) {
+			return nil, errors.New("publish_at must be in the future")
+		}
+		status = models.PostStatusScheduled
+	} else if req.Draft {
+		status = models.PostStatusDraft
+	}
+
+	// A poll is checked up front so it can't fail after the post is created
+	var poll *models.Poll
+	if req.Poll != nil {
+		poll, err = newPoll(req.Poll, req.PublishAt, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	media, err := s.pendingMedia(req.Media, userID, models.MaxPostMedia)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first image doubles as image_path for single-image clients
+	imagePath := req.ImagePath
+	if imagePath == nil && len(media) > 0 {
+		imagePath = &media[0].Path
+	}
+
 	// Create post with sanitized content
 	post := &models.Post{
 		UserID:       userID,
 		GroupID:      req.GroupID,
 		Title:        sanitizedTitle,
 		Content:      sanitizedContent,
-		ImagePath:    req.ImagePath,
+		ImagePath:    imagePath,
 		PrivacyLevel: req.PrivacyLevel,
 		CreatedAt:    time.Now(),
+		Status:       status,
+		PublishAt:    req.PublishAt,
+		SharedPostID: sharedPostID,
+		ShareType:    shareType,
 	}
 
 	err = db.CreatePost(s.database, post)
@@ -63,7 +122,38 @@ func (s *PostService) CreatePost(req *models.CreatePostRequest, userID int) (*mo
 		return nil, err
 	}
 
-	// Add viewers if private (specific chosen followers)
+	if poll != nil {
+		poll.PostID = post.ID
+		if err := db.CreatePoll(s.database, poll); err != nil {
+			db.DeletePost(s.database, post.ID)
+			return nil, err
+		}
+		if err := s.attachPolls([]*models.Post{post}, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Attaching can still fail if the same upload is used concurrently
+	attached, err := db.AttachPostMedia(s.database, post.ID, userID, media)
+	if err != nil || !attached {
+		db.DeletePost(s.database, post.ID)
+		if err == nil {
+			err = errors.New("media not found")
+		}
+		return nil, err
+	}
+	if err := s.claimUploads(imagePath, media, userID, models.UploadOwnerPost, post.ID); err != nil {
+		db.DeletePost(s.database, post.ID)
+		return nil, err
+	}
+	post.Media = media
+	if post.Media == nil {
+		post.Media = []*models.Media{}
+	}
+	s.signPostImages(post)
+
+	// Add viewers if private (specific chosen followers). Drafts keep theirs
+	// for when they're published.
 	if req.PrivacyLevel == "private" && len(req.Viewers) > 0 {
 		err = db.AddPostViewers(s.database, post.ID, req.Viewers)
 		if err != nil {
@@ -71,6 +161,14 @@ func (s *PostService) CreatePost(req *models.CreatePostRequest, userID int) (*mo
 		}
 	}
 
+	if status != models.PostStatusPublished {
+		return post, nil
+	}
+
+	if err := s.afterPublish(post, username); err != nil {
+		return nil, err
+	}
+
 	return post, nil
 }
 
@@ -92,11 +190,20 @@ func (s *PostService) GetPost(postID, userID int) (*models.Post, error) {
 		return nil, err
 	}
 
+	if err := s.attachPostDetails([]*models.Post{post}, userID); err != nil {
+		return nil, err
+	}
+
+	// Opening a post counts as seeing it for the ranked feed
+	if err := db.MarkPostsSeen(s.database, userID, []int{postID}, time.Now()); err != nil {
+		return nil, err
+	}
+
 	return post, nil
 }
 
 // UpdatePost updates an existing post
-func (s *PostService) UpdatePost(postID, userID int, req *models.UpdatePostRequest) (*models.Post, error) {
+func (s *PostService) UpdatePost(postID, userID int, username string, req *models.UpdatePostRequest) (*models.Post, error) {
 	// Get existing post
 	post, err := db.GetPostByID(s.database, postID)
 	if err != nil {
@@ -108,6 +215,16 @@ func (s *PostService) UpdatePost(postID, userID int, req *models.UpdatePostReque
 		return nil, errors.New("unauthorized: you can only update your own posts")
 	}
 
+	// A repost has nothing of its own to edit
+	if post.ShareType == models.ShareTypeRepost {
+		return nil, errors.New("cannot edit a repost")
+	}
+
+	// Unpublished posts have no history to keep; they're edited as drafts
+	if post.Status != models.PostStatusPublished {
+		return nil, errors.New("post is not published: edit it as a draft")
+	}
+
 	// Validate privacy level
 	if req.PrivacyLevel != "public" && req.PrivacyLevel != "private" && req.PrivacyLevel != "almost_private" {
 		return nil, errors.New("invalid privacy level")
@@ -118,12 +235,25 @@ func (s *PostService) UpdatePost(postID, userID int, req *models.UpdatePostReque
 		return nil, errors.New("content is required")
 	}
 
-	// Update post fields
+	// A title is only changed when one is sent
+	if req.Title != nil {
+		sanitizedTitle, err := utils.ValidateTitle(req.Title)
+		if err != nil {
+			return nil, err
+		}
+		post.Title = sanitizedTitle
+	}
+
+	if err := s.claimUploads(req.ImagePath, nil, userID, models.UploadOwnerPost, post.ID); err != nil {
+		return nil, err
+	}
+
+	// Update post fields (the previous version is kept as a revision)
 	post.Content = req.Content
 	post.ImagePath = req.ImagePath
 	post.PrivacyLevel = req.PrivacyLevel
 
-	err = db.UpdatePost(s.database, post)
+	err = db.UpdatePost(s.database, post, time.Now())
 	if err != nil {
 		return nil, err
 	}
@@ -142,6 +272,13 @@ func (s *PostService) UpdatePost(postID, userID int, req *models.UpdatePostReque
 		}
 	}
 
+	// Tags and mentions removed from the content are dropped, new ones added
+	if err := s.syncPostTags(post); err != nil {
+		return nil, err
+	}
+	s.syncPostMentions(post, username)
+
+	s.signPostImages(post)
 	return post, nil
 }
 
@@ -162,17 +299,18 @@ func (s *PostService) DeletePost(postID, userID int) error {
 	return db.DeletePost(s.database, postID)
 }
 
-// GetFeed retrieves posts for a user's feed
-func (s *PostService) GetFeed(userID int) ([]*models.Post, error) {
-	return db.GetFeedPosts(s.database, userID)
-}
+// GetFeed retrieves one page of a user's feed and whether more posts exist
+func (s *PostService) GetFeed(userID int, page models.PostPage) ([]*models.Post, bool, error) {
+	posts, hasMore, err := db.GetFeedPosts(s.database, userID, page)
+	if err != nil {
+		return nil, false, err
+	}
 
-// SearchPosts searches for posts based on query string
-func (s *PostService) SearchPosts(userID int, query string) ([]*models.Post, error) {
-	if query == "" {
-		return []*models.Post{}, nil
+	if err := s.attachPostDetails(posts, userID); err != nil {
+		return nil, false, err
 	}
-	return db.SearchPosts(s.database, userID, query)
+
+	return posts, hasMore, nil
 }
 
 // CreateComment creates a new comment on a post
@@ -187,6 +325,20 @@ func (s *PostService) CreateComment(req *models.CreateCommentRequest, userID int
 		return nil, errors.New("access denied: cannot comment on this post")
 	}
 
+	// Drafts and scheduled posts can't be commented on, even by their author
+	post, err := db.GetPostByID(s.database, req.PostID)
+	if err != nil {
+		return nil, err
+	}
+	if post.Status != models.PostStatusPublished {
+		return nil, errors.New("access denied: cannot comment on this post")
+	}
+
+	// Comments on a repost belong on the original
+	if post.ShareType == models.ShareTypeRepost {
+		return nil, errors.New("cannot comment on a repost")
+	}
+
 	// Validate and sanitize content
 	sanitizedContent, err := utils.ValidatePostContent(req.Content, false)
 	if err != nil {
@@ -198,31 +350,81 @@ func (s *PostService) CreateComment(req *models.CreateCommentRequest, userID int
 		return nil, err
 	}
 
+	media, err := s.pendingMedia(req.Media, userID, models.MaxCommentMedia)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first image doubles as image_path for single-image clients
+	imagePath := req.ImagePath
+	if imagePath == nil && len(media) > 0 {
+		imagePath = &media[0].Path
+	}
+
+	// Replies go under a live comment of the same post
+	var parent *models.Comment
+	if req.ParentCommentID != nil {
+		parent, err = s.replyParent(req.PostID, *req.ParentCommentID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Create comment with sanitized content
 	comment := &models.Comment{
 		PostID:    req.PostID,
 		UserID:    userID,
 		Content:   sanitizedContent,
-		ImagePath: req.ImagePath,
+		ImagePath: imagePath,
 		CreatedAt: time.Now(),
 	}
+	if parent != nil {
+		comment.ParentCommentID = &parent.ID
+		comment.Depth = parent.Depth + 1
+	}
 
 	err = db.CreateComment(s.database, comment)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get post author and send notification (don't notify if commenting on own post)
-	post, err := db.GetPostByID(s.database, req.PostID)
-	if err == nil && post.UserID != userID {
-		// Truncate content for preview
-		preview := sanitizedContent
-		if len(preview) > 50 {
-			preview = preview[:50] + "..."
+	attached, err := db.AttachCommentMedia(s.database, comment.ID, userID, media)
+	if err != nil || !attached {
+		db.DeleteComment(s.database, comment.ID)
+		if err == nil {
+			err = errors.New("media not found")
 		}
-		notify.NewComment(post.UserID, comment.ID, commenterName, preview)
+		return nil, err
+	}
+	if err := s.claimUploads(imagePath, media, userID, models.UploadOwnerComment, comment.ID); err != nil {
+		db.DeleteComment(s.database, comment.ID)
+		return nil, err
+	}
+	comment.Media = media
+	if comment.Media == nil {
+		comment.Media = []*models.Media{}
+	}
+	s.signCommentImages(comment)
+
+	// Truncate content for preview
+	preview := sanitizedContent
+	if len(preview) > 50 {
+		preview = preview[:50] + "..."
+	}
+
+	// Notify the parent comment's author (not for replies to yourself)
+	if parent != nil && parent.UserID != userID {
+		notify.CommentReply(parent.UserID, comment.PostID, parent.ID, comment.ID, userID, commenterName, preview)
+	}
+
+	// Get post author and send notification (don't notify if commenting on
+	// own post, or if the reply notification already went to them)
+	if post.UserID != userID && (parent == nil || parent.UserID != post.UserID) {
+		notify.NewComment(post.UserID, post.ID, comment.ID, userID, commenterName, preview)
 	}
 
+	s.syncCommentMentions(comment, commenterName)
+
 	return comment, nil
 }
 
@@ -239,13 +441,22 @@ func (s *PostService) GetComments(postID, userID int) ([]*models.Comment, error)
 	}
 
 	// Get comments
-	return db.GetCommentsByPostID(s.database, postID)
+	comments, err := db.GetCommentsByPostID(s.database, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachCommentDetails(comments, userID); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
 }
 
 // UpdateComment updates an existing comment
-func (s *PostService) UpdateComment(commentID, userID int, content string, imagePath *string) (*models.Comment, error) {
+func (s *PostService) UpdateComment(commentID, userID int, username, content string, imagePath *string) (*models.Comment, error) {
 	// Get existing comment
-	comment, err := db.GetCommentByID(s.database, commentID)
+	comment, err := s.liveComment(commentID)
 	if err != nil {
 		return nil, err
 	}
@@ -266,22 +477,29 @@ func (s *PostService) UpdateComment(commentID, userID int, content string, image
 		return nil, err
 	}
 
-	// Update comment
+	if err := s.claimUploads(imagePath, nil, userID, models.UploadOwnerComment, comment.ID); err != nil {
+		return nil, err
+	}
+
+	// Update comment (the previous version is kept as a revision)
 	comment.Content = sanitizedContent
 	comment.ImagePath = imagePath
 
-	err = db.UpdateComment(s.database, comment)
+	err = db.UpdateComment(s.database, comment, time.Now())
 	if err != nil {
 		return nil, err
 	}
 
+	s.syncCommentMentions(comment, username)
+
+	s.signCommentImages(comment)
 	return comment, nil
 }
 
 // DeleteComment deletes a comment
 func (s *PostService) DeleteComment(commentID, userID int) error {
 	// Get comment
-	comment, err := db.GetCommentByID(s.database, commentID)
+	comment, err := s.liveComment(commentID)
 	if err != nil {
 		return err
 	}
@@ -291,11 +509,52 @@ func (s *PostService) DeleteComment(commentID, userID int) error {
 		return errors.New("unauthorized: you can only delete your own comments")
 	}
 
-	// Delete comment
+	// Delete comment (kept as a tombstone while it has replies)
 	return db.DeleteComment(s.database, commentID)
 }
 
-// GetGroupPosts retrieves all posts for a specific group
-func (s *PostService) GetGroupPosts(groupID int) ([]*models.Post, error) {
-	return db.GetPostsByGroupID(s.database, groupID)
+// GetGroupPosts retrieves one page of posts for a specific group
+func (s *PostService) GetGroupPosts(groupID, userID int, page models.PostPage) ([]*models.Post, bool, error) {
+	posts, hasMore, err := db.GetPostsByGroupID(s.database, groupID, page)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := s.attachPostDetails(posts, userID); err != nil {
+		return nil, false, err
+	}
+
+	return posts, hasMore, nil
+}
+
+// attachPostDetails fills in the per-viewer details of a list of posts:
+// reactions, bookmarks, mention spans, media, polls and shared posts
+func (s *PostService) attachPostDetails(posts []*models.Post, viewerID int) error {
+	if err := s.attachPostReactions(posts, viewerID); err != nil {
+		return err
+	}
+	if err := s.attachBookmarks(posts, viewerID); err != nil {
+		return err
+	}
+	if err := s.attachPostMedia(posts); err != nil {
+		return err
+	}
+	if err := s.attachPostMentions(posts); err != nil {
+		return err
+	}
+	if err := s.attachPolls(posts, viewerID); err != nil {
+		return err
+	}
+	return s.attachSharedPosts(posts, viewerID)
+}
+
+// attachCommentDetails is attachPostDetails for comments
+func (s *PostService) attachCommentDetails(comments []*models.Comment, viewerID int) error {
+	if err := s.attachCommentReactions(comments, viewerID); err != nil {
+		return err
+	}
+	if err := s.attachCommentMedia(comments); err != nil {
+		return err
+	}
+	return s.attachCommentMentions(comments)
 }
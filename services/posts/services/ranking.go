@@ -0,0 +1,55 @@
+package services
+
+import (
+	"time"
+
+	"social-network/services/posts/db"
+	"social-network/services/posts/models"
+)
+
+// MaxSeenPerRequest caps the post IDs accepted by one MarkPostsSeen call
+const MaxSeenPerRequest = 100
+
+// GetRankedFeed returns one page of the ranked feed and the cursor of the
+// next page (nil on the last one). A nil cursor ranks a new snapshot as of
+// now; following pages re-rank the same snapshot, so a page never repeats
+// or skips posts because of activity in between.
+func (s *PostService) GetRankedFeed(userID int, cursor *models.RankCursor, limit int) ([]*models.Post, *models.RankCursor, error) {
+	if cursor == nil {
+		at := time.Now().UTC().Truncate(time.Second)
+		cursor = &models.RankCursor{At: at.Format(models.TimeLayout)}
+	}
+	at := cursor.Time()
+	from := at.Add(-s.rankConfig.Window).Format(models.TimeLayout)
+
+	candidates, err := db.GetRankCandidates(s.database, userID, from, cursor.At, s.rankConfig.MaxCandidates)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.ranker.Rank(candidates, at)
+
+	posts := []*models.Post{}
+	end := min(cursor.Offset+limit, len(candidates))
+	for i := cursor.Offset; i < end; i++ {
+		posts = append(posts, candidates[i].Post)
+	}
+
+	if err := s.attachPostDetails(posts, userID); err != nil {
+		return nil, nil, err
+	}
+
+	var next *models.RankCursor
+	if end < len(candidates) {
+		next = &models.RankCursor{At: cursor.At, Offset: end}
+	}
+	return posts, next, nil
+}
+
+// MarkPostsSeen records feed impressions so the ranked feed can move posts
+// the user has already scrolled past down
+func (s *PostService) MarkPostsSeen(userID int, postIDs []int) error {
+	if len(postIDs) > MaxSeenPerRequest {
+		postIDs = postIDs[:MaxSeenPerRequest]
+	}
+	return db.MarkPostsSeen(s.database, userID, postIDs, time.Now())
+}
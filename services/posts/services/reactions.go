@@ -0,0 +1,303 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"social-network/services/common/notify"
+	"social-network/services/posts/db"
+	"social-network/services/posts/models"
+	"social-network/services/posts/utils"
+)
+
+const (
+	// MaxReactionsPerUser caps the different emoji one user can add to a target
+	MaxReactionsPerUser = 10
+
+	// reactionNotifyWindow is how long reactions are collected before the
+	// author is notified, so a burst becomes a single notification
+	reactionNotifyWindow = 30 * time.Second
+)
+
+// AddReaction adds an emoji reaction to a post or comment and returns the
+// target's updated summary
+func (s *PostService) AddReaction(req *models.ReactionRequest, userID int, username string) (*models.ReactionSummary, error) {
+	if err := utils.ValidateEmoji(req.Emoji); err != nil {
+		return nil, err
+	}
+
+	postID, authorID, err := s.reactionTarget(req.TargetType, req.TargetID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := db.CountUserReactions(s.database, req.TargetType, req.TargetID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= MaxReactionsPerUser {
+		return nil, errors.New("reaction limit reached")
+	}
+
+	added, err := db.AddReaction(s.database, &models.Reaction{
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		UserID:     userID,
+		Emoji:      req.Emoji,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't notify for reactions to your own content or repeated adds
+	if added && authorID != userID {
+		s.reactions.add(authorID, postID, req.TargetType, req.TargetID, userID, username, req.Emoji)
+	}
+
+	return s.reactionSummary(req.TargetType, req.TargetID, userID)
+}
+
+// RemoveReaction removes the user's emoji from a post or comment and returns
+// the target's updated summary
+func (s *PostService) RemoveReaction(req *models.ReactionRequest, userID int) (*models.ReactionSummary, error) {
+	_, authorID, err := s.reactionTarget(req.TargetType, req.TargetID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := db.RemoveReaction(s.database, req.TargetType, req.TargetID, userID, req.Emoji)
+	if err != nil {
+		return nil, err
+	}
+
+	// A reaction taken back before the author was notified is never sent
+	if removed {
+		s.reactions.remove(authorID, req.TargetType, req.TargetID, userID, req.Emoji)
+	}
+
+	return s.reactionSummary(req.TargetType, req.TargetID, userID)
+}
+
+// GetReactions lists who reacted to a post or comment, optionally for one emoji
+func (s *PostService) GetReactions(targetType string, targetID, userID int, emoji string, limit int) ([]*models.Reaction, *models.ReactionSummary, error) {
+	if _, _, err := s.reactionTarget(targetType, targetID, userID); err != nil {
+		return nil, nil, err
+	}
+
+	reactions, err := db.GetReactions(s.database, targetType, targetID, emoji, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	summary, err := s.reactionSummary(targetType, targetID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return reactions, summary, nil
+}
+
+// reactionTarget checks that the target exists and the user can see it.
+// Returns the post the target belongs to and the target's author.
+func (s *PostService) reactionTarget(targetType string, targetID, userID int) (int, int, error) {
+	var postID, authorID int
+
+	switch targetType {
+	case models.ReactionTargetPost:
+		post, err := db.GetPostByID(s.database, targetID)
+		if err == sql.ErrNoRows || (err == nil && post.Status != models.PostStatusPublished) {
+			return 0, 0, errors.New("post not found")
+		} else if err != nil {
+			return 0, 0, err
+		}
+		// Reactions on a repost belong on the original
+		if post.ShareType == models.ShareTypeRepost {
+			return 0, 0, errors.New("cannot react to a repost")
+		}
+		postID, authorID = post.ID, post.UserID
+
+	case models.ReactionTargetComment:
+		comment, err := s.liveComment(targetID)
+		if err != nil {
+			return 0, 0, err
+		}
+		postID, authorID = comment.PostID, comment.UserID
+
+	default:
+		return 0, 0, errors.New("invalid target type")
+	}
+
+	hasAccess, err := db.CheckPostAccess(s.database, postID, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !hasAccess {
+		return 0, 0, errors.New("access denied: cannot react to this post")
+	}
+
+	return postID, authorID, nil
+}
+
+// reactionSummary loads the summary of a single target
+func (s *PostService) reactionSummary(targetType string, targetID, userID int) (*models.ReactionSummary, error) {
+	summaries, err := db.GetReactionSummaries(s.database, targetType, []int{targetID}, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, mine := summaryFields(summaries[targetID])
+	return &models.ReactionSummary{Counts: counts, ViewerReactions: mine}, nil
+}
+
+// attachPostReactions fills in the reaction counts and the viewer's own
+// reactions for a list of posts with a single query
+func (s *PostService) attachPostReactions(posts []*models.Post, viewerID int) error {
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	summaries, err := db.GetReactionSummaries(s.database, models.ReactionTargetPost, ids, viewerID)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		post.Reactions, post.ViewerReactions = summaryFields(summaries[post.ID])
+	}
+	return nil
+}
+
+// attachCommentReactions is attachPostReactions for comments
+func (s *PostService) attachCommentReactions(comments []*models.Comment, viewerID int) error {
+	ids := make([]int, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+
+	summaries, err := db.GetReactionSummaries(s.database, models.ReactionTargetComment, ids, viewerID)
+	if err != nil {
+		return err
+	}
+
+	for _, comment := range comments {
+		comment.Reactions, comment.ViewerReactions = summaryFields(summaries[comment.ID])
+	}
+	return nil
+}
+
+// summaryFields returns a summary's counts and viewer reactions as non-nil
+// slices, so targets without reactions serialize as [] rather than null
+func summaryFields(summary *models.ReactionSummary) ([]models.ReactionCount, []string) {
+	if summary == nil {
+		return []models.ReactionCount{}, []string{}
+	}
+
+	counts, mine := summary.Counts, summary.ViewerReactions
+	if mine == nil {
+		mine = []string{}
+	}
+	return counts, mine
+}
+
+// ============================================
+// NOTIFICATION BATCHING
+// ============================================
+
+// reactionKey identifies the notification a reaction is folded into
+type reactionKey struct {
+	authorID   int
+	targetType string
+	targetID   int
+}
+
+// reactionEntry is one pending reaction
+type reactionEntry struct {
+	userID   int
+	username string
+	emoji    string
+}
+
+// reactionBatch collects the reactions to one target during the window
+type reactionBatch struct {
+	postID  int
+	entries []reactionEntry // Oldest first
+}
+
+// reactionNotifier groups reactions to the same target arriving within
+// reactionNotifyWindow into one notification ("X and 3 others reacted")
+type reactionNotifier struct {
+	mu      sync.Mutex
+	window  time.Duration
+	pending map[reactionKey]*reactionBatch
+	send    func(authorID, postID int, targetType string, targetID, actorID int, actorName, emoji string, others int)
+}
+
+// newReactionNotifier creates a notifier that sends through notify.Reaction
+func newReactionNotifier(window time.Duration) *reactionNotifier {
+	return &reactionNotifier{
+		window:  window,
+		pending: make(map[reactionKey]*reactionBatch),
+		send:    notify.Reaction,
+	}
+}
+
+// add records a reaction, starting a new batch if none is pending
+func (n *reactionNotifier) add(authorID, postID int, targetType string, targetID, userID int, username, emoji string) {
+	key := reactionKey{authorID: authorID, targetType: targetType, targetID: targetID}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	batch, ok := n.pending[key]
+	if !ok {
+		batch = &reactionBatch{postID: postID}
+		n.pending[key] = batch
+		time.AfterFunc(n.window, func() { n.flush(key) })
+	}
+	batch.entries = append(batch.entries, reactionEntry{userID: userID, username: username, emoji: emoji})
+}
+
+// remove drops a reaction that was taken back before the batch was sent
+func (n *reactionNotifier) remove(authorID int, targetType string, targetID, userID int, emoji string) {
+	key := reactionKey{authorID: authorID, targetType: targetType, targetID: targetID}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	batch, ok := n.pending[key]
+	if !ok {
+		return
+	}
+	for i, entry := range batch.entries {
+		if entry.userID == userID && entry.emoji == emoji {
+			batch.entries = append(batch.entries[:i], batch.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// flush sends the batch for key, naming the latest reactor
+func (n *reactionNotifier) flush(key reactionKey) {
+	n.mu.Lock()
+	batch := n.pending[key]
+	delete(n.pending, key)
+	n.mu.Unlock()
+
+	if batch == nil || len(batch.entries) == 0 {
+		return
+	}
+
+	latest := batch.entries[len(batch.entries)-1]
+	others := make(map[int]bool)
+	for _, entry := range batch.entries {
+		if entry.userID != latest.userID {
+			others[entry.userID] = true
+		}
+	}
+
+	n.send(key.authorID, batch.postID, key.targetType, key.targetID, latest.userID, latest.username, latest.emoji, len(others))
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"social-network/services/common/notify"
+	"social-network/services/posts/db"
+	"social-network/services/posts/models"
+)
+
+// Repost shares a post with the user's followers as it is. Reposting a
+// repost shares its original, and reposting a post twice returns the
+// existing repost.
+func (s *PostService) Repost(postID, userID int, username string) (*models.Post, error) {
+	original, err := s.shareable(postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	repostID, err := db.GetRepostID(s.database, userID, original.ID)
+	if err != nil {
+		return nil, err
+	}
+	if repostID != 0 {
+		return s.sharePost(repostID, userID)
+	}
+
+	// The repost itself is public, but it's only listed to the people who
+	// can see the original (see db.repostVisible)
+	post := &models.Post{
+		UserID:       userID,
+		PrivacyLevel: "public",
+		CreatedAt:    time.Now(),
+		Status:       models.PostStatusPublished,
+		SharedPostID: &original.ID,
+		ShareType:    models.ShareTypeRepost,
+	}
+	if err := db.CreatePost(s.database, post); err != nil {
+		// Lost a race with another request for the same repost
+		if repostID, _ := db.GetRepostID(s.database, userID, original.ID); repostID != 0 {
+			return s.sharePost(repostID, userID)
+		}
+		return nil, err
+	}
+
+	s.notifyShare(post, original, username)
+
+	return s.sharePost(post.ID, userID)
+}
+
+// Unrepost removes the user's repost of a post. postID may be the original
+// or the repost itself.
+func (s *PostService) Unrepost(postID, userID int) error {
+	post, err := s.existingPost(postID)
+	if err != nil {
+		return err
+	}
+
+	if post.ShareType == models.ShareTypeRepost {
+		if post.UserID != userID {
+			return errors.New("unauthorized: you can only undo your own reposts")
+		}
+		return db.DeletePost(s.database, post.ID)
+	}
+
+	repostID, err := db.GetRepostID(s.database, userID, post.ID)
+	if err != nil {
+		return err
+	}
+	if repostID == 0 {
+		return errors.New("repost not found")
+	}
+	return db.DeletePost(s.database, repostID)
+}
+
+// shareable loads the post a share of postID would point to: the post
+// itself, or the original when postID is a repost. The user must be able
+// to see it, so only people who could already see a post can share it.
+func (s *PostService) shareable(postID, userID int) (*models.Post, error) {
+	for {
+		visible, err := db.GetVisiblePosts(s.database, userID, []int{postID})
+		if err != nil {
+			return nil, err
+		}
+
+		post := visible[postID]
+		if post == nil || (post.ShareType == models.ShareTypeRepost && post.SharedPostID == nil) {
+			return nil, errors.New("access denied: cannot share this post")
+		}
+		if post.ShareType != models.ShareTypeRepost {
+			return post, nil
+		}
+		postID = *post.SharedPostID
+	}
+}
+
+// sharePost loads a post created or found by Repost with its details
+func (s *PostService) sharePost(postID, viewerID int) (*models.Post, error) {
+	post, err := db.GetPostByID(s.database, postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachPostDetails([]*models.Post{post}, viewerID); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// notifyShare tells the original's author about a repost or quote, unless
+// they shared their own post or can't see the quote
+func (s *PostService) notifyShare(share, original *models.Post, sharerName string) {
+	if original.UserID == share.UserID {
+		return
+	}
+
+	quote := share.ShareType == models.ShareTypeQuote
+	if quote {
+		hasAccess, err := db.CheckPostAccess(s.database, share.ID, original.UserID)
+		if err != nil || !hasAccess {
+			return
+		}
+	}
+
+	go notify.Share(original.UserID, original.ID, share.ID, share.UserID, sharerName, quote)
+}
+
+// notifyQuote is notifyShare for a quote being published
+func (s *PostService) notifyQuote(post *models.Post, username string) error {
+	if post.ShareType != models.ShareTypeQuote || post.SharedPostID == nil {
+		return nil
+	}
+
+	original, err := db.GetPostByID(s.database, *post.SharedPostID)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	s.notifyShare(post, original, username)
+	return nil
+}
+
+// attachSharedPosts embeds the original of each share the viewer can see.
+// Shares of posts the viewer can't see, or that were deleted, are marked
+// unavailable instead. Repost counts are filled in on the posts and on the
+// embedded originals.
+func (s *PostService) attachSharedPosts(posts []*models.Post, viewerID int) error {
+	var sharedIDs []int
+	for _, post := range posts {
+		if post.SharedPostID != nil {
+			sharedIDs = append(sharedIDs, *post.SharedPostID)
+		}
+	}
+
+	shared, err := db.GetVisiblePosts(s.database, viewerID, sharedIDs)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int, 0, len(posts)+len(shared))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	for id := range shared {
+		ids = append(ids, id)
+	}
+	counts, err := db.GetRepostCounts(s.database, ids)
+	if err != nil {
+		return err
+	}
+
+	embedded := make([]*models.Post, 0, len(shared))
+	for _, original := range shared {
+		original.RepostCount = counts[original.ID]
+		embedded = append(embedded, original)
+	}
+	if err := s.attachPostMentions(embedded); err != nil {
+		return err
+	}
+	if err := s.attachPostMedia(embedded); err != nil {
+		return err
+	}
+	if err := s.attachBookmarks(embedded, viewerID); err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		post.RepostCount = counts[post.ID]
+		if post.ShareType == "" {
+			continue
+		}
+		if post.SharedPostID == nil || shared[*post.SharedPostID] == nil {
+			post.SharedPostUnavailable = true
+			continue
+		}
+		post.SharedPost = shared[*post.SharedPostID]
+	}
+	return nil
+}
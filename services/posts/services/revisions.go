@@ -0,0 +1,85 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+
+	"social-network/services/posts/db"
+	"social-network/services/posts/models"
+)
+
+// GetPostRevisions lists the previous versions of a post, newest first.
+// Viewers of the post only get the versions they could have seen then, by
+// their own privacy level and viewers.
+func (s *PostService) GetPostRevisions(postID, userID int) ([]*models.PostRevision, error) {
+	if _, err := s.existingPost(postID); err != nil {
+		return nil, err
+	}
+
+	hasAccess, err := db.CheckPostAccess(s.database, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, errors.New("access denied: cannot view this post")
+	}
+
+	return db.GetPostRevisions(s.database, postID, userID)
+}
+
+// PurgePostRevisions deletes a post's edit history; only its author can.
+// Returns the number of revisions removed.
+func (s *PostService) PurgePostRevisions(postID, userID int) (int, error) {
+	post, err := s.existingPost(postID)
+	if err != nil {
+		return 0, err
+	}
+
+	if post.UserID != userID {
+		return 0, errors.New("unauthorized: you can only purge the history of your own posts")
+	}
+
+	return db.DeletePostRevisions(s.database, postID)
+}
+
+// GetCommentRevisions lists the previous versions of a comment, newest
+// first, for anyone who can see the post it's on
+func (s *PostService) GetCommentRevisions(commentID, userID int) ([]*models.CommentRevision, error) {
+	comment, err := s.liveComment(commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	hasAccess, err := db.CheckPostAccess(s.database, comment.PostID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, errors.New("access denied: cannot view comments on this post")
+	}
+
+	return db.GetCommentRevisions(s.database, commentID)
+}
+
+// PurgeCommentRevisions deletes a comment's edit history; only its author can
+func (s *PostService) PurgeCommentRevisions(commentID, userID int) (int, error) {
+	comment, err := s.liveComment(commentID)
+	if err != nil {
+		return 0, err
+	}
+
+	if comment.UserID != userID {
+		return 0, errors.New("unauthorized: you can only purge the history of your own comments")
+	}
+
+	return db.DeleteCommentRevisions(s.database, commentID)
+}
+
+// existingPost loads a post, reporting a missing one as not found
+func (s *PostService) existingPost(postID int) (*models.Post, error) {
+	post, err := db.GetPostByID(s.database, postID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("post not found")
+	}
+	return post, err
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"social-network/services/posts/db"
+	"social-network/services/posts/models"
+	"social-network/services/posts/utils"
+)
+
+// SearchPosts runs a full-text search, best match first, and returns one
+// page of results with the cursor of the next page (nil on the last one)
+func (s *PostService) SearchPosts(userID int, query string, cursor *models.SearchCursor, limit int) ([]*models.Post, *models.SearchCursor, error) {
+	match, err := utils.BuildSearchQuery(query)
+	if err != nil || match == "" {
+		return []*models.Post{}, nil, err
+	}
+
+	offset := 0
+	if cursor != nil {
+		offset = cursor.Offset
+	}
+
+	posts, hasMore, err := db.SearchPosts(s.database, userID, match, offset, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.finishSearchResults(posts, userID); err != nil {
+		return nil, nil, err
+	}
+
+	var next *models.SearchCursor
+	if hasMore {
+		next = &models.SearchCursor{Offset: offset + len(posts)}
+	}
+	return posts, next, nil
+}
+
+// SearchPostsLatest runs a full-text search ordered newest first, paginated
+// like the feed
+func (s *PostService) SearchPostsLatest(userID int, query string, page models.PostPage) ([]*models.Post, bool, error) {
+	match, err := utils.BuildSearchQuery(query)
+	if err != nil || match == "" {
+		return []*models.Post{}, false, err
+	}
+
+	posts, hasMore, err := db.SearchPostsLatest(s.database, userID, match, page)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.finishSearchResults(posts, userID); err != nil {
+		return nil, false, err
+	}
+
+	return posts, hasMore, nil
+}
+
+// finishSearchResults turns raw highlights into HTML and attaches reactions and mentions
+func (s *PostService) finishSearchResults(posts []*models.Post, userID int) error {
+	for _, post := range posts {
+		post.Highlight.Title = utils.HighlightHTML(post.Highlight.Title)
+		post.Highlight.Snippet = utils.HighlightHTML(post.Highlight.Snippet)
+	}
+	return s.attachPostDetails(posts, userID)
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"social-network/services/posts/db"
+	"social-network/services/posts/models"
+	"social-network/services/posts/utils"
+)
+
+const (
+	// trendingWindow is how far back tag uses count towards trending
+	trendingWindow = 24 * time.Hour
+
+	// trendingHalfLife is the age at which a use counts half, so tags
+	// picking up speed now beat tags that were busy this morning
+	trendingHalfLife = 3 * time.Hour
+
+	// trendingCacheTTL is how long a computed trending list is served
+	trendingCacheTTL = time.Minute
+
+	// MaxTrendingTags caps the size of the trending list
+	MaxTrendingTags = 50
+)
+
+// GetPostsByTag retrieves one page of the posts with a hashtag
+func (s *PostService) GetPostsByTag(userID int, tag string, page models.PostPage) ([]*models.Post, bool, error) {
+	tag, err := utils.NormalizeTag(tag)
+	if err != nil {
+		return nil, false, err
+	}
+
+	posts, hasMore, err := db.GetPostsByTag(s.database, userID, tag, page)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := s.attachPostDetails(posts, userID); err != nil {
+		return nil, false, err
+	}
+
+	return posts, hasMore, nil
+}
+
+// TrendingTags returns the fastest-growing hashtags on public posts. Each
+// use within trendingWindow scores 0.5^(age/trendingHalfLife), and each
+// author counts once per tag (their most recent use), so one account
+// posting the same tag repeatedly can't make it trend.
+func (s *PostService) TrendingTags(limit int) ([]*models.TrendingTag, error) {
+	if limit <= 0 {
+		return nil, errors.New("invalid limit")
+	}
+
+	tags, err := s.trending.get(func(now time.Time) ([]*models.TrendingTag, error) {
+		since := now.Add(-trendingWindow).UTC().Format(models.TimeLayout)
+		uses, err := db.GetTagUses(s.database, since)
+		if err != nil {
+			return nil, err
+		}
+		return scoreTrendingTags(uses, now), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tags[:min(limit, len(tags))], nil
+}
+
+// scoreTrendingTags aggregates tag uses into the trending list, best first
+func scoreTrendingTags(uses []*models.TagUse, now time.Time) []*models.TrendingTag {
+	type authorKey struct {
+		tag      string
+		authorID int
+	}
+	weights := make(map[authorKey]float64)
+	byTag := make(map[string]*models.TrendingTag)
+
+	for _, use := range uses {
+		age := max(now.Sub(use.TaggedAt), 0)
+		weight := math.Exp2(-float64(age) / float64(trendingHalfLife))
+
+		key := authorKey{tag: use.Tag, authorID: use.AuthorID}
+		weights[key] = math.Max(weights[key], weight)
+
+		trending, ok := byTag[use.Tag]
+		if !ok {
+			trending = &models.TrendingTag{Tag: use.Tag}
+			byTag[use.Tag] = trending
+		}
+		trending.Posts++
+	}
+
+	for key, weight := range weights {
+		byTag[key.tag].Score += weight
+	}
+
+	tags := make([]*models.TrendingTag, 0, len(byTag))
+	for _, trending := range byTag {
+		trending.Score = math.Round(trending.Score*1000) / 1000
+		tags = append(tags, trending)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Score != tags[j].Score {
+			return tags[i].Score > tags[j].Score
+		}
+		if tags[i].Posts != tags[j].Posts {
+			return tags[i].Posts > tags[j].Posts
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+
+	return tags[:min(MaxTrendingTags, len(tags))]
+}
+
+// syncPostTags stores the hashtags currently in a post's title and content
+func (s *PostService) syncPostTags(post *models.Post) error {
+	texts := []string{post.Content}
+	if post.Title != nil {
+		texts = append(texts, *post.Title)
+	}
+	return db.SetPostTags(s.database, post.ID, utils.ExtractHashtags(texts...), time.Now())
+}
+
+// trendingCache keeps the last trending list for trendingCacheTTL, since
+// every client asks for the same list
+type trendingCache struct {
+	mu        sync.Mutex
+	tags      []*models.TrendingTag
+	expiresAt time.Time
+}
+
+// get returns the cached list, recomputing it with load when it has expired
+func (c *trendingCache) get(load func(now time.Time) ([]*models.TrendingTag, error)) ([]*models.TrendingTag, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.tags != nil && now.Before(c.expiresAt) {
+		return c.tags, nil
+	}
+
+	tags, err := load(now)
+	if err != nil {
+		return nil, err
+	}
+	c.tags, c.expiresAt = tags, now.Add(trendingCacheTTL)
+	return tags, nil
+}
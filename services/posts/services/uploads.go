@@ -0,0 +1,96 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+
+	"social-network/services/posts/db"
+	"social-network/services/posts/models"
+)
+
+// claimUploads makes a post or comment the owner of the uploads it uses:
+// its image_path and its media. Each must be an upload of the user that
+// nothing else uses yet.
+func (s *PostService) claimUploads(imagePath *string, media []*models.Media, userID int, ownerType string, ownerID int) error {
+	paths := make([]string, 0, len(media)+1)
+	if imagePath != nil && *imagePath != "" {
+		paths = append(paths, *imagePath)
+	}
+	for _, m := range media {
+		paths = append(paths, m.Path)
+	}
+
+	for _, path := range paths {
+		claimed, err := db.ClaimUpload(s.database, path, userID, ownerType, ownerID)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return errors.New("image not found")
+		}
+	}
+	return nil
+}
+
+// DeleteUpload forgets an upload of the user that nothing uses yet, so its
+// file can be removed
+func (s *PostService) DeleteUpload(key string, userID int) error {
+	deleted, err := db.DeleteUnusedMedia(s.database, key, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("image not found")
+	}
+	return nil
+}
+
+// CanViewUpload reports whether a user may see an uploaded post or comment
+// image, by its storage key: uploads nothing uses yet are only visible to
+// their uploader, the others to whoever can access the post. Images only
+// kept by revisions also need a revision the viewer could have seen.
+func (s *PostService) CanViewUpload(key string, viewerID int) (bool, error) {
+	upload, err := db.GetUpload(s.database, key)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var postID int
+	switch upload.OwnerType {
+	case "":
+		return upload.UserID == viewerID, nil
+	case models.UploadOwnerPost:
+		postID = upload.OwnerID
+	case models.UploadOwnerComment:
+		comment, err := db.GetCommentByID(s.database, upload.OwnerID)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		postID = comment.PostID
+	default:
+		// Chat images are served by the chat service
+		return false, nil
+	}
+
+	hasAccess, err := db.CheckPostAccess(s.database, postID, viewerID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil || !hasAccess {
+		return false, err
+	}
+
+	if upload.OwnerType == models.UploadOwnerPost {
+		hidden, err := db.RevisionImageHidden(s.database, postID, key, viewerID)
+		if err != nil || hidden {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"social-network/services/posts/models"
+)
+
+// MaxMentionsPerText caps the distinct @usernames resolved for one text
+const MaxMentionsPerText = 20
+
+// mentionRegex matches @name at the start of the text or after a character
+// that can't be part of a word or an email address. Names use the characters
+// allowed in usernames, which default to an email's local part.
+var mentionRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@])@([\p{L}\p{N}_.%+-]+)`)
+
+// mentionTrailing is punctuation that usually ends a sentence rather than a
+// username ("thanks @bob.")
+const mentionTrailing = ".-+%"
+
+// MentionCandidates returns the usernames that @mentions in texts may refer
+// to, to be resolved against users.username
+func MentionCandidates(texts ...string) []string {
+	seen := make(map[string]bool)
+	var names []string
+
+	for _, text := range texts {
+		for _, match := range mentionRegex.FindAllStringSubmatch(text, -1) {
+			for _, name := range mentionNames(match[1]) {
+				if !seen[name] && len(seen) < 2*MaxMentionsPerText {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// MentionIndex maps usernames to users for FindMentions: by exact username
+// and, for the first user of each, by its lowercase form
+func MentionIndex(users []models.MentionedUser) map[string]models.MentionedUser {
+	index := make(map[string]models.MentionedUser, 2*len(users))
+	for _, user := range users {
+		index[user.Username] = user
+	}
+	for _, user := range users {
+		if _, ok := index[strings.ToLower(user.Username)]; !ok {
+			index[strings.ToLower(user.Username)] = user
+		}
+	}
+	return index
+}
+
+// FindMentions locates the @mentions in text that refer to users in index.
+// A mention matches the exact username first, then case-insensitively, and
+// trailing punctuation is dropped if the full name is unknown.
+func FindMentions(text, field string, index map[string]models.MentionedUser) []models.Mention {
+	var mentions []models.Mention
+
+	for _, loc := range mentionRegex.FindAllStringSubmatchIndex(text, -1) {
+		at := loc[2] - 1 // The "@" right before the name
+		for _, name := range mentionNames(text[loc[2]:loc[3]]) {
+			user, ok := index[name]
+			if !ok {
+				user, ok = index[strings.ToLower(name)]
+			}
+			if !ok {
+				continue
+			}
+
+			start := utf16Len(text[:at])
+			mentions = append(mentions, models.Mention{
+				UserID:   user.ID,
+				Username: user.Username,
+				Field:    field,
+				Start:    start,
+				End:      start + 1 + utf16Len(name),
+			})
+			break
+		}
+	}
+	return mentions
+}
+
+// mentionNames returns the names a matched token may be: as written, then
+// without trailing punctuation
+func mentionNames(token string) []string {
+	names := []string{token}
+	if trimmed := strings.TrimRight(token, mentionTrailing); trimmed != "" && trimmed != token {
+		names = append(names, trimmed)
+	}
+	return names
+}
+
+// utf16Len returns the length of s in UTF-16 code units
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
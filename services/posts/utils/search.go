@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"unicode"
+
+	"social-network/services/posts/models"
+)
+
+// Search query limits
+const (
+	MaxSearchQueryLength = 200
+	MaxSearchTerms       = 16
+)
+
+// BuildSearchQuery turns what the user typed into an FTS5 MATCH expression.
+// All terms must match:
+//
+//	"exact phrase"   words in this order
+//	photo*           words starting with "photo"
+//	other words      whole words
+//
+// FTS5 operators and column filters in the input are matched as plain text.
+// Returns "" if the input contains nothing searchable.
+func BuildSearchQuery(input string) (string, error) {
+	if len(input) > MaxSearchQueryLength {
+		return "", errors.New("search query is too long")
+	}
+
+	var terms []string
+	add := func(text string, prefix bool) {
+		if !strings.ContainsFunc(text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+			return
+		}
+		term := `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+
+	rest := input
+	for rest != "" {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			break
+		}
+
+		if rest[0] == '"' {
+			// Phrase, up to the closing quote (or the end of the input)
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				add(rest[1:], false)
+				break
+			}
+			phrase := rest[1 : end+1]
+			rest = rest[end+2:]
+
+			prefix := strings.HasPrefix(rest, "*")
+			rest = strings.TrimLeft(rest, "*")
+			add(phrase, prefix)
+			continue
+		}
+
+		end := strings.IndexFunc(rest, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+		if end < 0 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		rest = rest[end:]
+
+		prefix := strings.HasSuffix(word, "*")
+		add(strings.TrimRight(word, "*"), prefix)
+	}
+
+	if len(terms) > MaxSearchTerms {
+		return "", errors.New("search query has too many terms")
+	}
+	return strings.Join(terms, " "), nil
+}
+
+// HighlightHTML escapes a raw highlight from the database and turns its
+// match markers into <mark> tags. Stored post text is already escaped, so
+// it's unescaped first to avoid showing "&amp;amp;".
+func HighlightHTML(raw string) string {
+	text := html.EscapeString(html.UnescapeString(raw))
+	text = strings.ReplaceAll(text, models.HighlightStart, "<mark>")
+	return strings.ReplaceAll(text, models.HighlightEnd, "</mark>")
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Hashtag limits
+const (
+	MaxTagLength   = 50 // Characters after the #
+	MaxTagsPerPost = 30
+)
+
+// hashtagRegex matches #tag at the start of the text or after a character
+// that can't be part of a word, so "a#b" and URL fragments aren't tags
+var hashtagRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&/#])#([\p{L}\p{N}_]+)`)
+
+// ExtractHashtags returns the distinct hashtags in texts, normalized with
+// NormalizeTag, in order of first appearance. Texts may be HTML-escaped as
+// stored, so entities like "&#39;" aren't mistaken for tags.
+func ExtractHashtags(texts ...string) []string {
+	seen := make(map[string]bool)
+	tags := []string{}
+
+	for _, text := range texts {
+		for _, match := range hashtagRegex.FindAllStringSubmatch(html.UnescapeString(text), -1) {
+			tag, err := NormalizeTag(match[1])
+			if err != nil || seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			tags = append(tags, tag)
+			if len(tags) == MaxTagsPerPost {
+				return tags
+			}
+		}
+	}
+	return tags
+}
+
+// NormalizeTag validates a tag (without the #) and returns it lowercased.
+// Tags are letters, digits and underscores, with at least one letter.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.TrimPrefix(tag, "#")
+
+	if tag == "" || utf8.RuneCountInString(tag) > MaxTagLength {
+		return "", errors.New("invalid tag")
+	}
+
+	hasLetter := false
+	for _, r := range tag {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r) || r == '_':
+		default:
+			return "", errors.New("invalid tag")
+		}
+	}
+	if !hasLetter {
+		return "", errors.New("invalid tag")
+	}
+
+	return strings.ToLower(tag), nil
+}
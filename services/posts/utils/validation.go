@@ -5,12 +5,16 @@ import (
 	"html"
 	"regexp"
 	"strings"
+	"unicode/utf8"
+
+	"social-network/services/posts/models"
 )
 
 const (
 	MaxPostContentLength = 1000
 	MaxTitleLength       = 200
 	MinPostContentLength = 1
+	MaxEmojiLength       = 32 // Bytes, enough for ZWJ sequences like family emoji
 )
 
 var dangerousRegex = regexp.MustCompile(`(?i)<script|javascript:|onerror=|onload=|<iframe|eval\(|<embed|<object`)
@@ -77,6 +81,70 @@ func ValidateTitle(title *string) (*string, error) {
 	return &sanitized, nil
 }
 
+// ValidatePollOption validates and sanitizes the text of a poll option
+func ValidatePollOption(text string) (string, error) {
+	trimmed := strings.TrimSpace(text)
+
+	if trimmed == "" {
+		return "", errors.New("Poll options cannot be empty")
+	}
+
+	// Check length
+	if utf8.RuneCountInString(trimmed) > models.MaxPollOptionLength {
+		return "", errors.New("Poll option is too long (max 100 characters)")
+	}
+
+	// Check for dangerous patterns
+	if dangerousRegex.MatchString(trimmed) {
+		return "", errors.New("Poll option contains potentially dangerous code")
+	}
+
+	// Escape HTML
+	return html.EscapeString(trimmed), nil
+}
+
+// ValidateAltText validates and sanitizes the alt text of an image. Empty
+// alt text is allowed.
+func ValidateAltText(text string) (string, error) {
+	trimmed := strings.TrimSpace(text)
+
+	// Check length
+	if utf8.RuneCountInString(trimmed) > models.MaxAltTextLength {
+		return "", errors.New("Alt text is too long (max 1000 characters)")
+	}
+
+	// Check for dangerous patterns
+	if dangerousRegex.MatchString(trimmed) {
+		return "", errors.New("Alt text contains potentially dangerous code")
+	}
+
+	// Escape HTML
+	return html.EscapeString(trimmed), nil
+}
+
+// ValidateCollectionName validates and sanitizes the name of a bookmark
+// collection
+func ValidateCollectionName(name string) (string, error) {
+	trimmed := strings.TrimSpace(name)
+
+	if trimmed == "" {
+		return "", errors.New("Collection name is required")
+	}
+
+	// Check length
+	if utf8.RuneCountInString(trimmed) > models.MaxBookmarkCollectionNameLen {
+		return "", errors.New("Collection name is too long (max 50 characters)")
+	}
+
+	// Check for dangerous patterns
+	if dangerousRegex.MatchString(trimmed) {
+		return "", errors.New("Collection name contains potentially dangerous code")
+	}
+
+	// Escape HTML
+	return html.EscapeString(trimmed), nil
+}
+
 // ValidateImagePath validates image path to prevent path traversal
 func ValidateImagePath(imagePath *string) error {
 	if imagePath == nil || *imagePath == "" {
@@ -97,3 +165,47 @@ func ValidateImagePath(imagePath *string) error {
 
 	return nil
 }
+
+// ValidateEmoji checks that a reaction is a single emoji (including skin tone,
+// keycap, flag and ZWJ sequences) and not arbitrary text
+func ValidateEmoji(emoji string) error {
+	if emoji == "" {
+		return errors.New("Emoji is required")
+	}
+	if len(emoji) > MaxEmojiLength || !utf8.ValidString(emoji) {
+		return errors.New("Invalid emoji")
+	}
+
+	pictographs := 0
+	keycap := strings.ContainsRune(emoji, 0x20E3)
+	for _, r := range emoji {
+		switch {
+		case isPictograph(r):
+			pictographs++
+		case r == 0x200D, r == 0xFE0F, r == 0x20E3: // ZWJ, emoji presentation, keycap
+		case r >= 0xE0020 && r <= 0xE007F: // Tag sequences (subdivision flags)
+		case keycap && (r == '#' || r == '*' || (r >= '0' && r <= '9')):
+			pictographs++
+		default:
+			return errors.New("Invalid emoji")
+		}
+	}
+	if pictographs == 0 {
+		return errors.New("Invalid emoji")
+	}
+
+	return nil
+}
+
+// isPictograph reports whether r is in one of the emoji blocks
+func isPictograph(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // Emoticons, symbols and pictographs, flags, skin tones
+		return true
+	case r >= 0x2190 && r <= 0x21FF, r >= 0x2300 && r <= 0x23FF, r >= 0x2600 && r <= 0x27BF, r >= 0x2B00 && r <= 0x2BFF:
+		return true
+	case r == 0x00A9, r == 0x00AE, r == 0x203C, r == 0x2049, r == 0x2122, r == 0x2139, r == 0x3030, r == 0x303D, r == 0x3297, r == 0x3299:
+		return true
+	}
+	return false
+}
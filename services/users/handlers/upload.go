@@ -2,37 +2,42 @@ package handlers
 
 import (
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
+	"path"
 	"time"
 
+	"social-network/services/common/imaging"
+	"social-network/services/common/storage"
 	"social-network/services/users/middleware"
 	"social-network/services/users/services"
 	"social-network/services/users/utils"
 )
 
 const (
-	maxUploadSize = 10 << 20 // 10 MB
-	uploadDir     = "./uploads/avatars"
+	maxUploadSize = 10 << 20  // 10 MB
+	uploadDir     = "avatars" // Storage key prefix, served publicly
 )
 
+// uploadLimits are the image limits of avatar uploads
+var uploadLimits = imaging.Limits{
+	MaxBytes:     maxUploadSize,
+	MaxDimension: imaging.DefaultLimits.MaxDimension,
+	MaxPixels:    imaging.DefaultLimits.MaxPixels,
+	MaxGIFPixels: imaging.DefaultLimits.MaxGIFPixels,
+}
+
 // UploadHandlers handles file upload operations
 type UploadHandlers struct {
 	userService *services.UserService
+	store       storage.Storage
 }
 
 // NewUploadHandlers creates a new upload handlers instance
-func NewUploadHandlers(userService *services.UserService) *UploadHandlers {
-	// Ensure upload directory exists
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		log.Printf("Warning: Failed to create upload directory: %v", err)
-	}
+func NewUploadHandlers(userService *services.UserService, store storage.Storage) *UploadHandlers {
 	return &UploadHandlers{
 		userService: userService,
+		store:       store,
 	}
 }
 
@@ -51,6 +56,7 @@ func (h *UploadHandlers) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse multipart form
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
 	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
 		log.Printf("Error parsing multipart form: %v", err)
 		utils.ErrorResponse(w, "File too large or invalid form data", http.StatusBadRequest)
@@ -58,7 +64,7 @@ func (h *UploadHandlers) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get file from form
-	file, header, err := r.FormFile("avatar")
+	file, _, err := r.FormFile("avatar")
 	if err != nil {
 		log.Printf("Error retrieving file: %v", err)
 		utils.ErrorResponse(w, "No file provided", http.StatusBadRequest)
@@ -66,39 +72,23 @@ func (h *UploadHandlers) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	// Validate file type
-	contentType := header.Header.Get("Content-Type")
-	if !isValidImageType(contentType) {
-		utils.ErrorResponse(w, "Invalid file type. Only JPEG, PNG, GIF, and WebP images are allowed", http.StatusBadRequest)
-		return
-	}
-
-	// Generate unique filename
-	ext := filepath.Ext(header.Filename)
-	if ext == "" {
-		ext = getExtensionFromContentType(contentType)
-	}
-	filename := fmt.Sprintf("avatar_%d_%d%s", userID, time.Now().Unix(), ext)
-	filePath := filepath.Join(uploadDir, filename)
-
-	// Create file
-	dst, err := os.Create(filePath)
+	// Identify, check and re-encode the image (stripping its metadata)
+	img, err := imaging.Process(file, uploadLimits)
 	if err != nil {
-		log.Printf("Error creating file: %v", err)
-		utils.ErrorResponse(w, "Failed to save file", http.StatusInternalServerError)
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer dst.Close()
 
-	// Copy file content
-	if _, err := io.Copy(dst, file); err != nil {
+	// Save it with its thumbnails under a unique name
+	key, err := img.Save(r.Context(), h.store, uploadDir, fmt.Sprintf("avatar_%d_%d", userID, time.Now().UnixNano()))
+	if err != nil {
 		log.Printf("Error saving file: %v", err)
 		utils.ErrorResponse(w, "Failed to save file", http.StatusInternalServerError)
 		return
 	}
 
 	// Update user's avatar_path in database
-	avatarPath := fmt.Sprintf("/uploads/avatars/%s", filename)
+	avatarPath := storage.Path(key)
 	if err := h.userService.UpdateUserAvatarPath(userID, avatarPath); err != nil {
 		log.Printf("Error updating user avatar path: %v", err)
 		// File was saved but DB update failed - log but don't fail the request
@@ -107,6 +97,7 @@ func (h *UploadHandlers) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 
 	utils.SuccessResponse(w, map[string]interface{}{
 		"avatar_path": avatarPath,
+		"thumbnails":  imaging.ThumbnailPaths(avatarPath),
 		"message":     "Avatar uploaded successfully",
 	})
 }
@@ -133,17 +124,18 @@ func (h *UploadHandlers) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract filename from path
-	filename := filepath.Base(avatarPath)
+	key := uploadDir + "/" + path.Base(avatarPath)
 
-	// Build full file path
-	fullPath := filepath.Join(uploadDir, filename)
+	// Check if file exists
+	obj, err := h.store.Get(r.Context(), key)
+	if err != nil {
+		utils.ErrorResponse(w, "Avatar not found", http.StatusNotFound)
+		return
+	}
+	obj.Body.Close()
 
-	// Delete the file
-	if err := os.Remove(fullPath); err != nil {
-		if os.IsNotExist(err) {
-			utils.ErrorResponse(w, "Avatar not found", http.StatusNotFound)
-			return
-		}
+	// Delete the file and its thumbnails
+	if err := imaging.Remove(r.Context(), h.store, key); err != nil {
 		log.Printf("Error deleting avatar: %v", err)
 		utils.ErrorResponse(w, "Failed to delete avatar", http.StatusInternalServerError)
 		return
@@ -153,36 +145,3 @@ func (h *UploadHandlers) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
 		"message": "Avatar deleted successfully",
 	})
 }
-
-// Helper functions
-
-func isValidImageType(contentType string) bool {
-	validTypes := []string{
-		"image/jpeg",
-		"image/jpg",
-		"image/png",
-		"image/gif",
-		"image/webp",
-	}
-	for _, validType := range validTypes {
-		if strings.EqualFold(contentType, validType) {
-			return true
-		}
-	}
-	return false
-}
-
-func getExtensionFromContentType(contentType string) string {
-	switch strings.ToLower(contentType) {
-	case "image/jpeg", "image/jpg":
-		return ".jpg"
-	case "image/png":
-		return ".png"
-	case "image/gif":
-		return ".gif"
-	case "image/webp":
-		return ".webp"
-	default:
-		return ".jpg"
-	}
-}
@@ -10,6 +10,7 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 
 	"social-network/services/common/authcache"
+	"social-network/services/common/storage"
 	"social-network/services/users/handlers"
 	"social-network/services/users/middleware"
 	"social-network/services/users/services"
@@ -32,9 +33,15 @@ func main() {
 	// Initialize services
 	userService := services.NewUserService(db)
 
+	// Avatars live in the shared storage
+	store, err := storage.FromEnv()
+	if err != nil {
+		log.Fatalf("Failed to set up upload storage: %v", err)
+	}
+
 	// Initialize handlers
 	userHandlers := handlers.NewUserHandlers(userService)
-	uploadHandlers := handlers.NewUploadHandlers(userService)
+	uploadHandlers := handlers.NewUploadHandlers(userService, store)
 
 	// Get auth service URL from environment
 	authServiceURL := os.Getenv("AUTH_SERVICE_URL")
@@ -53,9 +60,8 @@ func main() {
 	// Health check (no auth required)
 	mux.HandleFunc("/health", handlers.HealthHandler)
 
-	// Static file server for uploaded avatars
-	fs := http.FileServer(http.Dir("./uploads"))
-	mux.Handle("/uploads/", http.StripPrefix("/uploads/", fs))
+	// Uploaded avatars (public)
+	mux.Handle(storage.PathPrefix, storage.Handler(store, "avatars/"))
 
 	// Upload routes (auth required + rate limited)
 	mux.Handle("/upload/avatar", authMiddleware(rateLimiter.RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {